@@ -1,16 +1,48 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
+	"slices"
+	"time"
 
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/limit"
+	"github.com/MElghrbawy/simple_bank/money"
 	"github.com/MElghrbawy/simple_bank/token"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
+type accountResponse struct {
+	ID        int64            `json:"id"`
+	Owner     string           `json:"owner"`
+	Balance   money.Amount     `json:"balance"`
+	Currency  string           `json:"currency"`
+	Status    db.AccountStatus `json:"status"`
+	FrozenBy  string           `json:"frozen_by,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		ID:        account.ID,
+		Owner:     account.Owner,
+		Balance:   amountIn(account.Balance, account.Currency),
+		Currency:  account.Currency,
+		Status:    account.Status,
+		FrozenBy:  account.FrozenBy.String,
+		CreatedAt: account.CreatedAt,
+	}
+}
+
+// amountIn renders units, stored in minor units of the currency, as a decimal amount.
+func amountIn(units int64, code string) money.Amount {
+	currency, ok := money.LookupCurrency(code)
+	if !ok {
+		currency = money.Currency{Code: code}
+	}
+	return money.Amount{Units: units, Currency: currency}
+}
+
 type createAccountRequest struct {
 	Currency string `json:"currency" binding:"required,currency"`
 }
@@ -18,7 +50,7 @@ type createAccountRequest struct {
 func (server *Server) createAccount(c *gin.Context) {
 	var req createAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, bindingError(err))
 		return
 	}
 
@@ -30,22 +62,13 @@ func (server *Server) createAccount(c *gin.Context) {
 		Balance:  0,
 	}
 
-	account, err := server.store.CreateAccount(c, arg)
+	account, err := server.store.CreateAccountTx(c, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				c.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
-		}
-
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, account)
-
+	c.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type getAccountRequest struct {
@@ -55,28 +78,23 @@ type getAccountRequest struct {
 func (server *Server) getAccount(c *gin.Context) {
 	var req getAccountRequest
 	if err := c.ShouldBindUri(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, bindingError(err))
 		return
 	}
 
 	account, err := server.store.GetAccount(c, req.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
 	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
 	if account.Owner != authPayload.Username {
-		err := errors.New("account does not belong to the authenticated user")
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(c, errAccountNotOwned)
 		return
 	}
 
-	c.JSON(http.StatusOK, account)
+	c.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type listAccountRequest struct {
@@ -87,7 +105,7 @@ type listAccountRequest struct {
 func (server *Server) listAccounts(c *gin.Context) {
 	var req listAccountRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, bindingError(err))
 		return
 	}
 
@@ -101,9 +119,167 @@ func (server *Server) listAccounts(c *gin.Context) {
 
 	accounts, err := server.store.ListAccounts(c, arg)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, accounts)
+	rsp := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		rsp[i] = newAccountResponse(account)
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+type accountBalanceResponse struct {
+	ID               int64        `json:"id"`
+	Balance          money.Amount `json:"balance"`
+	AvailableBalance money.Amount `json:"available_balance"`
+}
+
+func (server *Server) getAccountBalance(c *gin.Context) {
+	var req getAccountRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		writeError(c, bindingError(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(c, req.ID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		writeError(c, errAccountNotOwned)
+		return
+	}
+
+	balance, err := server.store.GetAccountBalance(c, account.ID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, accountBalanceResponse{
+		ID:               balance.ID,
+		Balance:          amountIn(balance.Balance, account.Currency),
+		AvailableBalance: amountIn(balance.AvailableBalance, account.Currency),
+	})
+}
+
+type transferLimitsResponse struct {
+	PerTransfer money.Amount `json:"per_transfer"`
+	Daily       money.Amount `json:"daily"`
+	Monthly     money.Amount `json:"monthly"`
+}
+
+type transferUsageResponse struct {
+	Daily   money.Amount `json:"daily"`
+	Monthly money.Amount `json:"monthly"`
+}
+
+// transferAllowanceResponse renders the limits of an account in its currency.
+// A zero limit is not enforced, and a nil remaining amount belongs to a limit that is not enforced.
+type transferAllowanceResponse struct {
+	AccountID        int64                  `json:"account_id"`
+	Limits           transferLimitsResponse `json:"limits"`
+	Usage            transferUsageResponse  `json:"usage"`
+	DailyRemaining   *money.Amount          `json:"daily_remaining"`
+	MonthlyRemaining *money.Amount          `json:"monthly_remaining"`
+}
+
+func newTransferAllowanceResponse(allowance limit.Allowance, currency string) transferAllowanceResponse {
+	rsp := transferAllowanceResponse{
+		AccountID: allowance.AccountID,
+		Limits: transferLimitsResponse{
+			PerTransfer: amountIn(allowance.Limits.PerTransfer, currency),
+			Daily:       amountIn(allowance.Limits.Daily, currency),
+			Monthly:     amountIn(allowance.Limits.Monthly, currency),
+		},
+		Usage: transferUsageResponse{
+			Daily:   amountIn(allowance.Usage.Daily, currency),
+			Monthly: amountIn(allowance.Usage.Monthly, currency),
+		},
+	}
+	if allowance.DailyRemaining != nil {
+		remaining := amountIn(*allowance.DailyRemaining, currency)
+		rsp.DailyRemaining = &remaining
+	}
+	if allowance.MonthlyRemaining != nil {
+		remaining := amountIn(*allowance.MonthlyRemaining, currency)
+		rsp.MonthlyRemaining = &remaining
+	}
+	return rsp
+}
+
+func (server *Server) getTransferAllowance(c *gin.Context) {
+	var req getAccountRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		writeError(c, bindingError(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(c, req.ID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		writeError(c, errAccountNotOwned)
+		return
+	}
+
+	allowance, err := server.store.GetTransferAllowance(c, account.ID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTransferAllowanceResponse(allowance, account.Currency))
+}
+
+// updateAccountStatus returns a handler moving an account to status.
+// Owners can freeze and close their active accounts, but a frozen account is left to the bankers,
+// who can change the status of any account.
+func (server *Server) updateAccountStatus(status db.AccountStatus) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req getAccountRequest
+		if err := c.ShouldBindUri(&req); err != nil {
+			writeError(c, bindingError(err))
+			return
+		}
+
+		account, err := server.store.GetAccount(c, req.ID)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !slices.Contains(server.config.BankerUsernames, authPayload.Username) {
+			if account.Owner != authPayload.Username {
+				writeError(c, errAccountNotOwned)
+				return
+			}
+			if status == db.AccountStatusActive || account.Status == db.AccountStatusFrozen {
+				writeError(c, errBankerOnly)
+				return
+			}
+		}
+
+		account, err = server.store.UpdateAccountStatusTx(c, db.UpdateAccountStatusTxParams{
+			AccountID: account.ID,
+			Status:    status,
+			ChangedBy: authPayload.Username,
+		})
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, newAccountResponse(account))
+	}
 }
@@ -2,7 +2,6 @@ package api
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,9 +12,12 @@ import (
 
 	mockdb "github.com/MElghrbawy/simple_bank/db/mock"
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/limit"
 	"github.com/MElghrbawy/simple_bank/token"
 	"github.com/MElghrbawy/simple_bank/util"
 	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -56,7 +58,7 @@ func TestGetAccount(t *testing.T) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(db.Account{}, sql.ErrNoRows)
+					Return(db.Account{}, db.ErrRecordNotFound)
 			},
 			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
@@ -72,7 +74,7 @@ func TestGetAccount(t *testing.T) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(db.Account{}, sql.ErrConnDone)
+					Return(db.Account{}, pgx.ErrTxClosed)
 			},
 			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
@@ -126,6 +128,7 @@ func randomAccount(owner string) db.Account {
 		Owner:    owner,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Status:   db.AccountStatusActive,
 	}
 
 }
@@ -145,9 +148,259 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotAccount db.Account
+	var gotAccount accountResponse
 	err = json.Unmarshal(data, &gotAccount)
 	require.NoError(t, err)
-	require.Equal(t, account, gotAccount)
+	require.Equal(t, newAccountResponse(account), gotAccount)
 
 }
+
+func TestGetAccountBalance(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	account.Currency = util.USD
+
+	balance := db.GetAccountBalanceRow{
+		ID:               account.ID,
+		Balance:          1234,
+		AvailableBalance: 1000,
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetAccountBalance(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(balance, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/accounts/%d/balance", account.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got accountBalanceResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &got)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, got.ID)
+	require.Equal(t, "12.34", got.Balance.String())
+	require.Equal(t, "10.00", got.AvailableBalance.String())
+	require.Equal(t, util.USD, got.Balance.Currency.Code)
+}
+
+func TestGetTransferAllowance(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	account.Currency = util.USD
+	otherUser, _ := randomUser(t)
+
+	allowance := limit.Limits{Daily: 100}.Allowance(account.ID, limit.Usage{Daily: 40, Monthly: 40})
+
+	testCases := []struct {
+		name         string
+		username     string
+		buildStubs   func(store *mockdb.MockStore)
+		checkResults func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetTransferAllowance(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(allowance, nil)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got transferAllowanceResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, account.ID, got.AccountID)
+				require.Equal(t, "1.00", got.Limits.Daily.String())
+				require.Equal(t, "0.40", got.Usage.Daily.String())
+				require.NotNil(t, got.DailyRemaining)
+				require.Equal(t, "0.60", got.DailyRemaining.String())
+				require.Equal(t, util.USD, got.DailyRemaining.Currency.Code)
+				require.Nil(t, got.MonthlyRemaining)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: otherUser.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetTransferAllowance(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		tc.buildStubs(store)
+
+		server := newTestServer(t, store)
+		recorder := httptest.NewRecorder()
+
+		url := fmt.Sprintf("/accounts/%d/limits", account.ID)
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		tc.checkResults(t, recorder)
+	}
+}
+
+func TestUpdateAccountStatus(t *testing.T) {
+	user, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	frozen := account
+	frozen.Status = db.AccountStatusFrozen
+	frozen.FrozenBy = pgtype.Text{String: banker.Username, Valid: true}
+
+	testCases := []struct {
+		name         string
+		action       string
+		username     string
+		buildStubs   func(store *mockdb.MockStore)
+		checkResults func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Freeze",
+			action:   "freeze",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Eq(db.UpdateAccountStatusTxParams{
+						AccountID: account.ID,
+						Status:    db.AccountStatusFrozen,
+						ChangedBy: user.Username,
+					})).
+					Times(1).
+					Return(frozen, nil)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "CloseNotEmpty",
+			action:   "close",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, db.ErrAccountNotEmpty)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:     "OwnerUnfreeze",
+			action:   "unfreeze",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(frozen, nil)
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "OwnerCloseFrozen",
+			action:   "close",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(frozen, nil)
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "BankerUnfreeze",
+			action:   "unfreeze",
+			username: banker.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(frozen, nil)
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Eq(db.UpdateAccountStatusTxParams{
+						AccountID: account.ID,
+						Status:    db.AccountStatusActive,
+						ChangedBy: banker.Username,
+					})).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			action:   "freeze",
+			username: "stranger",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			action:   "unfreeze",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		tc.buildStubs(store)
+
+		server := newTestServer(t, store)
+		server.config.BankerUsernames = []string{banker.Username}
+		recorder := httptest.NewRecorder()
+
+		url := fmt.Sprintf("/accounts/%d/%s", account.ID, tc.action)
+		request, err := http.NewRequest(http.MethodPost, url, nil)
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		tc.checkResults(t, recorder)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	errAccountNotOwned      = apperr.New(apperr.PermissionDenied, "account does not belong to the authenticated user")
+	errSubscriptionNotOwned = apperr.New(apperr.PermissionDenied, "webhook subscription does not belong to the authenticated user")
+	errBankerOnly           = apperr.New(apperr.PermissionDenied, "only bankers can unfreeze an account or change a frozen one")
+	errInvalidCredentials   = apperr.New(apperr.Unauthenticated, "invalid username or password")
+)
+
+// writeError aborts the request with the RFC 9457 problem details describing err.
+// Internal errors are logged, their details are never sent to the client.
+func writeError(c *gin.Context, err error) {
+	problem := apperr.NewProblem(err, c.Request.URL.Path)
+	if problem.Status == http.StatusInternalServerError {
+		log.Ctx(c).Error().Err(err).Str("path", c.Request.URL.Path).Msg("request failed")
+	}
+
+	c.Header("Content-Type", apperr.ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// bindingError turns the error of binding a request into a validation error,
+// listing the fields that broke their binding rules.
+func bindingError(err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return apperr.Wrap(err, apperr.Validation, err.Error())
+	}
+
+	violations := make([]apperr.FieldViolation, len(validationErrs))
+	for i, fieldErr := range validationErrs {
+		violations[i] = apperr.FieldViolation{
+			Field:       fieldErr.Field(),
+			Description: ruleDescription(fieldErr),
+		}
+	}
+	return apperr.Invalid(violations...)
+}
+
+func ruleDescription(fieldErr validator.FieldError) string {
+	if fieldErr.Param() == "" {
+		return fmt.Sprintf("failed the %s rule", fieldErr.Tag())
+	}
+	return fmt.Sprintf("failed the %s=%s rule", fieldErr.Tag(), fieldErr.Param())
+}
+
+// unauthenticatedError reports a request whose credentials were refused because of err.
+func unauthenticatedError(err error) error {
+	return apperr.Wrap(err, apperr.Unauthenticated, err.Error())
+}
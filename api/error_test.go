@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	mockdb "github.com/MElghrbawy/simple_bank/db/mock"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder, status int) apperr.Problem {
+	require.Equal(t, status, recorder.Code)
+	require.Equal(t, apperr.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+	var problem apperr.Problem
+	err := json.Unmarshal(recorder.Body.Bytes(), &problem)
+	require.NoError(t, err)
+	require.Equal(t, status, problem.Status)
+	require.NotEmpty(t, problem.Type)
+	require.NotEmpty(t, problem.Title)
+	return problem
+}
+
+func TestProblemDetails(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Validation",
+			method: http.MethodPost,
+			url:    "/accounts",
+			body:   `{"currency":"XYZ"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusBadRequest)
+				require.Equal(t, "/problems/validation", problem.Type)
+				require.Equal(t, "/accounts", problem.Instance)
+				require.Equal(t, []apperr.FieldViolation{
+					{Field: "currency", Description: "failed the currency rule"},
+				}, problem.Errors)
+			},
+		},
+		{
+			name:   "NotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusNotFound)
+				require.Equal(t, "/problems/not_found", problem.Type)
+				require.Equal(t, "record not found", problem.Detail)
+			},
+		},
+		{
+			name:   "AlreadyExists",
+			method: http.MethodPost,
+			url:    "/accounts",
+			body:   `{"currency":"USD"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrUniqueViolation)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusConflict)
+			},
+		},
+		{
+			name:   "InternalErrorHidden",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusInternalServerError)
+				require.Equal(t, "internal error", problem.Detail)
+				require.NotContains(t, recorder.Body.String(), pgx.ErrTxClosed.Error())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/token"
 	"github.com/MElghrbawy/simple_bank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -13,11 +14,13 @@ import (
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
 		AccessTokenDuration: time.Minute,
 	}
 
-	server, err := NewServer(config, store)
+	tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	server, err := NewServer(config, store, tokenMaker)
 	require.NoError(t, err)
 
 	return server
@@ -1,11 +1,13 @@
 package api
 
 import (
-	"errors"
-	"fmt"
-	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/clientinfo"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/requestid"
 	"github.com/MElghrbawy/simple_bank/token"
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +16,8 @@ const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "authorization_payload"
+	clientInfoKey           = "client_info"
+	readYourWritesHeaderKey = "x-read-your-writes"
 )
 
 func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
@@ -21,34 +25,78 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		// Get the access token from the header
 		authorizationHeader := c.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header is not provided")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(c, apperr.New(apperr.Unauthenticated, "authorization header is not provided"))
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
-			err := errors.New("invalid authorization header")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(c, apperr.New(apperr.Unauthenticated, "invalid authorization header"))
 			return
 		}
 
 		authorizationType := strings.ToLower(fields[0])
 		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(c, apperr.Newf(apperr.Unauthenticated, "unsupported authorization type %s", authorizationType))
 			return
 		}
 
 		accessToken := fields[1]
 		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(c, unauthenticatedError(err))
 			return
 		}
 
 		c.Set(authorizationPayloadKey, payload)
+		withActor(c, payload.Username)
 		c.Next()
 
 	}
 }
+
+// withActor attributes the actions audited during the request to username.
+func withActor(c *gin.Context, username string) {
+	info := clientInfoOf(c)
+	c.Request = c.Request.WithContext(db.WithActor(c.Request.Context(), db.Actor{
+		Username:  username,
+		ClientIP:  info.ClientIP,
+		UserAgent: info.UserAgent,
+	}))
+}
+
+// clientInfoMiddleware identifies the client of the request, honoring X-Forwarded-For from the trusted proxies only.
+func clientInfoMiddleware(extractor *clientinfo.Extractor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(clientInfoKey, extractor.FromHTTP(c.Request))
+		c.Next()
+	}
+}
+
+// clientInfoOf returns the client of the request identified by clientInfoMiddleware.
+func clientInfoOf(c *gin.Context) clientinfo.Info {
+	info, _ := c.Value(clientInfoKey).(clientinfo.Info)
+	return info
+}
+
+// readYourWritesMiddleware serves the reads of the request from the primary database when the client
+// asks for it, so the request sees the client's latest writes even if the read replica lags behind.
+func readYourWritesMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enabled, _ := strconv.ParseBool(c.GetHeader(readYourWritesHeaderKey)); enabled {
+			c.Request = c.Request.WithContext(db.WithPrimaryReads(c.Request.Context()))
+		}
+		c.Next()
+	}
+}
+
+// requestIDMiddleware takes the request ID from the X-Request-ID header, or generates one,
+// carries it in the request context and returns it in the response header.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := requestid.FromClient(c.GetHeader(requestid.Header))
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+		c.Next()
+	}
+}
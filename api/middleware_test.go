@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/requestid"
 	"github.com/MElghrbawy/simple_bank/token"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -90,3 +92,106 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestReadYourWritesMiddleware(t *testing.T) {
+	testCases := []struct {
+		name        string
+		header      string
+		wantPrimary bool
+	}{
+		{
+			name:        "Enabled",
+			header:      "true",
+			wantPrimary: true,
+		},
+		{
+			name:        "Disabled",
+			header:      "false",
+			wantPrimary: false,
+		},
+		{
+			name:        "NoHeader",
+			wantPrimary: false,
+		},
+		{
+			name:        "Invalid",
+			header:      "please",
+			wantPrimary: false,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			path := "/read_your_writes"
+			server.router.GET(path, func(c *gin.Context) {
+				require.Equal(t, tc.wantPrimary, db.PrimaryReads(c))
+				c.String(http.StatusOK, "OK")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tc.header != "" {
+				req.Header.Set(readYourWritesHeaderKey, tc.header)
+			}
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, req)
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		wantID bool
+	}{
+		{
+			name:   "FromClient",
+			header: "checkout-42",
+			wantID: true,
+		},
+		{
+			name: "Generated",
+		},
+		{
+			name:   "Invalid",
+			header: "not a request id",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			var contextID string
+			path := "/request_id"
+			server.router.GET(path, func(c *gin.Context) {
+				contextID, _ = requestid.FromContext(c)
+				c.String(http.StatusOK, "OK")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tc.header != "" {
+				req.Header.Set(requestid.Header, tc.header)
+			}
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, req)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			responseID := recorder.Header().Get(requestid.Header)
+			require.NotEmpty(t, responseID)
+			require.Equal(t, responseID, contextID)
+			if tc.wantID {
+				require.Equal(t, tc.header, responseID)
+			} else {
+				require.NotEqual(t, tc.header, responseID)
+			}
+		})
+	}
+}
@@ -1,14 +1,17 @@
 package api
 
 import (
-	"fmt"
-
+	"github.com/MElghrbawy/simple_bank/approval"
+	"github.com/MElghrbawy/simple_bank/clientinfo"
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/risk"
 	"github.com/MElghrbawy/simple_bank/token"
+	"github.com/MElghrbawy/simple_bank/tracing"
 	"github.com/MElghrbawy/simple_bank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // Server serves HTTP requests for our banking service.
@@ -17,17 +20,34 @@ type Server struct {
 	store      db.Store
 	router     *gin.Engine
 	tokenMaker token.Maker
+	clientInfo *clientinfo.Extractor
+	risk       risk.Evaluator
+	approvals  approval.Thresholds
 }
 
 // NewServer creates a new HTTP server and set up routing.
-func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+func NewServer(config util.Config, store db.Store, tokenMaker token.Maker) (*Server, error) {
+	clientInfo, err := clientinfo.NewExtractor(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator, err := risk.NewEvaluator(config.RiskRulesPath, store)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create token maker %w", err)
+		return nil, err
 	}
-	server := &Server{config: config, store: store, tokenMaker: tokenMaker}
+
+	approvals, err := approval.NewThresholds(config.ApprovalThresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{config: config, store: store, tokenMaker: tokenMaker, clientInfo: clientInfo, risk: evaluator, approvals: approvals}
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("webhook_event", validWebhookEvent)
+		v.RegisterValidation("webhook_url", validWebhookURL)
+		v.RegisterTagNameFunc(requestFieldName)
 	}
 	server.setupRouter()
 
@@ -36,6 +56,12 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 
 func (server *Server) setupRouter() {
 	router := gin.Default()
+	// let the store see the request context through the gin context
+	router.ContextWithFallback = true
+	router.Use(otelgin.Middleware(tracing.ServiceName))
+	router.Use(requestIDMiddleware())
+	router.Use(clientInfoMiddleware(server.clientInfo))
+	router.Use(readYourWritesMiddleware())
 
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
@@ -46,9 +72,19 @@ func (server *Server) setupRouter() {
 	authRoutes.POST("/accounts", server.createAccount)
 	authRoutes.GET("/accounts/:id", server.getAccount)
 	authRoutes.GET("/accounts", server.listAccounts)
+	authRoutes.GET("/accounts/:id/balance", server.getAccountBalance)
+	authRoutes.GET("/accounts/:id/limits", server.getTransferAllowance)
+	authRoutes.POST("/accounts/:id/freeze", server.updateAccountStatus(db.AccountStatusFrozen))
+	authRoutes.POST("/accounts/:id/unfreeze", server.updateAccountStatus(db.AccountStatusActive))
+	authRoutes.POST("/accounts/:id/close", server.updateAccountStatus(db.AccountStatusClosed))
 
 	authRoutes.POST("/transfers", server.createTransfer)
 
+	authRoutes.POST("/webhooks", server.createWebhookSubscription)
+	authRoutes.GET("/webhooks", server.listWebhookSubscriptions)
+	authRoutes.DELETE("/webhooks/:id", server.deleteWebhookSubscription)
+	authRoutes.GET("/webhooks/:id/deliveries", server.listWebhookDeliveries)
+
 	server.router = router
 
 }
@@ -57,7 +93,3 @@ func (server *Server) setupRouter() {
 func (server *Server) Start(address string) error {
 	return server.router.Run(address)
 }
-
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
-}
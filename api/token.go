@@ -1,11 +1,10 @@
 package api
 
 import (
-	"database/sql"
-	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/MElghrbawy/simple_bank/apperr"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,59 +20,51 @@ type renewAccessTokenResponse struct {
 func (server *Server) renewAccessToken(c *gin.Context) {
 	var req renewAccessTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, bindingError(err))
 		return
 	}
 
 	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
 
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(c, unauthenticatedError(err))
 		return
 	}
 
 	session, err := server.store.GetSession(c, refreshPayload.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
 	if session.IsBlocked {
-		err := fmt.Errorf("blocked session")
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(c, apperr.New(apperr.Unauthenticated, "blocked session"))
 		return
 	}
 
 	if session.Username != refreshPayload.Username {
-		err := fmt.Errorf("incorrect session user")
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(c, apperr.New(apperr.Unauthenticated, "incorrect session user"))
 		return
 	}
 
 	if session.RefreshToken != req.RefreshToken {
-		err := fmt.Errorf("mis-matching session toke")
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(c, apperr.New(apperr.Unauthenticated, "mis-matching session toke"))
 		return
 	}
 
 	if time.Now().After(session.ExpiresAt) {
-		err := fmt.Errorf("session expired")
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(c, apperr.New(apperr.Unauthenticated, "session expired"))
 		return
 	}
 
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(session.Username, server.config.AccessTokenDuration)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
@@ -1,34 +1,129 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
-	"fmt"
+	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/MElghrbawy/simple_bank/apperr"
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/risk"
 	"github.com/MElghrbawy/simple_bank/token"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
+// transferRequest takes the amount as a decimal in major units of the currency, e.g. "12.34",
+// sent either as a JSON string or a JSON number.
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountId   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,currency"`
+	FromAccountID int64       `json:"from_account_id" binding:"required,min=1"`
+	ToAccountId   int64       `json:"to_account_id" binding:"required,min=1"`
+	Amount        json.Number `json:"amount" binding:"required"`
+	Currency      string      `json:"currency" binding:"required,currency"`
+}
+
+type entryResponse struct {
+	ID        int64        `json:"id"`
+	AccountID int64        `json:"account_id"`
+	Amount    money.Amount `json:"amount"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func newEntryResponse(entry db.Entry, currency string) *entryResponse {
+	return &entryResponse{
+		ID:        entry.ID,
+		AccountID: entry.AccountID,
+		Amount:    amountIn(entry.Amount, currency),
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+type transferResponse struct {
+	ID            int64           `json:"id"`
+	FromAccountID int64           `json:"from_account_id"`
+	ToAccountID   int64           `json:"to_account_id"`
+	Amount        money.Amount    `json:"amount"`
+	Fee           money.Amount    `json:"fee"`
+	CreatedAt     time.Time       `json:"created_at"`
+	FromAccount   accountResponse `json:"from_account"`
+	ToAccount     accountResponse `json:"to_account"`
+	FromEntry     *entryResponse  `json:"from_entry"`
+	ToEntry       *entryResponse  `json:"to_entry"`
+	FeeEntry      *entryResponse  `json:"fee_entry,omitempty"`
+}
+
+// newTransferResponse renders the amounts of a transfer in the currency of the account they move on.
+// The fee is charged in the currency of the sending account.
+func newTransferResponse(result db.TransferTxResult) transferResponse {
+	fromCurrency := result.FromAccount.Currency
+	rsp := transferResponse{
+		ID:            result.Transfer.ID,
+		FromAccountID: result.Transfer.FromAccountID,
+		ToAccountID:   result.Transfer.ToAccountID,
+		Amount:        amountIn(result.Transfer.Amount, fromCurrency),
+		Fee:           amountIn(result.Fee.Total, fromCurrency),
+		CreatedAt:     result.Transfer.CreatedAt,
+		FromAccount:   newAccountResponse(result.FromAccount),
+		ToAccount:     newAccountResponse(result.ToAccount),
+		FromEntry:     newEntryResponse(result.FromEntry, fromCurrency),
+		ToEntry:       newEntryResponse(result.ToEntry, result.ToAccount.Currency),
+	}
+	if result.FeeEntry != nil {
+		rsp.FeeEntry = newEntryResponse(*result.FeeEntry, fromCurrency)
+	}
+	return rsp
+}
+
+type pendingTransferResponse struct {
+	ID            int64                    `json:"id"`
+	FromAccountID int64                    `json:"from_account_id"`
+	ToAccountID   int64                    `json:"to_account_id"`
+	Amount        money.Amount             `json:"amount"`
+	RequestedBy   string                   `json:"requested_by"`
+	Reason        string                   `json:"reason"`
+	Status        db.PendingTransferStatus `json:"status"`
+	CreatedAt     time.Time                `json:"created_at"`
+	ExpiresAt     time.Time                `json:"expires_at"`
+}
+
+// newPendingTransferResponse renders a transfer waiting for approval, whose amount is in the currency of the sending account.
+func newPendingTransferResponse(pending db.PendingTransfer, currency string) pendingTransferResponse {
+	return pendingTransferResponse{
+		ID:            pending.ID,
+		FromAccountID: pending.FromAccountID,
+		ToAccountID:   pending.ToAccountID,
+		Amount:        amountIn(pending.Amount, currency),
+		RequestedBy:   pending.RequestedBy,
+		Reason:        pending.Reason,
+		Status:        pending.Status,
+		CreatedAt:     pending.CreatedAt,
+		ExpiresAt:     pending.ExpiresAt,
+	}
 }
 
 func (server *Server) createTransfer(c *gin.Context) {
 	var req transferRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, bindingError(err))
+		return
+	}
+
+	amount, err := money.Parse(req.Amount.String(), req.Currency)
+	if err != nil {
+		writeError(c, apperr.Invalid(apperr.FieldViolation{Field: "amount", Description: err.Error()}))
+		return
+	}
+	if !amount.IsPositive() {
+		writeError(c, apperr.Invalid(apperr.FieldViolation{Field: "amount", Description: "must be positive"}))
 		return
 	}
 
 	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountId,
-		Amount:        req.Amount,
+		Amount:        amount,
 	}
 
 	fromAccount, valid := server.validAccount(c, arg.FromAccountID, req.Currency)
@@ -39,8 +134,7 @@ func (server *Server) createTransfer(c *gin.Context) {
 
 	authorizationPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
 	if fromAccount.Owner != authorizationPayload.Username {
-		err := errors.New("account %d does not belong to the user")
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(c, errAccountNotOwned)
 		return
 	}
 
@@ -49,30 +143,76 @@ func (server *Server) createTransfer(c *gin.Context) {
 		return
 	}
 
+	transfer := risk.Transfer{
+		Username:      authorizationPayload.Username,
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ClientIP:      clientInfoOf(c).ClientIP,
+	}
+	assessment, err := server.risk.Evaluate(c, transfer)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if assessment.Decision == risk.Deny {
+		server.recordDeclinedTransfer(c, arg, assessment)
+		writeError(c, risk.ErrDeclined)
+		return
+	}
+
+	if reasons := server.approvals.Reasons(arg.Amount, assessment); len(reasons) > 0 {
+		pending, err := server.store.CreatePendingTransferTx(c, db.CreatePendingTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount.Units,
+			RequestedBy:   authorizationPayload.Username,
+			Reason:        strings.Join(reasons, "; "),
+			ExpiresAt:     time.Now().Add(server.config.PendingTransferTTL),
+		})
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, newPendingTransferResponse(pending, arg.Amount.Currency.Code))
+		return
+	}
+
 	result, err := server.store.TransferTx(c, arg)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, newTransferResponse(result))
+}
 
+// recordDeclinedTransfer keeps the reasons a transfer was declined in the audit log, as the client is not told.
+func (server *Server) recordDeclinedTransfer(c *gin.Context, arg db.TransferTxParams, assessment risk.Assessment) {
+	_, err := server.store.RecordAuditEventTx(c, db.RecordAuditEventTxParams{
+		Action:  db.AuditTransferDeclined,
+		Subject: db.AuditSubject("account", arg.FromAccountID),
+		After: map[string]any{
+			"transfer":   arg,
+			"assessment": assessment,
+		},
+	})
+	if err != nil {
+		log.Ctx(c).Error().Err(err).Msg("cannot record declined transfer")
+	}
 }
 
 func (server *Server) validAccount(c *gin.Context, accountID int64, currency string) (db.Account, bool) {
 	account, err := server.store.GetAccount(c, accountID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, errorResponse(err))
-			return account, false
-		}
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
-
+		writeError(c, err)
+		return account, false
 	}
 
 	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency)
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, apperr.Newf(apperr.Validation, "account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency))
 		return account, false
 	}
 
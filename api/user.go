@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -8,7 +9,7 @@ import (
 	"github.com/MElghrbawy/simple_bank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
 )
 
 type createUserRequest struct {
@@ -39,13 +40,13 @@ func newUserResponse(user db.User) userResponse {
 func (server *Server) createUser(c *gin.Context) {
 	var req createUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, bindingError(err))
 		return
 	}
 
 	hashedPassword, err := util.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
@@ -56,21 +57,14 @@ func (server *Server) createUser(c *gin.Context) {
 		Email:          req.Email,
 	}
 
-	user, err := server.store.CreateUser(c, arg)
+	withActor(c, req.Username)
+	result, err := server.store.CreateUserTx(c, db.CreateUserTxParams{CreateUserParams: arg})
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				c.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
-		}
-
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
-	rsp := newUserResponse(user)
+	rsp := newUserResponse(result.User)
 
 	c.JSON(http.StatusOK, rsp)
 
@@ -93,45 +87,53 @@ type loginUserResponse struct {
 func (server *Server) loginUser(c *gin.Context) {
 	var req loginUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(c, bindingError(err))
 		return
 	}
 
+	withActor(c, req.Username)
 	user, err := server.store.GetUser(c, req.Username)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		if errors.Is(err, db.ErrRecordNotFound) {
+			// check the password anyway, or the quicker response would give away that the user does not exist
+			util.CheckUnknownUserPassword(req.Password)
+			server.recordFailedLogin(c, req.Username, "user not found")
+			err = errInvalidCredentials
+		}
+		writeError(c, err)
 		return
 	}
 
 	err = util.CheckPasswordHash(req.Password, user.HashedPassword)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		server.recordFailedLogin(c, req.Username, "wrong password")
+		writeError(c, errInvalidCredentials)
 		return
 	}
 
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
 	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.RefreshTokenDuration)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
-	session, err := server.store.CreateSession(c, db.CreateSessionParams{
+	session, err := server.store.CreateSessionTx(c, db.CreateSessionParams{
 		ID:           refreshPayload.ID,
 		Username:     user.Username,
 		RefreshToken: refreshToken,
-		UserAgent:    c.Request.UserAgent(),
-		ClientIp:     c.ClientIP(),
+		UserAgent:    clientInfoOf(c).UserAgent,
+		ClientIp:     clientInfoOf(c).ClientIP,
 		IsBlocked:    false,
 		ExpiresAt:    refreshPayload.ExpiresAt,
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(c, err)
 		return
 	}
 
@@ -146,3 +148,16 @@ func (server *Server) loginUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, rsp)
 }
+
+// recordFailedLogin records a failed login in the audit log. The client is told its credentials
+// are invalid whether the log is written or not.
+func (server *Server) recordFailedLogin(c *gin.Context, username string, reason string) {
+	_, err := server.store.RecordAuditEventTx(c, db.RecordAuditEventTxParams{
+		Action:  db.AuditUserLoginFailed,
+		Subject: db.AuditSubject("user", username),
+		After:   map[string]string{"reason": reason},
+	})
+	if err != nil {
+		log.Ctx(c).Error().Err(err).Msg("cannot record failed login")
+	}
+}
@@ -1,7 +1,11 @@
 package api
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/MElghrbawy/simple_bank/webhook"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -11,3 +15,28 @@ var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validWebhookEvent validator.Func = func(fl validator.FieldLevel) bool {
+	if eventType, ok := fl.Field().Interface().(string); ok {
+		return webhook.IsSupportedEventType(eventType)
+	}
+	return false
+}
+
+var validWebhookURL validator.Func = func(fl validator.FieldLevel) bool {
+	if url, ok := fl.Field().Interface().(string); ok {
+		return webhook.ValidateURL(url) == nil
+	}
+	return false
+}
+
+// requestFieldName names the fields of a request after their JSON, URI or query parameter,
+// so validation errors point at the field the client sent.
+func requestFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "uri", "form"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
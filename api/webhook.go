@@ -0,0 +1,163 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// webhookSecretSize is the number of random bytes of a subscription secret.
+const webhookSecretSize = 32
+
+type webhookSubscriptionResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// newWebhookSubscriptionResponse leaves the secret out, it is only returned when the subscription is created.
+func newWebhookSubscriptionResponse(subscription db.WebhookSubscription) webhookSubscriptionResponse {
+	return webhookSubscriptionResponse{
+		ID:         subscription.ID,
+		URL:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+type createWebhookSubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required,webhook_url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,webhook_event"`
+}
+
+func (server *Server) createWebhookSubscription(c *gin.Context) {
+	var req createWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, bindingError(err))
+		return
+	}
+
+	secret := make([]byte, webhookSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.CreateWebhookSubscriptionParams{
+		Owner:      authPayload.Username,
+		Url:        req.URL,
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: req.EventTypes,
+	}
+
+	subscription, err := server.store.CreateWebhookSubscription(c, arg)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rsp := newWebhookSubscriptionResponse(subscription)
+	rsp.Secret = subscription.Secret
+	c.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) listWebhookSubscriptions(c *gin.Context) {
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	subscriptions, err := server.store.ListWebhookSubscriptions(c, authPayload.Username)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rsp := make([]webhookSubscriptionResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		rsp[i] = newWebhookSubscriptionResponse(subscription)
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+type webhookSubscriptionRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) deleteWebhookSubscription(c *gin.Context) {
+	var req webhookSubscriptionRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		writeError(c, bindingError(err))
+		return
+	}
+
+	if _, ok := server.ownedWebhookSubscription(c, req.ID); !ok {
+		return
+	}
+
+	err := server.store.DeleteWebhookSubscription(c, req.ID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listWebhookDeliveries(c *gin.Context) {
+	var uri webhookSubscriptionRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		writeError(c, bindingError(err))
+		return
+	}
+
+	var req listWebhookDeliveriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeError(c, bindingError(err))
+		return
+	}
+
+	if _, ok := server.ownedWebhookSubscription(c, uri.ID); !ok {
+		return
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveries(c, db.ListWebhookDeliveriesParams{
+		SubscriptionID: uri.ID,
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ownedWebhookSubscription fetches a subscription and checks it belongs to the authenticated user.
+func (server *Server) ownedWebhookSubscription(c *gin.Context, id int64) (db.WebhookSubscription, bool) {
+	subscription, err := server.store.GetWebhookSubscription(c, id)
+	if err != nil {
+		writeError(c, err)
+		return subscription, false
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if subscription.Owner != authPayload.Username {
+		writeError(c, errSubscriptionNotOwned)
+		return subscription, false
+	}
+
+	return subscription, true
+}
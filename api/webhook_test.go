@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/MElghrbawy/simple_bank/db/mock"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/MElghrbawy/simple_bank/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomWebhookSubscription(owner string) db.WebhookSubscription {
+	return db.WebhookSubscription{
+		ID:         util.RandomInt(1, 1000),
+		Owner:      owner,
+		Url:        "https://example.com/" + util.RandomString(6),
+		Secret:     util.RandomString(32),
+		EventTypes: []string{webhook.EventTransferReceived},
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestCreateWebhookSubscription(t *testing.T) {
+	user, _ := randomUser(t)
+	subscription := randomWebhookSubscription(user.Username)
+
+	testCases := []struct {
+		name         string
+		body         gin.H
+		buildStubs   func(store *mockdb.MockStore)
+		checkResults func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"url":         subscription.Url,
+				"event_types": subscription.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, subscription.Url, arg.Url)
+						require.Equal(t, subscription.EventTypes, arg.EventTypes)
+						require.Len(t, arg.Secret, webhookSecretSize*2)
+						return subscription, nil
+					})
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got webhookSubscriptionResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, subscription.ID, got.ID)
+				require.Equal(t, subscription.Secret, got.Secret)
+			},
+		},
+		{
+			name: "UnsupportedEventType",
+			body: gin.H{
+				"url":         subscription.Url,
+				"event_types": []string{"transfer.deleted"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsecureURL",
+			body: gin.H{
+				"url":         "http://example.com/hook",
+				"event_types": subscription.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "PrivateAddress",
+			body: gin.H{
+				"url":         "https://169.254.169.254/latest/meta-data",
+				"event_types": subscription.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidURL",
+			body: gin.H{
+				"url":         "not a url",
+				"event_types": subscription.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		tc.buildStubs(store)
+
+		server := newTestServer(t, store)
+		recorder := httptest.NewRecorder()
+
+		data, err := json.Marshal(tc.body)
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(data))
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		tc.checkResults(t, recorder)
+	}
+}
+
+func TestListWebhookSubscriptions(t *testing.T) {
+	user, _ := randomUser(t)
+	subscription := randomWebhookSubscription(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListWebhookSubscriptions(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return([]db.WebhookSubscription{subscription}, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/webhooks", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotContains(t, recorder.Body.String(), subscription.Secret)
+}
+
+func TestDeleteWebhookSubscription(t *testing.T) {
+	user, _ := randomUser(t)
+	otherUser, _ := randomUser(t)
+	subscription := randomWebhookSubscription(user.Username)
+
+	testCases := []struct {
+		name         string
+		username     string
+		buildStubs   func(store *mockdb.MockStore)
+		checkResults func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().DeleteWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(nil)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: otherUser.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().DeleteWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResults: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		tc.buildStubs(store)
+
+		server := newTestServer(t, store)
+		recorder := httptest.NewRecorder()
+
+		url := fmt.Sprintf("/webhooks/%d", subscription.ID)
+		request, err := http.NewRequest(http.MethodDelete, url, nil)
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		tc.checkResults(t, recorder)
+	}
+}
@@ -0,0 +1,89 @@
+// Package apperr defines the errors the application reports to its clients,
+// so the REST and gRPC servers describe the same failure the same way.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies an error by how clients should react to it.
+type Kind string
+
+const (
+	Internal           Kind = "internal"
+	Validation         Kind = "validation"
+	NotFound           Kind = "not_found"
+	AlreadyExists      Kind = "already_exists"
+	Unauthenticated    Kind = "unauthenticated"
+	PermissionDenied   Kind = "permission_denied"
+	InsufficientFunds  Kind = "insufficient_funds"
+	FailedPrecondition Kind = "failed_precondition"
+	Unavailable        Kind = "unavailable"
+	RateLimited        Kind = "rate_limited"
+)
+
+// FieldViolation describes an invalid field of a request.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Error is an error whose message is safe to show to clients.
+// The cause it wraps is kept for errors.Is, errors.As and the logs, but never reported.
+// Error appends the cause to the message unless it is an *Error or the message already restates it.
+type Error struct {
+	Kind       Kind
+	Message    string
+	Violations []FieldViolation
+	Err        error
+}
+
+func (e *Error) Error() string {
+	var cause *Error
+	if e.Err == nil || errors.As(e.Err, &cause) || e.Err.Error() == e.Message {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func Newf(kind Kind, format string, args ...any) *Error {
+	return New(kind, fmt.Sprintf(format, args...))
+}
+
+// Wrap returns an error of the given kind caused by err.
+func Wrap(err error, kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+func Wrapf(err error, kind Kind, format string, args ...any) *Error {
+	return Wrap(err, kind, fmt.Sprintf(format, args...))
+}
+
+// Invalid returns a validation error listing the invalid fields of a request.
+func Invalid(violations ...FieldViolation) *Error {
+	return &Error{Kind: Validation, Message: "invalid argument", Violations: violations}
+}
+
+// From returns the first *Error in the chain of err. Any other error is internal,
+// reported with a generic message so its details do not leak to clients.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Wrap(err, Internal, "internal error")
+}
+
+// KindOf returns the kind of err.
+func KindOf(err error) Kind {
+	return From(err).Kind
+}
@@ -0,0 +1,121 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+func TestError(t *testing.T) {
+	cause := errors.New("connection reset")
+	notFound := New(NotFound, "account not found")
+
+	testCases := []struct {
+		name    string
+		err     error
+		message string
+		kind    Kind
+	}{
+		{
+			name:    "New",
+			err:     notFound,
+			message: "account not found",
+			kind:    NotFound,
+		},
+		{
+			name:    "Wrap",
+			err:     Wrap(cause, Unavailable, "database unavailable"),
+			message: "database unavailable: connection reset",
+			kind:    Unavailable,
+		},
+		{
+			name:    "WrapError",
+			err:     Wrapf(notFound, NotFound, "account %d not found", 7),
+			message: "account 7 not found",
+			kind:    NotFound,
+		},
+		{
+			name:    "WrapRestated",
+			err:     Wrap(cause, FailedPrecondition, cause.Error()),
+			message: "connection reset",
+			kind:    FailedPrecondition,
+		},
+		{
+			name:    "Wrapped",
+			err:     fmt.Errorf("get account: %w", notFound),
+			message: "get account: account not found",
+			kind:    NotFound,
+		},
+		{
+			name:    "Internal",
+			err:     cause,
+			message: "connection reset",
+			kind:    Internal,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualError(t, tc.err, tc.message)
+			require.Equal(t, tc.kind, KindOf(tc.err))
+		})
+	}
+
+	require.ErrorIs(t, Wrap(cause, Internal, "internal error"), cause)
+	require.Equal(t, "internal error", From(cause).Message)
+}
+
+func TestNewProblem(t *testing.T) {
+	err := Invalid(FieldViolation{Field: "currency", Description: "unsupported currency"})
+
+	problem := NewProblem(fmt.Errorf("bind request: %w", err), "/accounts")
+	require.Equal(t, Problem{
+		Type:     "/problems/validation",
+		Title:    "Invalid request",
+		Status:   http.StatusBadRequest,
+		Detail:   "invalid argument",
+		Instance: "/accounts",
+		Errors:   []FieldViolation{{Field: "currency", Description: "unsupported currency"}},
+	}, problem)
+
+	problem = NewProblem(errors.New("pq: password authentication failed"), "/accounts")
+	require.Equal(t, http.StatusInternalServerError, problem.Status)
+	require.Equal(t, "internal error", problem.Detail)
+
+	problem = NewProblem(New(Kind("unknown"), "unknown kind"), "/accounts")
+	require.Equal(t, "/problems/internal", problem.Type)
+	require.Equal(t, http.StatusInternalServerError, problem.Status)
+}
+
+func TestGRPCStatus(t *testing.T) {
+	st := GRPCStatus(New(InsufficientFunds, "insufficient available balance"))
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+	require.Equal(t, "insufficient available balance", st.Message())
+	require.Len(t, st.Details(), 1)
+
+	errorInfo, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, "INSUFFICIENT_FUNDS", errorInfo.GetReason())
+	require.Equal(t, ErrorDomain, errorInfo.GetDomain())
+
+	st = GRPCStatus(Invalid(FieldViolation{Field: "amount", Description: "must be positive"}))
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 2)
+
+	badRequest, ok := st.Details()[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, badRequest.GetFieldViolations(), 1)
+	require.Equal(t, "amount", badRequest.GetFieldViolations()[0].GetField())
+	require.Equal(t, "must be positive", badRequest.GetFieldViolations()[0].GetDescription())
+
+	st = GRPCStatus(errors.New("connection reset"))
+	require.Equal(t, codes.Internal, st.Code())
+	require.Equal(t, "internal error", st.Message())
+}
@@ -0,0 +1,67 @@
+package apperr
+
+import (
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the ErrorInfo details of gRPC errors.
+const ErrorDomain = "simplebank"
+
+var grpcCodes = map[Kind]codes.Code{
+	Validation:         codes.InvalidArgument,
+	NotFound:           codes.NotFound,
+	AlreadyExists:      codes.AlreadyExists,
+	Unauthenticated:    codes.Unauthenticated,
+	PermissionDenied:   codes.PermissionDenied,
+	InsufficientFunds:  codes.FailedPrecondition,
+	FailedPrecondition: codes.FailedPrecondition,
+	Unavailable:        codes.Unavailable,
+	RateLimited:        codes.ResourceExhausted,
+}
+
+// GRPCCode returns the gRPC status code of an error kind.
+func GRPCCode(kind Kind) codes.Code {
+	if code, ok := grpcCodes[kind]; ok {
+		return code
+	}
+	return codes.Internal
+}
+
+// GRPCStatus describes err to a client of the gRPC API. The kind is detailed by an ErrorInfo
+// whose reason is the upper case kind, and the invalid fields by a BadRequest.
+func GRPCStatus(err error) *status.Status {
+	appErr := From(err)
+	st := status.New(GRPCCode(appErr.Kind), appErr.Message)
+
+	errorInfo := &errdetails.ErrorInfo{
+		Reason: strings.ToUpper(string(appErr.Kind)),
+		Domain: ErrorDomain,
+	}
+
+	var withDetails *status.Status
+	if len(appErr.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, violation := range appErr.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+		withDetails, err = st.WithDetails(errorInfo, badRequest)
+	} else {
+		withDetails, err = st.WithDetails(errorInfo)
+	}
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// GRPCError returns the gRPC status error describing err.
+func GRPCError(err error) error {
+	return GRPCStatus(err).Err()
+}
@@ -0,0 +1,68 @@
+package apperr
+
+import "net/http"
+
+// ProblemContentType is the media type of Problem responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object.
+type Problem struct {
+	Type     string           `json:"type"`
+	Title    string           `json:"title"`
+	Status   int              `json:"status"`
+	Detail   string           `json:"detail,omitempty"`
+	Instance string           `json:"instance,omitempty"`
+	Errors   []FieldViolation `json:"errors,omitempty"`
+}
+
+var httpStatuses = map[Kind]int{
+	Validation:         http.StatusBadRequest,
+	NotFound:           http.StatusNotFound,
+	AlreadyExists:      http.StatusConflict,
+	Unauthenticated:    http.StatusUnauthorized,
+	PermissionDenied:   http.StatusForbidden,
+	InsufficientFunds:  http.StatusUnprocessableEntity,
+	FailedPrecondition: http.StatusUnprocessableEntity,
+	Unavailable:        http.StatusServiceUnavailable,
+	RateLimited:        http.StatusTooManyRequests,
+}
+
+var titles = map[Kind]string{
+	Internal:           "Internal error",
+	Validation:         "Invalid request",
+	NotFound:           "Not found",
+	AlreadyExists:      "Already exists",
+	Unauthenticated:    "Unauthenticated",
+	PermissionDenied:   "Permission denied",
+	InsufficientFunds:  "Insufficient funds",
+	FailedPrecondition: "Failed precondition",
+	Unavailable:        "Temporarily unavailable",
+	RateLimited:        "Too many requests",
+}
+
+// HTTPStatus returns the HTTP status code of an error kind.
+func HTTPStatus(kind Kind) int {
+	if status, ok := httpStatuses[kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// NewProblem describes err to a client of the REST API. instance identifies the
+// request that failed, usually its path.
+func NewProblem(err error, instance string) Problem {
+	appErr := From(err)
+	kind := appErr.Kind
+	if _, ok := titles[kind]; !ok {
+		kind = Internal
+	}
+
+	return Problem{
+		Type:     "/problems/" + string(kind),
+		Title:    titles[kind],
+		Status:   HTTPStatus(kind),
+		Detail:   appErr.Message,
+		Instance: instance,
+		Errors:   appErr.Violations,
+	}
+}
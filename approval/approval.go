@@ -0,0 +1,73 @@
+// Package approval decides which transfers need a second person to approve them before they are made.
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/risk"
+)
+
+// Thresholds holds the amount above which a transfer needs approval, in minor units, keyed by currency.
+// The transfers in the other currencies never need approval.
+type Thresholds map[string]int64
+
+// LoadThresholds reads the approval thresholds per currency from a JSON file such as
+//
+//	{"USD": 1000000, "EUR": 1000000}
+func LoadThresholds(path string) (Thresholds, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read approval thresholds: %w", err)
+	}
+
+	var thresholds Thresholds
+	if err := json.Unmarshal(data, &thresholds); err != nil {
+		return nil, fmt.Errorf("cannot parse approval thresholds: %w", err)
+	}
+
+	for currency, threshold := range thresholds {
+		if threshold < 0 {
+			return nil, fmt.Errorf("%s approval threshold must not be negative", currency)
+		}
+	}
+	return thresholds, nil
+}
+
+// Required reports whether a transfer of amount needs approval.
+func (t Thresholds) Required(amount money.Amount) bool {
+	threshold, ok := t[amount.Currency.Code]
+	return ok && amount.Units > threshold
+}
+
+// Reason tells why a transfer of amount needs approval, or returns "" if it does not.
+func (t Thresholds) Reason(amount money.Amount) string {
+	if !t.Required(amount) {
+		return ""
+	}
+	threshold := money.Amount{Units: t[amount.Currency.Code], Currency: amount.Currency}
+	return fmt.Sprintf("amount above the approval threshold of %s %s", threshold, amount.Currency.Code)
+}
+
+// Reasons tells why a transfer of amount, given the assessment of the risk checks, needs approval,
+// or returns none if it can be made right away.
+func (t Thresholds) Reasons(amount money.Amount, assessment risk.Assessment) []string {
+	var reasons []string
+	if assessment.Decision == risk.Review {
+		reasons = append(reasons, assessment.Reasons...)
+	}
+	if reason := t.Reason(amount); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	return reasons
+}
+
+// NewThresholds reads the thresholds of path, or returns none without path, so no transfer needs approval.
+func NewThresholds(path string) (Thresholds, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return LoadThresholds(path)
+}
@@ -0,0 +1,56 @@
+package approval
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/risk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequired(t *testing.T) {
+	thresholds := Thresholds{"USD": 1_000_000}
+
+	require.False(t, thresholds.Required(money.MustNew(1_000_000, "USD")))
+	require.Empty(t, thresholds.Reason(money.MustNew(1_000_000, "USD")))
+
+	require.True(t, thresholds.Required(money.MustNew(1_000_001, "USD")))
+	require.Equal(t, "amount above the approval threshold of 10000.00 USD", thresholds.Reason(money.MustNew(1_000_001, "USD")))
+
+	require.False(t, thresholds.Required(money.MustNew(1_000_000_000, "EUR")))
+	require.False(t, Thresholds(nil).Required(money.MustNew(1_000_000_000, "USD")))
+}
+
+func TestLoadThresholds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approval.json")
+	err := os.WriteFile(path, []byte(`{"USD": 1000000, "EUR": 500000}`), 0o600)
+	require.NoError(t, err)
+
+	thresholds, err := NewThresholds(path)
+	require.NoError(t, err)
+	require.Equal(t, Thresholds{"USD": 1_000_000, "EUR": 500_000}, thresholds)
+
+	err = os.WriteFile(path, []byte(`{"USD": -1}`), 0o600)
+	require.NoError(t, err)
+
+	_, err = LoadThresholds(path)
+	require.Error(t, err)
+
+	thresholds, err = NewThresholds("")
+	require.NoError(t, err)
+	require.Empty(t, thresholds)
+}
+
+func TestReasons(t *testing.T) {
+	thresholds := Thresholds{"USD": 1_000_000}
+	review := risk.Assessment{Decision: risk.Review, Reasons: []string{"unusual client IP"}}
+
+	require.Empty(t, thresholds.Reasons(money.MustNew(100, "USD"), risk.Assessment{Decision: risk.Allow}))
+	require.Equal(t, []string{"unusual client IP"}, thresholds.Reasons(money.MustNew(100, "USD"), review))
+	require.Equal(t,
+		[]string{"unusual client IP", "amount above the approval threshold of 10000.00 USD"},
+		thresholds.Reasons(money.MustNew(2_000_000, "USD"), review),
+	)
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/spf13/cobra"
+)
+
+var accountHeader = []string{"ID", "OWNER", "CURRENCY", "BALANCE", "STATUS", "CREATED AT"}
+
+func accountRow(account db.Account) []string {
+	return []string{
+		strconv.FormatInt(account.ID, 10),
+		account.Owner,
+		account.Currency,
+		formatUnits(account.Balance, account.Currency),
+		string(account.Status),
+		formatTime(account.CreatedAt),
+	}
+}
+
+func (a *app) printAccounts(cmd *cobra.Command, value any, accounts ...db.Account) error {
+	rows := make([][]string, len(accounts))
+	for i, account := range accounts {
+		rows[i] = accountRow(account)
+	}
+	return a.print(cmd.OutOrStdout(), value, accountHeader, rows...)
+}
+
+// parseID reads the id argument of a command.
+func parseID(name string, arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, arg)
+	}
+	return id, nil
+}
+
+func (a *app) accountCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "account",
+		Short: "Manage accounts",
+	}
+	cmd.AddCommand(
+		a.openAccountCommand(),
+		a.getAccountCommand(),
+		a.listAccountsCommand(),
+		a.accountStatusCommand("freeze", "Freeze an account, blocking the money moving in and out of it", db.AccountStatusFrozen),
+		a.accountStatusCommand("unfreeze", "Unfreeze a frozen account", db.AccountStatusActive),
+		a.accountStatusCommand("close", "Close an account with a zero balance", db.AccountStatusClosed),
+	)
+	return cmd
+}
+
+func (a *app) openAccountCommand() *cobra.Command {
+	var arg db.CreateAccountParams
+
+	cmd := &cobra.Command{
+		Use:   "open",
+		Short: "Open an account with a zero balance",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !util.IsSupportedCurrency(arg.Currency) {
+				return fmt.Errorf("unsupported currency %q", arg.Currency)
+			}
+
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			account, err := store.CreateAccountTx(cmd.Context(), arg)
+			if err != nil {
+				return err
+			}
+			return a.printAccounts(cmd, account, account)
+		},
+	}
+	cmd.Flags().StringVar(&arg.Owner, "owner", "", "username of the account owner")
+	cmd.Flags().StringVar(&arg.Currency, "currency", "", "currency of the account")
+	cmd.MarkFlagRequired("owner")
+	cmd.MarkFlagRequired("currency")
+	return cmd
+}
+
+func (a *app) getAccountCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get ACCOUNT_ID",
+		Short: "Show an account",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID("account id", args[0])
+			if err != nil {
+				return err
+			}
+
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			account, err := store.GetAccount(db.WithPrimaryReads(cmd.Context()), id)
+			if err != nil {
+				return err
+			}
+			return a.printAccounts(cmd, account, account)
+		},
+	}
+}
+
+func (a *app) listAccountsCommand() *cobra.Command {
+	arg := db.ListAccountsParams{Limit: 50}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the accounts of a user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			accounts, err := store.ListAccounts(db.WithPrimaryReads(cmd.Context()), arg)
+			if err != nil {
+				return err
+			}
+			return a.printAccounts(cmd, accounts, accounts...)
+		},
+	}
+	cmd.Flags().StringVar(&arg.Owner, "owner", "", "username of the accounts owner")
+	cmd.Flags().Int32Var(&arg.Limit, "limit", arg.Limit, "maximum number of accounts")
+	cmd.Flags().Int32Var(&arg.Offset, "offset", 0, "number of accounts to skip")
+	cmd.MarkFlagRequired("owner")
+	return cmd
+}
+
+func (a *app) accountStatusCommand(use string, short string, status db.AccountStatus) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " ACCOUNT_ID",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID("account id", args[0])
+			if err != nil {
+				return err
+			}
+
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			account, err := store.UpdateAccountStatusTx(cmd.Context(), db.UpdateAccountStatusTxParams{
+				AccountID: id,
+				Status:    status,
+				ChangedBy: db.ActorFromContext(cmd.Context()).Username,
+			})
+			if err != nil {
+				return err
+			}
+			return a.printAccounts(cmd, account, account)
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) auditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Read and verify the audit log",
+	}
+	cmd.AddCommand(
+		a.listAuditEventsCommand(),
+		a.verifyAuditChainCommand(),
+	)
+	return cmd
+}
+
+func (a *app) listAuditEventsCommand() *cobra.Command {
+	var actor, subject, action string
+	arg := db.ListAuditEventsParams{Limit: 50}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the audit events, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			arg.Actor = pgtype.Text{String: actor, Valid: actor != ""}
+			arg.Subject = pgtype.Text{String: subject, Valid: subject != ""}
+			arg.Action = pgtype.Text{String: action, Valid: action != ""}
+
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			events, err := store.ListAuditEvents(cmd.Context(), arg)
+			if err != nil {
+				return err
+			}
+
+			rows := make([][]string, len(events))
+			for i, event := range events {
+				rows[i] = []string{
+					strconv.FormatInt(event.ID, 10),
+					formatTime(event.CreatedAt),
+					event.Action,
+					event.Actor,
+					event.Subject,
+					event.ClientIp,
+				}
+			}
+			return a.print(cmd.OutOrStdout(), events,
+				[]string{"ID", "CREATED AT", "ACTION", "ACTOR", "SUBJECT", "CLIENT IP"},
+				rows...,
+			)
+		},
+	}
+	cmd.Flags().StringVar(&actor, "actor", "", "only the events of this actor")
+	cmd.Flags().StringVar(&subject, "subject", "", `only the events of this subject, e.g. "account:7"`)
+	cmd.Flags().StringVar(&action, "action", "", `only the events of this action, e.g. "user.login_failed"`)
+	cmd.Flags().Int64Var(&arg.AfterID, "after", 0, "only the events following this event id")
+	cmd.Flags().Int32Var(&arg.Limit, "limit", arg.Limit, "maximum number of events")
+	return cmd
+}
+
+func (a *app) verifyAuditChainCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check the audit log was not tampered with",
+		Long:  "Check every audit event matches its hash and chains to the previous one, and fail if not so the command can run from a scheduler.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			report, err := store.VerifyAuditChainTx(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			status := "intact"
+			if !report.Intact() {
+				status = "broken"
+			}
+			err = a.print(cmd.OutOrStdout(), report,
+				[]string{"EVENTS", "STATUS", "BROKEN AT", "PROBLEM"},
+				[]string{strconv.FormatInt(report.Events, 10), status, strconv.FormatInt(report.BrokenAt, 10), report.Problem},
+			)
+			if err != nil {
+				return err
+			}
+
+			if !report.Intact() {
+				return fmt.Errorf("the audit log is broken at event %d: %s", report.BrokenAt, report.Problem)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// runCommand runs simplebank on store with the config of the repository, and returns its output.
+func runCommand(t *testing.T, store db.Store, stdin string, args ...string) (string, error) {
+	cmd := NewRootCommand(func(ctx context.Context, config util.Config) (db.Store, func(), error) {
+		return store, func() {}, nil
+	})
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetArgs(append([]string{"--config", ".."}, args...))
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func runJSONCommand(t *testing.T, store db.Store, result any, args ...string) {
+	out, err := runCommand(t, store, "", append([]string{"--output", "json"}, args...)...)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(out), result))
+}
+
+func createUser(t *testing.T, store db.Store) string {
+	username := util.RandomOwner()
+	_, err := runCommand(t, store, "secret\n", "user", "create",
+		"--username", username,
+		"--full-name", "Jane Doe",
+		"--email", util.RandomEmail(),
+	)
+	require.NoError(t, err)
+	return username
+}
+
+func TestCreateUser(t *testing.T) {
+	store := db.NewMemStore()
+	username := util.RandomOwner()
+
+	var user userOutput
+	runJSONCommand(t, store, &user, "user", "create",
+		"--username", username,
+		"--full-name", "Jane Doe",
+		"--email", "jane@email.com",
+		"--password", "secret",
+	)
+	require.Equal(t, username, user.Username)
+
+	got, err := store.GetUser(context.Background(), username)
+	require.NoError(t, err)
+	require.NoError(t, util.CheckPasswordHash("secret", got.HashedPassword))
+
+	_, err = runCommand(t, store, "", "user", "create",
+		"--username", username,
+		"--full-name", "Jane Doe",
+		"--email", "other@email.com",
+		"--password", "secret",
+	)
+	require.Equal(t, apperr.AlreadyExists, apperr.KindOf(err))
+
+	_, err = runCommand(t, store, "", "user", "create",
+		"--username", util.RandomOwner(),
+		"--full-name", "Jane Doe",
+		"--email", "not an email",
+		"--password", "secret",
+	)
+	require.Error(t, err)
+
+	_, err = runCommand(t, store, "", "user", "create", "--username", util.RandomOwner())
+	require.Error(t, err)
+}
+
+func TestAccountCommands(t *testing.T) {
+	store := db.NewMemStore()
+	username := createUser(t, store)
+
+	var account db.Account
+	runJSONCommand(t, store, &account, "account", "open", "--owner", username, "--currency", util.USD)
+	require.Equal(t, username, account.Owner)
+	require.Equal(t, db.AccountStatusActive, account.Status)
+	accountID := fmt.Sprint(account.ID)
+
+	var deposit db.DepositTxResult
+	runJSONCommand(t, store, &deposit, "deposit", accountID, "12.34")
+	require.Equal(t, int64(1234), deposit.Account.Balance)
+	require.Equal(t, int64(1234), deposit.Entry.Amount)
+
+	_, err := runCommand(t, store, "", "deposit", accountID, "1.234")
+	require.ErrorIs(t, err, money.ErrInvalidAmount)
+
+	runJSONCommand(t, store, &account, "account", "freeze", accountID)
+	require.Equal(t, db.AccountStatusFrozen, account.Status)
+
+	_, err = runCommand(t, store, "", "deposit", accountID, "1")
+	require.ErrorIs(t, err, db.ErrAccountFrozen)
+
+	_, err = runCommand(t, store, "", "account", "close", accountID)
+	require.ErrorIs(t, err, db.ErrAccountNotEmpty)
+
+	runJSONCommand(t, store, &account, "account", "unfreeze", accountID)
+	require.Equal(t, db.AccountStatusActive, account.Status)
+
+	var accounts []db.Account
+	runJSONCommand(t, store, &accounts, "account", "list", "--owner", username)
+	require.Len(t, accounts, 1)
+	require.Equal(t, int64(1234), accounts[0].Balance)
+
+	out, err := runCommand(t, store, "", "account", "get", accountID)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, []string{"ID", "OWNER", "CURRENCY", "BALANCE", "STATUS", "CREATED", "AT"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{accountID, username, util.USD, "12.34", "active"}, strings.Fields(lines[1])[:5])
+
+	_, err = runCommand(t, store, "", "account", "get", "abc")
+	require.EqualError(t, err, `invalid account id "abc"`)
+
+	_, err = runCommand(t, store, "", "--output", "yaml", "account", "get", accountID)
+	require.Error(t, err)
+}
+
+func TestBlockSessionCommand(t *testing.T) {
+	store := db.NewMemStore()
+	username := createUser(t, store)
+
+	sessions := make([]db.Session, 3)
+	for i := range sessions {
+		session, err := store.CreateSession(context.Background(), db.CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     username,
+			RefreshToken: util.RandomString(32),
+			ExpiresAt:    time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		sessions[i] = session
+	}
+
+	var out blockSessionsOutput
+	runJSONCommand(t, store, &out, "session", "block", sessions[0].ID.String())
+	require.Equal(t, blockSessionsOutput{SessionID: sessions[0].ID.String(), Username: username, Blocked: 1}, out)
+
+	var userOut blockSessionsOutput
+	runJSONCommand(t, store, &userOut, "session", "block", "--user", username)
+	require.Equal(t, blockSessionsOutput{Username: username, Blocked: 2}, userOut)
+
+	for _, session := range sessions {
+		got, err := store.GetSession(context.Background(), session.ID)
+		require.NoError(t, err)
+		require.True(t, got.IsBlocked)
+	}
+
+	_, err := runCommand(t, store, "", "session", "block")
+	require.Error(t, err)
+
+	_, err = runCommand(t, store, "", "session", "block", uuid.NewString())
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func TestReconcileLedgerCommand(t *testing.T) {
+	store := db.NewMemStore()
+	username := createUser(t, store)
+
+	var account db.Account
+	runJSONCommand(t, store, &account, "account", "open", "--owner", username, "--currency", util.USD)
+	runJSONCommand(t, store, &db.DepositTxResult{}, "deposit", fmt.Sprint(account.ID), "10")
+
+	var mismatches []db.ListLedgerMismatchesRow
+	runJSONCommand(t, store, &mismatches, "ledger", "reconcile")
+	require.Empty(t, mismatches)
+
+	_, err := store.AddAccountBalance(context.Background(), db.AddAccountBalanceParams{ID: account.ID, Amount: 5})
+	require.NoError(t, err)
+
+	out, err := runCommand(t, store, "", "--output", "json", "ledger", "reconcile")
+	require.EqualError(t, err, "1 accounts do not match their entries")
+	require.NoError(t, json.Unmarshal([]byte(out), &mismatches))
+	require.Equal(t, []db.ListLedgerMismatchesRow{{
+		ID:           account.ID,
+		Owner:        username,
+		Currency:     util.USD,
+		Balance:      1005,
+		EntriesTotal: 1000,
+	}}, mismatches)
+}
+
+func TestAuditCommands(t *testing.T) {
+	store := db.NewMemStore(db.WithAuditKey([]byte("audit-key")))
+	username := createUser(t, store)
+
+	var account db.Account
+	runJSONCommand(t, store, &account, "account", "open", "--owner", username, "--currency", util.USD)
+	_, err := store.ChainAuditEventsTx(context.Background(), 10)
+	require.NoError(t, err)
+
+	var events []db.AuditEvent
+	runJSONCommand(t, store, &events, "audit", "list", "--subject", db.AuditSubject("account", account.ID))
+	require.Len(t, events, 1)
+	require.Equal(t, db.AuditAccountCreated, events[0].Action)
+	require.True(t, strings.HasPrefix(events[0].Actor, "cli:"))
+	require.Equal(t, "simplebank-cli", events[0].UserAgent)
+
+	runJSONCommand(t, store, &events, "audit", "list", "--after", fmt.Sprint(events[0].ID))
+	require.Empty(t, events)
+
+	var report db.AuditChainReport
+	runJSONCommand(t, store, &report, "audit", "verify")
+	require.True(t, report.Intact())
+	require.EqualValues(t, 2, report.Events)
+}
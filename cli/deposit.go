@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"strconv"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) depositCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "deposit ACCOUNT_ID AMOUNT",
+		Short: "Deposit money into an account",
+		Long:  "Deposit money brought into the bank from outside into an account. The amount is a decimal in the currency of the account, e.g. 12.34.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID("account id", args[0])
+			if err != nil {
+				return err
+			}
+
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			account, err := store.GetAccount(db.WithPrimaryReads(cmd.Context()), id)
+			if err != nil {
+				return err
+			}
+
+			amount, err := money.Parse(args[1], account.Currency)
+			if err != nil {
+				return err
+			}
+
+			result, err := store.DepositTx(cmd.Context(), db.DepositTxParams{
+				AccountID: id,
+				Amount:    amount,
+			})
+			if err != nil {
+				return err
+			}
+
+			return a.print(cmd.OutOrStdout(), result,
+				[]string{"ENTRY ID", "ACCOUNT ID", "AMOUNT", "BALANCE", "CURRENCY"},
+				[]string{
+					strconv.FormatInt(result.Entry.ID, 10),
+					strconv.FormatInt(result.Account.ID, 10),
+					amount.String(),
+					formatUnits(result.Account.Balance, result.Account.Currency),
+					result.Account.Currency,
+				},
+			)
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) ledgerCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ledger",
+		Short: "Check the ledger",
+	}
+	cmd.AddCommand(a.reconcileLedgerCommand())
+	return cmd
+}
+
+func (a *app) reconcileLedgerCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reconcile",
+		Short: "List the accounts whose balance differs from the sum of their entries",
+		Long:  "List the accounts whose balance differs from the sum of their entries, and fail if there is any so the command can run from a scheduler.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			mismatches, err := store.ListLedgerMismatches(db.WithPrimaryReads(cmd.Context()))
+			if err != nil {
+				return err
+			}
+
+			rows := make([][]string, len(mismatches))
+			for i, mismatch := range mismatches {
+				rows[i] = []string{
+					strconv.FormatInt(mismatch.ID, 10),
+					mismatch.Owner,
+					mismatch.Currency,
+					formatUnits(mismatch.Balance, mismatch.Currency),
+					formatUnits(mismatch.EntriesTotal, mismatch.Currency),
+					formatUnits(mismatch.Balance-mismatch.EntriesTotal, mismatch.Currency),
+				}
+			}
+			err = a.print(cmd.OutOrStdout(), mismatches,
+				[]string{"ACCOUNT ID", "OWNER", "CURRENCY", "BALANCE", "ENTRIES TOTAL", "DIFFERENCE"},
+				rows...,
+			)
+			if err != nil {
+				return err
+			}
+
+			if len(mismatches) > 0 {
+				return fmt.Errorf("%d accounts do not match their entries", len(mismatches))
+			}
+			return nil
+		},
+	}
+}
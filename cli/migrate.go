@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/spf13/cobra"
+)
+
+type migrationVersionOutput struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
+}
+
+func (a *app) migrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the database schema",
+		Long:  "Migrate the database schema with the migrations at MIGRATION_URL.",
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up [VERSION]",
+			Short: "Apply the migrations up to VERSION, or all of them",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return a.runMigration(cmd, args, true)
+			},
+		},
+		&cobra.Command{
+			Use:   "down VERSION",
+			Short: "Roll the migrations back down to VERSION, 0 rolling back all of them",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return a.runMigration(cmd, args, false)
+			},
+		},
+		&cobra.Command{
+			Use:   "version",
+			Short: "Show the version of the database schema",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				migration, err := a.newMigration()
+				if err != nil {
+					return err
+				}
+				defer migration.Close()
+
+				return a.printMigrationVersion(cmd.OutOrStdout(), migration)
+			},
+		},
+	)
+	return cmd
+}
+
+func (a *app) newMigration() (*migrate.Migrate, error) {
+	migration, err := migrate.New(a.config.MIGRATION_URL, a.config.DBSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migration: %w", err)
+	}
+	return migration, nil
+}
+
+// runMigration migrates to the version of args, refusing to go in the other direction than up asks.
+// Up without a version applies every migration.
+func (a *app) runMigration(cmd *cobra.Command, args []string, up bool) error {
+	migration, err := a.newMigration()
+	if err != nil {
+		return err
+	}
+	defer migration.Close()
+
+	if len(args) == 0 {
+		err = migration.Up()
+	} else {
+		var target uint64
+		target, err = strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		err = migrateTo(migration, uint(target), up)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	return a.printMigrationVersion(cmd.OutOrStdout(), migration)
+}
+
+func migrateTo(migration *migrate.Migrate, target uint, up bool) error {
+	current, dirty, err := migration.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d, fix it and force the version with the migrate tool", current)
+	}
+
+	switch {
+	case up && target < current:
+		return fmt.Errorf("version %d is below the current version %d, use down", target, current)
+	case !up && target > current:
+		return fmt.Errorf("version %d is above the current version %d, use up", target, current)
+	case !up && target == 0:
+		return migration.Down()
+	}
+	return migration.Migrate(target)
+}
+
+func (a *app) printMigrationVersion(w io.Writer, migration *migrate.Migrate) error {
+	version, dirty, err := migration.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+
+	out := migrationVersionOutput{Version: version, Dirty: dirty}
+	return a.print(w, out,
+		[]string{"VERSION", "DIRTY"},
+		[]string{strconv.FormatUint(uint64(out.Version), 10), strconv.FormatBool(out.Dirty)},
+	)
+}
@@ -0,0 +1,156 @@
+// Package cli implements simplebank, the command line tool operators use to
+// manage users, accounts and the database without going through psql.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/cobra"
+)
+
+// The --output values.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// StoreOpener opens the store the commands work on. The returned function releases it.
+type StoreOpener func(ctx context.Context, config util.Config) (db.Store, func(), error)
+
+// app holds the state shared by the commands of a run.
+type app struct {
+	openStore  StoreOpener
+	configPath string
+	output     string
+
+	config     util.Config
+	store      db.Store
+	closeStore func()
+}
+
+// Execute runs the command line with the arguments of the process.
+func Execute() error {
+	return NewRootCommand(openSQLStore).Execute()
+}
+
+// NewRootCommand creates the simplebank command, opening the store with openStore
+// the first time a command needs it.
+func NewRootCommand(openStore StoreOpener) *cobra.Command {
+	a := &app{openStore: openStore}
+
+	root := &cobra.Command{
+		Use:          "simplebank",
+		Short:        "Operate a simple bank deployment",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.output != outputTable && a.output != outputJSON {
+				return fmt.Errorf("unsupported output %q, use %s or %s", a.output, outputTable, outputJSON)
+			}
+
+			config, err := util.LoadConfig(a.configPath)
+			if err != nil {
+				return fmt.Errorf("cannot load config: %w", err)
+			}
+			a.config = config
+
+			cmd.SetContext(db.WithActor(cmd.Context(), cliActor()))
+			return nil
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if a.closeStore != nil {
+				a.closeStore()
+			}
+		},
+	}
+	root.PersistentFlags().StringVar(&a.configPath, "config", ".", "directory of the app.env config file")
+	root.PersistentFlags().StringVarP(&a.output, "output", "o", outputTable, "output format: table or json")
+
+	root.AddCommand(
+		a.userCommand(),
+		a.accountCommand(),
+		a.depositCommand(),
+		a.sessionCommand(),
+		a.migrateCommand(),
+		a.ledgerCommand(),
+		a.auditCommand(),
+	)
+	return root
+}
+
+// cliActor attributes the actions audited by the commands to the operator running them.
+func cliActor() db.Actor {
+	operator := "unknown"
+	if current, err := user.Current(); err == nil {
+		operator = current.Username
+	}
+	return db.Actor{Username: "cli:" + operator, UserAgent: "simplebank-cli"}
+}
+
+// openSQLStore connects to the database of the config.
+func openSQLStore(ctx context.Context, config util.Config) (db.Store, func(), error) {
+	if config.DBDriver == "memory" {
+		return nil, nil, errors.New("the in-memory store lives in the server process, set DB_DRIVER and DB_SOURCE to a database")
+	}
+
+	connPool, err := pgxpool.New(ctx, config.DBSource)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot connect to db: %w", err)
+	}
+	return db.NewStore(connPool, db.WithAuditKey([]byte(config.AuditKey))), connPool.Close, nil
+}
+
+// getStore opens the store on first use.
+func (a *app) getStore(ctx context.Context) (db.Store, error) {
+	if a.store != nil {
+		return a.store, nil
+	}
+
+	store, closeStore, err := a.openStore(ctx, a.config)
+	if err != nil {
+		return nil, err
+	}
+	a.store, a.closeStore = store, closeStore
+	return store, nil
+}
+
+// print writes value as indented JSON, or as a table with the header and rows.
+func (a *app) print(w io.Writer, value any, header []string, rows ...[]string) error {
+	if a.output == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// formatUnits formats minor units of a currency as a decimal amount, e.g. "12.34".
+func formatUnits(units int64, currency string) string {
+	amount, err := money.New(units, currency)
+	if err != nil {
+		return strconv.FormatInt(units, 10)
+	}
+	return amount.String()
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
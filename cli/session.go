@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+type blockSessionsOutput struct {
+	SessionID string `json:"session_id,omitempty"`
+	Username  string `json:"username"`
+	Blocked   int64  `json:"blocked"`
+}
+
+func (a *app) sessionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Manage login sessions",
+	}
+	cmd.AddCommand(a.blockSessionCommand())
+	return cmd
+}
+
+func (a *app) blockSessionCommand() *cobra.Command {
+	var username string
+
+	cmd := &cobra.Command{
+		Use:   "block [SESSION_ID]",
+		Short: "Block a session, or every session of a user with --user",
+		Long:  "Block a session so its refresh token cannot renew access tokens anymore. Access tokens already issued stay valid until they expire.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 0) == (username == "") {
+				return errors.New("give either a session id or --user")
+			}
+
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			out := blockSessionsOutput{Username: username}
+			if len(args) == 1 {
+				id, err := uuid.Parse(args[0])
+				if err != nil {
+					return errors.New("invalid session id")
+				}
+
+				session, err := store.BlockSession(cmd.Context(), id)
+				if err != nil {
+					return err
+				}
+				out = blockSessionsOutput{SessionID: session.ID.String(), Username: session.Username, Blocked: 1}
+			} else {
+				out.Blocked, err = store.BlockUserSessions(cmd.Context(), username)
+				if err != nil {
+					return err
+				}
+			}
+
+			return a.print(cmd.OutOrStdout(), out,
+				[]string{"SESSION ID", "USERNAME", "BLOCKED"},
+				[]string{out.SessionID, out.Username, strconv.FormatInt(out.Blocked, 10)},
+			)
+		},
+	}
+	cmd.Flags().StringVar(&username, "user", "", "block every active session of this user")
+	return cmd
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/MElghrbawy/simple_bank/val"
+	"github.com/spf13/cobra"
+)
+
+type userOutput struct {
+	Username  string `json:"username"`
+	FullName  string `json:"full_name"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (a *app) userCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage users",
+	}
+	cmd.AddCommand(a.createUserCommand())
+	return cmd
+}
+
+func (a *app) createUserCommand() *cobra.Command {
+	var arg db.CreateUserParams
+	var password string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user",
+		Long:  "Create a user. Without --password, the password is read from the first line of stdin.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if password == "" {
+				line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if err != nil && line == "" {
+					return errors.New("password is not provided")
+				}
+				password = strings.TrimRight(line, "\r\n")
+			}
+
+			err := errors.Join(
+				val.ValidateUsername(arg.Username),
+				val.ValidatePassword(password),
+				val.ValidateFullName(arg.FullName),
+				val.ValidateEmail(arg.Email),
+			)
+			if err != nil {
+				return err
+			}
+
+			arg.HashedPassword, err = util.HashPassword(password)
+			if err != nil {
+				return err
+			}
+
+			store, err := a.getStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			result, err := store.CreateUserTx(cmd.Context(), db.CreateUserTxParams{CreateUserParams: arg})
+			if err != nil {
+				return err
+			}
+			user := result.User
+
+			out := userOutput{
+				Username:  user.Username,
+				FullName:  user.FullName,
+				Email:     user.Email,
+				CreatedAt: formatTime(user.CreatedAt),
+			}
+			return a.print(cmd.OutOrStdout(), out,
+				[]string{"USERNAME", "FULL NAME", "EMAIL", "CREATED AT"},
+				[]string{out.Username, out.FullName, out.Email, out.CreatedAt},
+			)
+		},
+	}
+	cmd.Flags().StringVar(&arg.Username, "username", "", "username")
+	cmd.Flags().StringVar(&arg.FullName, "full-name", "", "full name")
+	cmd.Flags().StringVar(&arg.Email, "email", "", "email address")
+	cmd.Flags().StringVar(&password, "password", "", "password, read from stdin when empty")
+	cmd.MarkFlagRequired("username")
+	cmd.MarkFlagRequired("full-name")
+	cmd.MarkFlagRequired("email")
+	return cmd
+}
@@ -0,0 +1,141 @@
+// Package clientinfo identifies the client behind a request, the same way for the gRPC server,
+// the gateway and the HTTP server, so sessions, audit logs and rate limits agree on who the client is.
+package clientinfo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// ForwardedForHeader is the HTTP header the proxies append the address of their client to.
+	ForwardedForHeader = "X-Forwarded-For"
+
+	forwardedForKey     = "x-forwarded-for"
+	userAgentKey        = "user-agent"
+	gatewayUserAgentKey = "grpcgateway-user-agent"
+)
+
+// Info describes the client of a request.
+type Info struct {
+	ClientIP  string
+	UserAgent string
+}
+
+// Extractor extracts the Info of the requests. It only honors X-Forwarded-For when the request comes
+// through trusted proxies, since any client can send the header.
+type Extractor struct {
+	trustedProxies []netip.Prefix
+}
+
+// NewExtractor creates an extractor trusting the proxies within trustedProxies, given as CIDRs such as
+// "10.0.0.0/8" or single IPs. Without trusted proxies, the client is the peer of the connection.
+func NewExtractor(trustedProxies []string) (*Extractor, error) {
+	extractor := &Extractor{}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		extractor.trustedProxies = append(extractor.trustedProxies, prefix.Masked())
+	}
+	return extractor, nil
+}
+
+// ClientIP returns the IP of the client of a request received from remoteAddr with the forwardedFor
+// X-Forwarded-For values. It walks the forwarded addresses from the closest one, as long as they were
+// added by trusted proxies, and returns the first address not added by a trusted proxy.
+func (extractor *Extractor) ClientIP(remoteAddr string, forwardedFor []string) string {
+	ip := hostOf(remoteAddr)
+
+	var hops []string
+	for _, value := range forwardedFor {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0 && extractor.trusted(ip); i-- {
+		hop, err := netip.ParseAddr(hostOf(hops[i]))
+		if err != nil {
+			// a trusted proxy would not forward garbage, so the client sent it
+			break
+		}
+		ip = hop.Unmap().String()
+	}
+	return ip
+}
+
+// FromHTTP extracts the Info of an HTTP request.
+func (extractor *Extractor) FromHTTP(req *http.Request) Info {
+	return Info{
+		ClientIP:  extractor.ClientIP(req.RemoteAddr, req.Header.Values(ForwardedForHeader)),
+		UserAgent: req.UserAgent(),
+	}
+}
+
+// FromGRPC extracts the Info of a gRPC call, received either over a connection or from the gateway,
+// which calls the server in process and passes the HTTP request along as metadata.
+func (extractor *Extractor) FromGRPC(ctx context.Context) Info {
+	var info Info
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if userAgents := md.Get(gatewayUserAgentKey); len(userAgents) > 0 {
+		info.UserAgent = userAgents[0]
+	} else if userAgents := md.Get(userAgentKey); len(userAgents) > 0 {
+		info.UserAgent = userAgents[0]
+	}
+
+	forwardedFor := md.Get(forwardedForKey)
+	if p, ok := peer.FromContext(ctx); ok {
+		info.ClientIP = extractor.ClientIP(p.Addr.String(), forwardedFor)
+		return info
+	}
+
+	// the gateway appends the remote address of the HTTP request to X-Forwarded-For
+	hops := strings.Split(strings.Join(forwardedFor, ","), ",")
+	remoteAddr := strings.TrimSpace(hops[len(hops)-1])
+	info.ClientIP = extractor.ClientIP(remoteAddr, hops[:len(hops)-1])
+	return info
+}
+
+func (extractor *Extractor) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range extractor.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf strips the port of addr, if any.
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
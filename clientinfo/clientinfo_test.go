@@ -0,0 +1,138 @@
+package clientinfo
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestNewExtractor(t *testing.T) {
+	extractor, err := NewExtractor([]string{"10.0.0.0/8", " 192.168.1.7 ", "", "::1"})
+	require.NoError(t, err)
+	require.Len(t, extractor.trustedProxies, 3)
+
+	_, err = NewExtractor([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+
+	_, err = NewExtractor([]string{"proxy.internal"})
+	require.Error(t, err)
+}
+
+func TestClientIP(t *testing.T) {
+	testCases := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   []string
+		clientIP       string
+	}{
+		{
+			name:       "NoProxy",
+			remoteAddr: "203.0.113.5:4321",
+			clientIP:   "203.0.113.5",
+		},
+		{
+			name:         "UntrustedForwardedFor",
+			remoteAddr:   "203.0.113.5:4321",
+			forwardedFor: []string{"198.51.100.1"},
+			clientIP:     "203.0.113.5",
+		},
+		{
+			name:           "TrustedProxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:4321",
+			forwardedFor:   []string{"198.51.100.1"},
+			clientIP:       "198.51.100.1",
+		},
+		{
+			name:           "ChainOfTrustedProxies",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:4321",
+			forwardedFor:   []string{"198.51.100.1, 10.0.0.3", "10.0.0.4"},
+			clientIP:       "198.51.100.1",
+		},
+		{
+			name:           "SpoofedForwardedFor",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:4321",
+			forwardedFor:   []string{"1.2.3.4, 198.51.100.1"},
+			clientIP:       "198.51.100.1",
+		},
+		{
+			name:           "InvalidForwardedFor",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:4321",
+			forwardedFor:   []string{"unknown"},
+			clientIP:       "10.0.0.2",
+		},
+		{
+			name:           "OnlyTrustedProxies",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:4321",
+			forwardedFor:   []string{"10.0.0.3"},
+			clientIP:       "10.0.0.3",
+		},
+		{
+			name:           "IPv6",
+			trustedProxies: []string{"fd00::/8"},
+			remoteAddr:     "[fd00::1]:4321",
+			forwardedFor:   []string{"2001:db8::1"},
+			clientIP:       "2001:db8::1",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			extractor, err := NewExtractor(tc.trustedProxies)
+			require.NoError(t, err)
+			require.Equal(t, tc.clientIP, extractor.ClientIP(tc.remoteAddr, tc.forwardedFor))
+		})
+	}
+}
+
+func TestFromHTTP(t *testing.T) {
+	extractor, err := NewExtractor([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.2:4321"
+	req.Header.Set(ForwardedForHeader, "198.51.100.1")
+	req.Header.Set("User-Agent", "curl/8.0")
+
+	require.Equal(t, Info{ClientIP: "198.51.100.1", UserAgent: "curl/8.0"}, extractor.FromHTTP(req))
+}
+
+func TestFromGRPC(t *testing.T) {
+	extractor, err := NewExtractor([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	// a call over a connection
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"user-agent", "grpc-go/1.62",
+		"x-forwarded-for", "198.51.100.1",
+	))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 4321}})
+	require.Equal(t, Info{ClientIP: "203.0.113.5", UserAgent: "grpc-go/1.62"}, extractor.FromGRPC(ctx))
+
+	// a call from the gateway, received from a trusted proxy
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"grpcgateway-user-agent", "curl/8.0",
+		"x-forwarded-for", "198.51.100.1, 10.0.0.2",
+	))
+	require.Equal(t, Info{ClientIP: "198.51.100.1", UserAgent: "curl/8.0"}, extractor.FromGRPC(ctx))
+
+	// a call from the gateway, received from the client itself
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-forwarded-for", "1.2.3.4, 203.0.113.5",
+	))
+	require.Equal(t, Info{ClientIP: "203.0.113.5"}, extractor.FromGRPC(ctx))
+
+	require.Equal(t, Info{}, extractor.FromGRPC(context.Background()))
+}
@@ -0,0 +1,11 @@
+CREATE TABLE "account_limits" (
+  "account_id" bigint PRIMARY KEY,
+  "per_transfer" bigint,
+  "daily" bigint,
+  "monthly" bigint,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "account_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE INDEX "transfers_from_account_id_created_at_idx" ON "transfers" ("from_account_id", "created_at");
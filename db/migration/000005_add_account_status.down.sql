@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "owner_currency_key";
+
+ALTER TABLE IF EXISTS "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "frozen_by";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "status";
+
+DROP TYPE IF EXISTS "account_status";
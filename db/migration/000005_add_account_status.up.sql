@@ -0,0 +1,16 @@
+CREATE TYPE "account_status" AS ENUM (
+  'active',
+  'frozen',
+  'closed'
+);
+
+ALTER TABLE "accounts" ADD COLUMN "status" account_status NOT NULL DEFAULT 'active';
+
+ALTER TABLE "accounts" ADD COLUMN "frozen_by" varchar;
+
+COMMENT ON COLUMN "accounts"."frozen_by" IS 'the user who froze the account, null unless frozen';
+
+-- closed accounts keep their history, so only open accounts need a unique currency per owner
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "owner_currency_key";
+
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency") WHERE "status" <> 'closed';
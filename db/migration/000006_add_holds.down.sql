@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "holds";
+
+DROP TYPE IF EXISTS "hold_status";
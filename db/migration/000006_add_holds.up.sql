@@ -0,0 +1,24 @@
+CREATE TYPE "hold_status" AS ENUM (
+  'pending',
+  'captured',
+  'released'
+);
+
+CREATE TABLE "holds" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "captured_amount" bigint NOT NULL DEFAULT 0,
+  "status" hold_status NOT NULL DEFAULT 'pending',
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "holds" ("account_id", "status");
+
+COMMENT ON COLUMN "holds"."amount" IS 'it must be positive';
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
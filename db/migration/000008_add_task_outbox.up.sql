@@ -0,0 +1,13 @@
+CREATE TABLE "task_outbox" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "task_type" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "queue" varchar NOT NULL DEFAULT 'default',
+  "max_retry" integer NOT NULL DEFAULT 25,
+  "dispatched_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "task_outbox" ("id") WHERE "dispatched_at" IS NULL;
+
+COMMENT ON COLUMN "task_outbox"."dispatched_at" IS 'null until the task is enqueued';
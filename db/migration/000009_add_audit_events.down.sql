@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "audit_chain_head";
+
+DROP TABLE IF EXISTS "staged_audit_events";
+
+DROP TABLE IF EXISTS "audit_events";
+
+DROP FUNCTION IF EXISTS "reject_audit_event_change";
@@ -0,0 +1,65 @@
+CREATE TABLE "audit_events" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "action" varchar NOT NULL,
+  "actor" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "before" json,
+  "after" json,
+  "prev_hash" bytea NOT NULL,
+  "hash" bytea UNIQUE NOT NULL,
+  "created_at" timestamptz NOT NULL
+);
+
+CREATE TABLE "staged_audit_events" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "action" varchar NOT NULL,
+  "actor" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "before" json,
+  "after" json,
+  "created_at" timestamptz NOT NULL
+);
+
+CREATE TABLE "audit_chain_head" (
+  "id" boolean PRIMARY KEY DEFAULT true CHECK ("id"),
+  "event_id" bigint,
+  "hash" bytea NOT NULL
+);
+
+INSERT INTO "audit_chain_head" ("hash") VALUES ('');
+
+CREATE INDEX ON "audit_events" ("actor", "id");
+
+CREATE INDEX ON "audit_events" ("subject", "id");
+
+CREATE INDEX ON "audit_events" ("action", "id");
+
+COMMENT ON COLUMN "audit_events"."before" IS 'kept as json rather than jsonb so the hashed text is stored as is';
+
+COMMENT ON COLUMN "audit_events"."hash" IS 'HMAC-SHA256 of prev_hash and the other columns but id, keyed with a key kept out of the database';
+
+COMMENT ON TABLE "staged_audit_events" IS 'the events recorded by the transactions, until the chainer moves them to audit_events';
+
+COMMENT ON TABLE "audit_chain_head" IS 'single row holding the hash of the last audit event, locked by the chainer to append to the chain';
+
+CREATE FUNCTION "reject_audit_event_change"() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_events_append_only"
+BEFORE UPDATE OR DELETE ON "audit_events"
+FOR EACH ROW EXECUTE FUNCTION "reject_audit_event_change"();
+
+CREATE TRIGGER "audit_events_no_truncate"
+BEFORE TRUNCATE ON "audit_events"
+FOR EACH STATEMENT EXECUTE FUNCTION "reject_audit_event_change"();
+
+CREATE TRIGGER "staged_audit_events_no_update"
+BEFORE UPDATE ON "staged_audit_events"
+FOR EACH ROW EXECUTE FUNCTION "reject_audit_event_change"();
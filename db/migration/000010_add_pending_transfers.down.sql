@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "pending_transfers";
+
+DROP TYPE IF EXISTS "pending_transfer_status";
+
+DROP INDEX IF EXISTS "sessions_username_created_at_idx";
+
+DROP INDEX IF EXISTS "transfers_from_account_id_to_account_id_idx";
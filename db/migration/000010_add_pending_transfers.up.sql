@@ -0,0 +1,39 @@
+CREATE TYPE "pending_transfer_status" AS ENUM (
+  'pending',
+  'approved',
+  'rejected'
+);
+
+CREATE TABLE "pending_transfers" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "requested_by" varchar NOT NULL,
+  "reason" varchar NOT NULL,
+  "status" pending_transfer_status NOT NULL DEFAULT 'pending',
+  "decided_by" varchar NOT NULL DEFAULT '',
+  "decided_at" timestamptz,
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "pending_transfers" ("status", "id");
+
+CREATE INDEX ON "sessions" ("username", "created_at");
+
+CREATE INDEX ON "transfers" ("from_account_id", "to_account_id");
+
+COMMENT ON COLUMN "pending_transfers"."amount" IS 'it must be positive, in the currency of the source account';
+
+COMMENT ON COLUMN "pending_transfers"."reason" IS 'why the transfer needs approval';
+
+COMMENT ON COLUMN "pending_transfers"."transfer_id" IS 'the transfer posted once approved';
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("requested_by") REFERENCES "users" ("username");
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
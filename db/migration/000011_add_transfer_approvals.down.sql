@@ -0,0 +1,20 @@
+ALTER TABLE "pending_transfers" DROP COLUMN IF EXISTS "expires_at";
+
+-- enum values cannot be dropped, so the type is recreated without 'expired'
+UPDATE "pending_transfers" SET "status" = 'rejected' WHERE "status" = 'expired';
+
+ALTER TYPE "pending_transfer_status" RENAME TO "pending_transfer_status_old";
+
+CREATE TYPE "pending_transfer_status" AS ENUM (
+  'pending',
+  'approved',
+  'rejected'
+);
+
+ALTER TABLE "pending_transfers" ALTER COLUMN "status" DROP DEFAULT;
+
+ALTER TABLE "pending_transfers" ALTER COLUMN "status" TYPE pending_transfer_status USING "status"::text::pending_transfer_status;
+
+ALTER TABLE "pending_transfers" ALTER COLUMN "status" SET DEFAULT 'pending';
+
+DROP TYPE "pending_transfer_status_old";
@@ -0,0 +1,9 @@
+ALTER TYPE "pending_transfer_status" ADD VALUE 'expired';
+
+ALTER TABLE "pending_transfers" ADD COLUMN "expires_at" timestamptz;
+
+UPDATE "pending_transfers" SET "expires_at" = "created_at" + interval '24 hours';
+
+ALTER TABLE "pending_transfers" ALTER COLUMN "expires_at" SET NOT NULL;
+
+CREATE INDEX ON "pending_transfers" ("status", "expires_at");
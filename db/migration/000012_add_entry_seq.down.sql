@@ -0,0 +1,3 @@
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "seq";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "last_entry_seq";
@@ -0,0 +1,20 @@
+ALTER TABLE "accounts" ADD COLUMN "last_entry_seq" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "entries" ADD COLUMN "seq" bigint;
+
+UPDATE "entries" SET "seq" = "numbered"."seq"
+FROM (
+  SELECT "id", row_number() OVER (PARTITION BY "account_id" ORDER BY "id") AS "seq"
+  FROM "entries"
+) AS "numbered"
+WHERE "entries"."id" = "numbered"."id";
+
+UPDATE "accounts" SET "last_entry_seq" = COALESCE((
+  SELECT max("seq") FROM "entries" WHERE "entries"."account_id" = "accounts"."id"
+), 0);
+
+ALTER TABLE "entries" ALTER COLUMN "seq" SET NOT NULL;
+
+CREATE UNIQUE INDEX ON "entries" ("account_id", "seq");
+
+COMMENT ON COLUMN "entries"."seq" IS 'position of the entry on its account, taken from accounts.last_entry_seq under the account row lock so it follows commit order';
@@ -0,0 +1,4 @@
+ALTER TABLE "task_outbox" DROP COLUMN IF EXISTS "failed_at";
+ALTER TABLE "task_outbox" DROP COLUMN IF EXISTS "last_error";
+ALTER TABLE "task_outbox" DROP COLUMN IF EXISTS "next_attempt_at";
+ALTER TABLE "task_outbox" DROP COLUMN IF EXISTS "attempts";
@@ -0,0 +1,8 @@
+ALTER TABLE "task_outbox" ADD COLUMN "attempts" integer NOT NULL DEFAULT 0;
+ALTER TABLE "task_outbox" ADD COLUMN "next_attempt_at" timestamptz NOT NULL DEFAULT (now());
+ALTER TABLE "task_outbox" ADD COLUMN "last_error" varchar NOT NULL DEFAULT '';
+ALTER TABLE "task_outbox" ADD COLUMN "failed_at" timestamptz;
+
+COMMENT ON COLUMN "task_outbox"."attempts" IS 'the number of times a relay claimed the task';
+COMMENT ON COLUMN "task_outbox"."next_attempt_at" IS 'the task is not claimed before, while a relay enqueues it or after it failed';
+COMMENT ON COLUMN "task_outbox"."failed_at" IS 'set once the task failed too many times, it is no longer enqueued';
@@ -0,0 +1,34 @@
+// Package migration embeds the migrations of the database schema, so the server knows
+// the schema version it was built for whatever the database it connects to.
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// LatestVersion returns the version of the last migration, the one the database must be
+// migrated to for the server to work.
+func LatestVersion() (uint, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration name %s: %w", name, err)
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
@@ -0,0 +1,24 @@
+package migration
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersion(t *testing.T) {
+	ups, err := fs.Glob(files, "*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, ups)
+
+	version, err := LatestVersion()
+	require.NoError(t, err)
+	require.Equal(t, uint(len(ups)), version)
+
+	for _, up := range ups {
+		_, err := fs.Stat(files, strings.TrimSuffix(up, ".up.sql")+".down.sql")
+		require.NoError(t, err, up)
+	}
+}
@@ -17,6 +17,18 @@ SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE;
 
+-- name: GetAccountBalance :one
+SELECT
+  accounts.id,
+  accounts.balance,
+  (accounts.balance - COALESCE(SUM(holds.amount), 0))::bigint AS available_balance
+FROM accounts
+LEFT JOIN holds ON holds.account_id = accounts.id
+  AND holds.status = 'pending'
+  AND holds.expires_at > now()
+WHERE accounts.id = $1
+GROUP BY accounts.id;
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 WHERE owner = $1
@@ -36,6 +48,8 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
--- name: DeleteAccount :exec
-DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+SET status = $2, frozen_by = $3
+WHERE id = $1
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,60 @@
+-- name: GetAuditChainHeadForUpdate :one
+SELECT * FROM audit_chain_head
+LIMIT 1
+FOR UPDATE;
+
+-- name: GetAuditChainHead :one
+SELECT * FROM audit_chain_head
+LIMIT 1;
+
+-- name: UpdateAuditChainHead :exec
+UPDATE audit_chain_head
+SET event_id = $1, hash = $2;
+
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+  action,
+  actor,
+  subject,
+  client_ip,
+  user_agent,
+  before,
+  after,
+  prev_hash,
+  hash,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: CreateStagedAuditEvent :one
+INSERT INTO staged_audit_events (
+  action,
+  actor,
+  subject,
+  client_ip,
+  user_agent,
+  before,
+  after,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: ListStagedAuditEvents :many
+SELECT * FROM staged_audit_events
+ORDER BY id
+LIMIT $1;
+
+-- name: DeleteStagedAuditEvent :exec
+DELETE FROM staged_audit_events
+WHERE id = $1;
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE id > sqlc.arg(after_id)
+  AND (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor))
+  AND (sqlc.narg(subject)::varchar IS NULL OR subject = sqlc.narg(subject))
+  AND (sqlc.narg(action)::varchar IS NULL OR action = sqlc.narg(action))
+ORDER BY id
+LIMIT sqlc.arg('limit');
@@ -1,9 +1,18 @@
 -- name: CreateEntry :one
+-- The account row stays locked until the transaction commits, so the entries
+-- of an account commit in the order of their seq.
+WITH account AS (
+  UPDATE accounts
+  SET last_entry_seq = last_entry_seq + 1
+  WHERE id = sqlc.arg(account_id)
+  RETURNING last_entry_seq
+)
 INSERT INTO entries (
-    account_id, 
-    amount
+    account_id,
+    amount,
+    seq
 ) VALUES (
-  $1, $2
+  sqlc.arg(account_id), sqlc.arg(amount), COALESCE((SELECT last_entry_seq FROM account), 0)
 ) RETURNING *;
 
 
@@ -16,3 +25,31 @@ SELECT * FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2;
+
+-- name: ListAccountEventsAfter :many
+-- The balance before the first entry is the account balance less the entries after after_seq,
+-- the running sum then gives the balance after each entry. Pages are limited to max_events,
+-- the next page starts after the seq of the last event.
+WITH gap AS (
+  SELECT COALESCE(SUM(amount), 0)::bigint AS amount
+  FROM entries
+  WHERE account_id = sqlc.arg(account_id)
+    AND seq > sqlc.arg(after_seq)
+)
+SELECT
+  entries.id,
+  entries.seq,
+  entries.account_id,
+  entries.amount,
+  entries.created_at,
+  (accounts.balance - gap.amount + SUM(entries.amount) OVER (ORDER BY entries.seq))::bigint AS balance
+FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+CROSS JOIN gap
+WHERE entries.account_id = sqlc.arg(account_id)
+  AND entries.seq > sqlc.arg(after_seq)
+ORDER BY entries.seq
+LIMIT sqlc.arg(max_events);
+
+-- name: NotifyAccountEvent :exec
+SELECT pg_notify(sqlc.arg(channel)::text, sqlc.arg(payload)::text);
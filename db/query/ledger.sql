@@ -0,0 +1,12 @@
+-- name: ListLedgerMismatches :many
+SELECT
+  accounts.id,
+  accounts.owner,
+  accounts.currency,
+  accounts.balance,
+  COALESCE(SUM(entries.amount), 0)::bigint AS entries_total
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+GROUP BY accounts.id
+HAVING accounts.balance <> COALESCE(SUM(entries.amount), 0)
+ORDER BY accounts.id;
@@ -0,0 +1,45 @@
+-- name: CreatePendingTransfer :one
+INSERT INTO pending_transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  requested_by,
+  reason,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetPendingTransfer :one
+SELECT * FROM pending_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetPendingTransferForUpdate :one
+SELECT * FROM pending_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListPendingTransfers :many
+SELECT * FROM pending_transfers
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListExpiredPendingTransfersForUpdate :many
+SELECT * FROM pending_transfers
+WHERE status = 'pending'
+AND expires_at <= sqlc.arg(now)
+ORDER BY id
+LIMIT sqlc.arg('limit')
+FOR NO KEY UPDATE SKIP LOCKED;
+
+-- name: DecidePendingTransfer :one
+UPDATE pending_transfers
+SET
+  status = sqlc.arg(status),
+  decided_by = sqlc.arg(decided_by),
+  decided_at = now(),
+  transfer_id = sqlc.narg(transfer_id)
+WHERE id = sqlc.arg(id)
+RETURNING *;
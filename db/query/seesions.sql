@@ -14,4 +14,30 @@ INSERT INTO sessions (
 
 -- name: GetSession :one
 SELECT * FROM sessions
-WHERE id = $1 LIMIT 1;
\ No newline at end of file
+WHERE id = $1 LIMIT 1;
+
+-- name: BlockSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1
+RETURNING *;
+
+-- name: BlockUserSessions :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE username = $1
+  AND is_blocked = false;
+
+-- name: GetLatestSession :one
+SELECT * FROM sessions
+WHERE username = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CountEarlierSessions :one
+SELECT
+  COUNT(*) AS logins,
+  COUNT(*) FILTER (WHERE client_ip = sqlc.arg(client_ip)) AS logins_from_client_ip
+FROM sessions
+WHERE username = sqlc.arg(username)
+AND created_at < sqlc.arg(before);
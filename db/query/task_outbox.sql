@@ -0,0 +1,42 @@
+-- name: CreateOutboxTask :one
+INSERT INTO task_outbox (
+  task_type,
+  payload,
+  queue,
+  max_retry
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetOutboxTask :one
+SELECT * FROM task_outbox
+WHERE id = $1 LIMIT 1;
+
+-- name: ClaimOutboxTasks :many
+-- A claimed task is not claimed again before claimed_until, so the relay enqueues
+-- the tasks outside of the transaction while the other relays skip them.
+UPDATE task_outbox
+SET attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(claimed_until)
+WHERE id IN (
+  SELECT id FROM task_outbox
+  WHERE dispatched_at IS NULL
+    AND failed_at IS NULL
+    AND next_attempt_at <= now()
+  ORDER BY id
+  LIMIT sqlc.arg(max_tasks)
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxTaskDispatched :exec
+UPDATE task_outbox
+SET dispatched_at = now()
+WHERE id = $1;
+
+-- name: RecordOutboxTaskFailure :exec
+UPDATE task_outbox
+SET last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    failed_at = sqlc.narg(failed_at)
+WHERE id = sqlc.arg(id);
@@ -16,3 +16,18 @@ SELECT * FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2;
+
+-- name: SumTransfersSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM transfers
+WHERE from_account_id = sqlc.arg(from_account_id)
+AND created_at >= sqlc.arg(since);
+
+-- name: CountTransfersBetween :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+AND to_account_id = $2;
+
+-- name: CountTransfersSince :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = sqlc.arg(from_account_id)
+AND created_at >= sqlc.arg(since);
@@ -0,0 +1,73 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: account_limit.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const getAccountLimit = `-- name: GetAccountLimit :one
+SELECT account_id, per_transfer, daily, monthly, updated_at FROM account_limits
+WHERE account_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetAccountLimit(ctx context.Context, accountID int64) (AccountLimit, error) {
+	row := q.db.QueryRow(ctx, getAccountLimit, accountID)
+	var i AccountLimit
+	err := row.Scan(
+		&i.AccountID,
+		&i.PerTransfer,
+		&i.Daily,
+		&i.Monthly,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertAccountLimit = `-- name: UpsertAccountLimit :one
+INSERT INTO account_limits (
+  account_id,
+  per_transfer,
+  daily,
+  monthly
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (account_id) DO UPDATE
+SET
+  per_transfer = EXCLUDED.per_transfer,
+  daily = EXCLUDED.daily,
+  monthly = EXCLUDED.monthly,
+  updated_at = now()
+RETURNING account_id, per_transfer, daily, monthly, updated_at
+`
+
+type UpsertAccountLimitParams struct {
+	AccountID   int64       `json:"account_id"`
+	PerTransfer pgtype.Int8 `json:"per_transfer"`
+	Daily       pgtype.Int8 `json:"daily"`
+	Monthly     pgtype.Int8 `json:"monthly"`
+}
+
+func (q *Queries) UpsertAccountLimit(ctx context.Context, arg UpsertAccountLimitParams) (AccountLimit, error) {
+	row := q.db.QueryRow(ctx, upsertAccountLimit,
+		arg.AccountID,
+		arg.PerTransfer,
+		arg.Daily,
+		arg.Monthly,
+	)
+	var i AccountLimit
+	err := row.Scan(
+		&i.AccountID,
+		&i.PerTransfer,
+		&i.Daily,
+		&i.Monthly,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
@@ -11,13 +10,17 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountIn(t, util.RandomCurrency())
+}
 
+// createRandomAccountIn creates an account in currency, for tests moving money between accounts.
+func createRandomAccountIn(t *testing.T, currency string) Account {
 	user := createRandomUser(t)
 
 	arg := CreateAccountParams{
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
-		Currency: util.RandomCurrency(),
+		Currency: currency,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), arg)
@@ -82,19 +85,74 @@ func TestUpdateAccount(t *testing.T) {
 
 }
 
-func TestDeleteAccount(t *testing.T) {
+func TestUpdateAccountStatus(t *testing.T) {
 	account1 := createRandomAccount(t)
+	require.Equal(t, AccountStatusActive, account1.Status)
 
-	err := testQueries.DeleteAccount(context.Background(), account1.ID)
+	account2, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account1.ID,
+		Status: AccountStatusFrozen,
+	})
 
 	require.NoError(t, err)
+	require.Equal(t, account1.ID, account2.ID)
+	require.Equal(t, account1.Balance, account2.Balance)
+	require.Equal(t, AccountStatusFrozen, account2.Status)
 
-	account2, err := testQueries.GetAccount(context.Background(), account1.ID)
+}
+
+func TestUpdateAccountStatusTx(t *testing.T) {
+	store := NewStore(testPool)
+	account := createRandomAccount(t)
+
+	frozen, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, frozen.Status)
 
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, account2)
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusFrozen,
+	})
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
 
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: 10})
+	require.NoError(t, err)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusClosed,
+	})
+	require.ErrorIs(t, err, ErrAccountNotEmpty)
+
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: 0})
+	require.NoError(t, err)
+
+	closed, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusClosed,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, closed.Status)
+
+	// closing keeps the row
+	account2, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, account2.Status)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusActive,
+	})
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: 0,
+		Status:    AccountStatusFrozen,
+	})
+	require.EqualError(t, err, ErrRecordNotFound.Error())
 }
 
 func TestListAccounts(t *testing.T) {
@@ -0,0 +1,267 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: audit_event.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createAuditEvent = `-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+  action,
+  actor,
+  subject,
+  client_ip,
+  user_agent,
+  before,
+  after,
+  prev_hash,
+  hash,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING id, action, actor, subject, client_ip, user_agent, before, after, prev_hash, hash, created_at
+`
+
+type CreateAuditEventParams struct {
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Subject   string    `json:"subject"`
+	ClientIp  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	Before    []byte    `json:"before"`
+	After     []byte    `json:"after"`
+	PrevHash  []byte    `json:"prev_hash"`
+	Hash      []byte    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	row := q.db.QueryRow(ctx, createAuditEvent,
+		arg.Action,
+		arg.Actor,
+		arg.Subject,
+		arg.ClientIp,
+		arg.UserAgent,
+		arg.Before,
+		arg.After,
+		arg.PrevHash,
+		arg.Hash,
+		arg.CreatedAt,
+	)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Action,
+		&i.Actor,
+		&i.Subject,
+		&i.ClientIp,
+		&i.UserAgent,
+		&i.Before,
+		&i.After,
+		&i.PrevHash,
+		&i.Hash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createStagedAuditEvent = `-- name: CreateStagedAuditEvent :one
+INSERT INTO staged_audit_events (
+  action,
+  actor,
+  subject,
+  client_ip,
+  user_agent,
+  before,
+  after,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, action, actor, subject, client_ip, user_agent, before, after, created_at
+`
+
+type CreateStagedAuditEventParams struct {
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Subject   string    `json:"subject"`
+	ClientIp  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	Before    []byte    `json:"before"`
+	After     []byte    `json:"after"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) CreateStagedAuditEvent(ctx context.Context, arg CreateStagedAuditEventParams) (StagedAuditEvent, error) {
+	row := q.db.QueryRow(ctx, createStagedAuditEvent,
+		arg.Action,
+		arg.Actor,
+		arg.Subject,
+		arg.ClientIp,
+		arg.UserAgent,
+		arg.Before,
+		arg.After,
+		arg.CreatedAt,
+	)
+	var i StagedAuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Action,
+		&i.Actor,
+		&i.Subject,
+		&i.ClientIp,
+		&i.UserAgent,
+		&i.Before,
+		&i.After,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteStagedAuditEvent = `-- name: DeleteStagedAuditEvent :exec
+DELETE FROM staged_audit_events
+WHERE id = $1
+`
+
+func (q *Queries) DeleteStagedAuditEvent(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, deleteStagedAuditEvent, id)
+	return err
+}
+
+const getAuditChainHead = `-- name: GetAuditChainHead :one
+SELECT id, event_id, hash FROM audit_chain_head
+LIMIT 1
+`
+
+func (q *Queries) GetAuditChainHead(ctx context.Context) (AuditChainHead, error) {
+	row := q.db.QueryRow(ctx, getAuditChainHead)
+	var i AuditChainHead
+	err := row.Scan(&i.ID, &i.EventID, &i.Hash)
+	return i, err
+}
+
+const getAuditChainHeadForUpdate = `-- name: GetAuditChainHeadForUpdate :one
+SELECT id, event_id, hash FROM audit_chain_head
+LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetAuditChainHeadForUpdate(ctx context.Context) (AuditChainHead, error) {
+	row := q.db.QueryRow(ctx, getAuditChainHeadForUpdate)
+	var i AuditChainHead
+	err := row.Scan(&i.ID, &i.EventID, &i.Hash)
+	return i, err
+}
+
+const listAuditEvents = `-- name: ListAuditEvents :many
+SELECT id, action, actor, subject, client_ip, user_agent, before, after, prev_hash, hash, created_at FROM audit_events
+WHERE id > $1
+  AND ($2::varchar IS NULL OR actor = $2)
+  AND ($3::varchar IS NULL OR subject = $3)
+  AND ($4::varchar IS NULL OR action = $4)
+ORDER BY id
+LIMIT $5
+`
+
+type ListAuditEventsParams struct {
+	AfterID int64       `json:"after_id"`
+	Actor   pgtype.Text `json:"actor"`
+	Subject pgtype.Text `json:"subject"`
+	Action  pgtype.Text `json:"action"`
+	Limit   int32       `json:"limit"`
+}
+
+func (q *Queries) ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, listAuditEvents,
+		arg.AfterID,
+		arg.Actor,
+		arg.Subject,
+		arg.Action,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Action,
+			&i.Actor,
+			&i.Subject,
+			&i.ClientIp,
+			&i.UserAgent,
+			&i.Before,
+			&i.After,
+			&i.PrevHash,
+			&i.Hash,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStagedAuditEvents = `-- name: ListStagedAuditEvents :many
+SELECT id, action, actor, subject, client_ip, user_agent, before, after, created_at FROM staged_audit_events
+ORDER BY id
+LIMIT $1
+`
+
+func (q *Queries) ListStagedAuditEvents(ctx context.Context, limit int32) ([]StagedAuditEvent, error) {
+	rows, err := q.db.Query(ctx, listStagedAuditEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []StagedAuditEvent{}
+	for rows.Next() {
+		var i StagedAuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Action,
+			&i.Actor,
+			&i.Subject,
+			&i.ClientIp,
+			&i.UserAgent,
+			&i.Before,
+			&i.After,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateAuditChainHead = `-- name: UpdateAuditChainHead :exec
+UPDATE audit_chain_head
+SET event_id = $1, hash = $2
+`
+
+type UpdateAuditChainHeadParams struct {
+	EventID pgtype.Int8 `json:"event_id"`
+	Hash    []byte      `json:"hash"`
+}
+
+func (q *Queries) UpdateAuditChainHead(ctx context.Context, arg UpdateAuditChainHeadParams) error {
+	_, err := q.db.Exec(ctx, updateAuditChainHead, arg.EventID, arg.Hash)
+	return err
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// translatingDBTX runs the queries on db and passes their errors through translateError.
+type translatingDBTX struct {
+	db DBTX
+}
+
+func newQueries(db DBTX) *Queries {
+	return New(translatingDBTX{db: db})
+}
+
+func (t translatingDBTX) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := t.db.Exec(ctx, sql, args...)
+	return tag, translateError(err)
+}
+
+func (t translatingDBTX) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := t.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return translatingRows{rows}, nil
+}
+
+func (t translatingDBTX) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return translatingRow{t.db.QueryRow(ctx, sql, args...)}
+}
+
+type translatingRows struct {
+	pgx.Rows
+}
+
+func (rows translatingRows) Scan(dest ...any) error {
+	return translateError(rows.Rows.Scan(dest...))
+}
+
+func (rows translatingRows) Err() error {
+	return translateError(rows.Rows.Err())
+}
+
+type translatingRow struct {
+	row pgx.Row
+}
+
+func (row translatingRow) Scan(dest ...any) error {
+	return translateError(row.row.Scan(dest...))
+}
@@ -7,15 +7,23 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
+WITH account AS (
+  UPDATE accounts
+  SET last_entry_seq = last_entry_seq + 1
+  WHERE id = $1
+  RETURNING last_entry_seq
+)
 INSERT INTO entries (
-    account_id, 
-    amount
+    account_id,
+    amount,
+    seq
 ) VALUES (
-  $1, $2
-) RETURNING id, account_id, amount, created_at
+  $1, $2, COALESCE((SELECT last_entry_seq FROM account), 0)
+) RETURNING id, account_id, amount, created_at, seq
 `
 
 type CreateEntryParams struct {
@@ -23,37 +31,109 @@ type CreateEntryParams struct {
 	Amount    int64 `json:"amount"`
 }
 
+// The account row stays locked until the transaction commits, so the entries
+// of an account commit in the order of their seq.
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createEntry, arg.AccountID, arg.Amount)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Seq,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, seq FROM entries
 WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, getEntry, id)
+	row := q.db.QueryRow(ctx, getEntry, id)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Seq,
 	)
 	return i, err
 }
 
+const listAccountEventsAfter = `-- name: ListAccountEventsAfter :many
+WITH gap AS (
+  SELECT COALESCE(SUM(amount), 0)::bigint AS amount
+  FROM entries
+  WHERE account_id = $1
+    AND seq > $2
+)
+SELECT
+  entries.id,
+  entries.seq,
+  entries.account_id,
+  entries.amount,
+  entries.created_at,
+  (accounts.balance - gap.amount + SUM(entries.amount) OVER (ORDER BY entries.seq))::bigint AS balance
+FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+CROSS JOIN gap
+WHERE entries.account_id = $1
+  AND entries.seq > $2
+ORDER BY entries.seq
+LIMIT $3
+`
+
+type ListAccountEventsAfterParams struct {
+	AccountID int64 `json:"account_id"`
+	AfterSeq  int64 `json:"after_seq"`
+	MaxEvents int32 `json:"max_events"`
+}
+
+type ListAccountEventsAfterRow struct {
+	ID        int64     `json:"id"`
+	Seq       int64     `json:"seq"`
+	AccountID int64     `json:"account_id"`
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+	Balance   int64     `json:"balance"`
+}
+
+// The balance before the first entry is the account balance less the entries after after_seq,
+// the running sum then gives the balance after each entry. Pages are limited to max_events,
+// the next page starts after the seq of the last event.
+func (q *Queries) ListAccountEventsAfter(ctx context.Context, arg ListAccountEventsAfterParams) ([]ListAccountEventsAfterRow, error) {
+	rows, err := q.db.Query(ctx, listAccountEventsAfter, arg.AccountID, arg.AfterSeq, arg.MaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountEventsAfterRow{}
+	for rows.Next() {
+		var i ListAccountEventsAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Seq,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Balance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, seq FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -65,7 +145,7 @@ type ListEntriesParams struct {
 }
 
 func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
-	rows, err := q.db.QueryContext(ctx, listEntries, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listEntries, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -78,16 +158,28 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Seq,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
+
+const notifyAccountEvent = `-- name: NotifyAccountEvent :exec
+SELECT pg_notify($1::text, $2::text)
+`
+
+type NotifyAccountEventParams struct {
+	Channel string `json:"channel"`
+	Payload string `json:"payload"`
+}
+
+func (q *Queries) NotifyAccountEvent(ctx context.Context, arg NotifyAccountEventParams) error {
+	_, err := q.db.Exec(ctx, notifyAccountEvent, arg.Channel, arg.Payload)
+	return err
+}
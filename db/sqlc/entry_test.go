@@ -27,6 +27,7 @@ func createRandomEntry(t *testing.T) Entry {
 	require.Equal(t, arg.Amount, entry.Amount)
 
 	require.NotZero(t, entry.ID)
+	require.Equal(t, int64(1), entry.Seq)
 	require.NotZero(t, entry.CreatedAt)
 
 	return entry
@@ -73,3 +74,34 @@ func TestListEntries(t *testing.T) {
 	}
 
 }
+
+func TestCreateEntrySeqFollowsCommitOrder(t *testing.T) {
+	account := createRandomAccount(t)
+	ctx := context.Background()
+
+	tx1, err := testPool.Begin(ctx)
+	require.NoError(t, err)
+	defer tx1.Rollback(ctx)
+
+	entry1, err := New(tx1).CreateEntry(ctx, CreateEntryParams{AccountID: account.ID, Amount: 10})
+	require.NoError(t, err)
+
+	// the second entry waits for the account row until the first transaction commits
+	var entry2 Entry
+	done := make(chan error)
+	go func() {
+		var err error
+		entry2, err = testQueries.CreateEntry(ctx, CreateEntryParams{AccountID: account.ID, Amount: 20})
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("entry created while the account was locked")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, tx1.Commit(ctx))
+	require.NoError(t, <-done)
+	require.Greater(t, entry2.Seq, entry1.Seq)
+}
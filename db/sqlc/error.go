@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The Postgres SQLSTATE codes the application reacts to.
+const (
+	ForeignKeyViolation  = "23503"
+	UniqueViolation      = "23505"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+// ErrRecordNotFound is returned by the queries selecting a single row when there is none.
+var ErrRecordNotFound = apperr.Wrap(pgx.ErrNoRows, apperr.NotFound, "record not found")
+
+var ErrUniqueViolation = translateError(&pgconn.PgError{
+	Code: UniqueViolation,
+})
+
+// ErrorCode returns the SQLSTATE code of a Postgres error, or an empty string for other errors.
+func ErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// constraintMessages tells clients which rule a write broke, by constraint name.
+var constraintMessages = map[string]string{
+	"users_pkey":                       "username already exists",
+	"users_email_key":                  "email already exists",
+	"owner_currency_key":               "an account in this currency already exists",
+	"accounts_owner_fkey":              "user does not exist",
+	"sessions_username_fkey":           "user does not exist",
+	"webhook_subscriptions_owner_fkey": "user does not exist",
+	"webhook_events_owner_fkey":        "user does not exist",
+}
+
+// translateError turns the errors of the database into apperr errors, keeping the original
+// error as the cause so ErrorCode and errors.Is still see it. Other errors are returned as is.
+func translateError(err error) error {
+	var appErr *apperr.Error
+	if err == nil || errors.As(err, &appErr) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	message := constraintMessages[pgErr.ConstraintName]
+	switch pgErr.Code {
+	case UniqueViolation:
+		if message == "" {
+			message = "record already exists"
+		}
+		return apperr.Wrap(err, apperr.AlreadyExists, message)
+	case ForeignKeyViolation:
+		if message == "" {
+			message = "referenced record does not exist"
+		}
+		return apperr.Wrap(err, apperr.FailedPrecondition, message)
+	case SerializationFailure, DeadlockDetected:
+		return apperr.Wrap(err, apperr.Unavailable, "request conflicted with concurrent requests, please retry")
+	}
+	return err
+}
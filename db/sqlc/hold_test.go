@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomHold(t *testing.T, account Account, toAccount Account) Hold {
+	arg := CreateHoldParams{
+		AccountID:   account.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      util.RandomInt(1, 100),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	hold, err := testQueries.CreateHold(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, hold)
+
+	require.Equal(t, arg.AccountID, hold.AccountID)
+	require.Equal(t, arg.ToAccountID, hold.ToAccountID)
+	require.Equal(t, arg.Amount, hold.Amount)
+	require.Equal(t, HoldStatusPending, hold.Status)
+	require.Zero(t, hold.CapturedAmount)
+	require.WithinDuration(t, arg.ExpiresAt, hold.ExpiresAt, time.Second)
+
+	require.NotZero(t, hold.ID)
+	require.NotZero(t, hold.CreatedAt)
+
+	return hold
+}
+
+func TestCreateHold(t *testing.T) {
+	createRandomHold(t, createRandomAccount(t), createRandomAccount(t))
+}
+
+func TestGetHold(t *testing.T) {
+	hold1 := createRandomHold(t, createRandomAccount(t), createRandomAccount(t))
+	hold2, err := testQueries.GetHold(context.Background(), hold1.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, hold1.ID, hold2.ID)
+	require.Equal(t, hold1.Amount, hold2.Amount)
+	require.Equal(t, hold1.Status, hold2.Status)
+	require.WithinDuration(t, hold1.CreatedAt, hold2.CreatedAt, time.Second)
+}
+
+func TestGetAccountBalance(t *testing.T) {
+	account := createRandomAccount(t)
+	toAccount := createRandomAccount(t)
+
+	hold1 := createRandomHold(t, account, toAccount)
+	hold2 := createRandomHold(t, account, toAccount)
+
+	expired, err := testQueries.CreateHold(context.Background(), CreateHoldParams{
+		AccountID:   account.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      10,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, expired.ID)
+
+	_, err = testQueries.UpdateHoldStatus(context.Background(), UpdateHoldStatusParams{
+		ID:     hold2.ID,
+		Status: HoldStatusReleased,
+	})
+	require.NoError(t, err)
+
+	balance, err := testQueries.GetAccountBalance(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, balance.Balance)
+	require.Equal(t, account.Balance-hold1.Amount, balance.AvailableBalance)
+}
@@ -0,0 +1,58 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: ledger.sql
+
+package db
+
+import (
+	"context"
+)
+
+const listLedgerMismatches = `-- name: ListLedgerMismatches :many
+SELECT
+  accounts.id,
+  accounts.owner,
+  accounts.currency,
+  accounts.balance,
+  COALESCE(SUM(entries.amount), 0)::bigint AS entries_total
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+GROUP BY accounts.id
+HAVING accounts.balance <> COALESCE(SUM(entries.amount), 0)
+ORDER BY accounts.id
+`
+
+type ListLedgerMismatchesRow struct {
+	ID           int64  `json:"id"`
+	Owner        string `json:"owner"`
+	Currency     string `json:"currency"`
+	Balance      int64  `json:"balance"`
+	EntriesTotal int64  `json:"entries_total"`
+}
+
+func (q *Queries) ListLedgerMismatches(ctx context.Context) ([]ListLedgerMismatchesRow, error) {
+	rows, err := q.db.Query(ctx, listLedgerMismatches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLedgerMismatchesRow{}
+	for rows.Next() {
+		var i ListLedgerMismatchesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Currency,
+			&i.Balance,
+			&i.EntriesTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
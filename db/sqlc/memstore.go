@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MemStore is a Store keeping all its data in memory, for tests and demos that run without Postgres.
+// It enforces the keys and foreign keys of the database schema and returns the same errors as SQLStore,
+// so handlers cannot tell the two apart. Account event notifications are dropped.
+type MemStore struct {
+	mu sync.Mutex
+	*memQueries
+	transactions
+}
+
+// NewMemStore creates an empty in-memory store.
+func NewMemStore(opts ...StoreOption) Store {
+	store := &MemStore{}
+	store.memQueries = &memQueries{mu: &store.mu, data: newMemData()}
+	store.transactions = newStoreOptions(opts).transactions
+	store.transactions.runTx = store.runTx
+	return store
+}
+
+// runTx runs fn on a copy of the data and keeps the copy only if fn succeeds.
+// Transactions and queries run one at a time, so transactions are serializable
+// whatever the options ask for, and never fail with a serialization failure.
+func (store *MemStore) runTx(ctx context.Context, _ pgx.TxOptions, fn func(Querier) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx := &memQueries{data: store.data.clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	store.data = tx.data
+	return nil
+}
+
+// memQueries implements Querier on top of the in-memory tables.
+// Inside a transaction mu is nil, as the transaction already holds the lock.
+type memQueries struct {
+	mu   *sync.Mutex
+	data *memData
+}
+
+func (q *memQueries) lock() func() {
+	if q.mu == nil {
+		return func() {}
+	}
+	q.mu.Lock()
+	return q.mu.Unlock
+}
+
+type memData struct {
+	users                map[string]User
+	sessions             map[uuid.UUID]Session
+	accountLimits        map[int64]AccountLimit
+	accounts             memTable[Account]
+	entries              memTable[Entry]
+	transfers            memTable[Transfer]
+	holds                memTable[Hold]
+	webhookSubscriptions memTable[WebhookSubscription]
+	webhookEvents        memTable[WebhookEvent]
+	webhookDeliveries    memTable[WebhookDelivery]
+	outboxTasks          memTable[TaskOutbox]
+	auditEvents          memTable[AuditEvent]
+	stagedAuditEvents    memTable[StagedAuditEvent]
+	auditChainHead       AuditChainHead
+	pendingTransfers     memTable[PendingTransfer]
+}
+
+func newMemData() *memData {
+	return &memData{
+		users:                make(map[string]User),
+		sessions:             make(map[uuid.UUID]Session),
+		accountLimits:        make(map[int64]AccountLimit),
+		accounts:             newMemTable[Account](),
+		entries:              newMemTable[Entry](),
+		transfers:            newMemTable[Transfer](),
+		holds:                newMemTable[Hold](),
+		webhookSubscriptions: newMemTable[WebhookSubscription](),
+		webhookEvents:        newMemTable[WebhookEvent](),
+		webhookDeliveries:    newMemTable[WebhookDelivery](),
+		outboxTasks:          newMemTable[TaskOutbox](),
+		auditEvents:          newMemTable[AuditEvent](),
+		stagedAuditEvents:    newMemTable[StagedAuditEvent](),
+		auditChainHead:       AuditChainHead{ID: true, Hash: []byte{}},
+		pendingTransfers:     newMemTable[PendingTransfer](),
+	}
+}
+
+// clone copies the tables. Rows are copied by value and never modified in place,
+// so the slices they hold can be shared.
+func (data *memData) clone() *memData {
+	return &memData{
+		users:                maps.Clone(data.users),
+		sessions:             maps.Clone(data.sessions),
+		accountLimits:        maps.Clone(data.accountLimits),
+		accounts:             data.accounts.clone(),
+		entries:              data.entries.clone(),
+		transfers:            data.transfers.clone(),
+		holds:                data.holds.clone(),
+		webhookSubscriptions: data.webhookSubscriptions.clone(),
+		webhookEvents:        data.webhookEvents.clone(),
+		webhookDeliveries:    data.webhookDeliveries.clone(),
+		outboxTasks:          data.outboxTasks.clone(),
+		auditEvents:          data.auditEvents.clone(),
+		stagedAuditEvents:    data.stagedAuditEvents.clone(),
+		auditChainHead:       data.auditChainHead,
+		pendingTransfers:     data.pendingTransfers.clone(),
+	}
+}
+
+// memTable is a table with a BIGSERIAL primary key.
+type memTable[T any] struct {
+	rows   map[int64]T
+	lastID int64
+}
+
+func newMemTable[T any]() memTable[T] {
+	return memTable[T]{rows: make(map[int64]T)}
+}
+
+func (table memTable[T]) clone() memTable[T] {
+	return memTable[T]{rows: maps.Clone(table.rows), lastID: table.lastID}
+}
+
+func (table *memTable[T]) insert(newRow func(id int64) T) T {
+	table.lastID++
+	row := newRow(table.lastID)
+	table.rows[table.lastID] = row
+	return row
+}
+
+func (table memTable[T]) get(id int64) (T, error) {
+	row, ok := table.rows[id]
+	if !ok {
+		return row, ErrRecordNotFound
+	}
+	return row, nil
+}
+
+func (table memTable[T]) exists(id int64) bool {
+	_, ok := table.rows[id]
+	return ok
+}
+
+// list returns the rows matching keep in ascending id order.
+func (table memTable[T]) list(keep func(row T) bool) []T {
+	ids := make([]int64, 0, len(table.rows))
+	for id := range table.rows {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	rows := []T{}
+	for _, id := range ids {
+		if row := table.rows[id]; keep(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// page applies LIMIT and OFFSET to the rows.
+func page[T any](rows []T, limit int32, offset int32) []T {
+	start := min(int(offset), len(rows))
+	end := min(start+int(limit), len(rows))
+	return rows[start:end]
+}
+
+// memNow returns the current time at the precision Postgres stores timestamps with.
+func memNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func uniqueViolation(constraint string) error {
+	return translateError(&pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           UniqueViolation,
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		ConstraintName: constraint,
+	})
+}
+
+func foreignKeyViolation(table string, constraint string) error {
+	return translateError(&pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           ForeignKeyViolation,
+		Message:        fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	})
+}
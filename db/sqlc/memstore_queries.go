@@ -0,0 +1,895 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (q *memQueries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.users[arg.Username]; ok {
+		return User{}, uniqueViolation("users_pkey")
+	}
+	for _, user := range q.data.users {
+		if user.Email == arg.Email {
+			return User{}, uniqueViolation("users_email_key")
+		}
+	}
+
+	user := User{
+		Username:          arg.Username,
+		HashedPassword:    arg.HashedPassword,
+		FullName:          arg.FullName,
+		Email:             arg.Email,
+		PasswordChangedAt: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt:         memNow(),
+	}
+	q.data.users[user.Username] = user
+	return user, nil
+}
+
+func (q *memQueries) GetUser(ctx context.Context, username string) (User, error) {
+	defer q.lock()()
+
+	user, ok := q.data.users[username]
+	if !ok {
+		return user, ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (q *memQueries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	defer q.lock()()
+
+	user, ok := q.data.users[arg.Username]
+	if !ok {
+		return user, ErrRecordNotFound
+	}
+
+	if arg.Email.Valid && arg.Email.String != user.Email {
+		for _, other := range q.data.users {
+			if other.Email == arg.Email.String {
+				return User{}, uniqueViolation("users_email_key")
+			}
+		}
+		user.Email = arg.Email.String
+	}
+	if arg.HashedPassword.Valid {
+		user.HashedPassword = arg.HashedPassword.String
+	}
+	if arg.PasswordChangedAt.Valid {
+		user.PasswordChangedAt = arg.PasswordChangedAt.Time
+	}
+	if arg.FullName.Valid {
+		user.FullName = arg.FullName.String
+	}
+
+	q.data.users[user.Username] = user
+	return user, nil
+}
+
+func (q *memQueries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.sessions[arg.ID]; ok {
+		return Session{}, uniqueViolation("sessions_pkey")
+	}
+	if _, ok := q.data.users[arg.Username]; !ok {
+		return Session{}, foreignKeyViolation("sessions", "sessions_username_fkey")
+	}
+
+	session := Session{
+		ID:           arg.ID,
+		Username:     arg.Username,
+		RefreshToken: arg.RefreshToken,
+		UserAgent:    arg.UserAgent,
+		ClientIp:     arg.ClientIp,
+		IsBlocked:    arg.IsBlocked,
+		ExpiresAt:    arg.ExpiresAt,
+		CreatedAt:    memNow(),
+	}
+	q.data.sessions[session.ID] = session
+	return session, nil
+}
+
+func (q *memQueries) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	defer q.lock()()
+
+	session, ok := q.data.sessions[id]
+	if !ok {
+		return session, ErrRecordNotFound
+	}
+	return session, nil
+}
+
+func (q *memQueries) BlockSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	defer q.lock()()
+
+	session, ok := q.data.sessions[id]
+	if !ok {
+		return session, ErrRecordNotFound
+	}
+	session.IsBlocked = true
+	q.data.sessions[id] = session
+	return session, nil
+}
+
+func (q *memQueries) BlockUserSessions(ctx context.Context, username string) (int64, error) {
+	defer q.lock()()
+
+	var blocked int64
+	for id, session := range q.data.sessions {
+		if session.Username == username && !session.IsBlocked {
+			session.IsBlocked = true
+			q.data.sessions[id] = session
+			blocked++
+		}
+	}
+	return blocked, nil
+}
+
+func (q *memQueries) GetLatestSession(ctx context.Context, username string) (Session, error) {
+	defer q.lock()()
+
+	var latest Session
+	found := false
+	for _, session := range q.data.sessions {
+		if session.Username == username && (!found || session.CreatedAt.After(latest.CreatedAt)) {
+			latest, found = session, true
+		}
+	}
+	if !found {
+		return latest, ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func (q *memQueries) CountEarlierSessions(ctx context.Context, arg CountEarlierSessionsParams) (CountEarlierSessionsRow, error) {
+	defer q.lock()()
+
+	var row CountEarlierSessionsRow
+	for _, session := range q.data.sessions {
+		if session.Username == arg.Username && session.CreatedAt.Before(arg.Before) {
+			row.Logins++
+			if session.ClientIp == arg.ClientIp {
+				row.LoginsFromClientIp++
+			}
+		}
+	}
+	return row, nil
+}
+
+func (q *memQueries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.users[arg.Owner]; !ok {
+		return Account{}, foreignKeyViolation("accounts", "accounts_owner_fkey")
+	}
+	if err := q.checkOwnerCurrency(0, arg.Owner, arg.Currency); err != nil {
+		return Account{}, err
+	}
+
+	account := q.data.accounts.insert(func(id int64) Account {
+		return Account{
+			ID:        id,
+			Owner:     arg.Owner,
+			Balance:   arg.Balance,
+			Currency:  arg.Currency,
+			CreatedAt: memNow(),
+			Status:    AccountStatusActive,
+		}
+	})
+	return account, nil
+}
+
+// checkOwnerCurrency enforces the owner_currency_key index, which only covers open accounts.
+func (q *memQueries) checkOwnerCurrency(accountID int64, owner string, currency string) error {
+	for _, account := range q.data.accounts.rows {
+		if account.ID != accountID && account.Owner == owner && account.Currency == currency &&
+			account.Status != AccountStatusClosed {
+			return uniqueViolation("owner_currency_key")
+		}
+	}
+	return nil
+}
+
+func (q *memQueries) GetAccount(ctx context.Context, id int64) (Account, error) {
+	defer q.lock()()
+	return q.data.accounts.get(id)
+}
+
+// GetAccountForUpdate needs no row lock, as transactions run one at a time.
+func (q *memQueries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	defer q.lock()()
+	return q.data.accounts.get(id)
+}
+
+func (q *memQueries) GetAccountBalance(ctx context.Context, id int64) (GetAccountBalanceRow, error) {
+	defer q.lock()()
+
+	account, err := q.data.accounts.get(id)
+	if err != nil {
+		return GetAccountBalanceRow{}, err
+	}
+
+	now := time.Now()
+	row := GetAccountBalanceRow{ID: account.ID, Balance: account.Balance, AvailableBalance: account.Balance}
+	for _, hold := range q.data.holds.rows {
+		if hold.AccountID == id && hold.Status == HoldStatusPending && hold.ExpiresAt.After(now) {
+			row.AvailableBalance -= hold.Amount
+		}
+	}
+	return row, nil
+}
+
+func (q *memQueries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	defer q.lock()()
+
+	accounts := q.data.accounts.list(func(account Account) bool {
+		return account.Owner == arg.Owner
+	})
+	return page(accounts, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	defer q.lock()()
+
+	return q.updateAccount(arg.ID, func(account *Account) error {
+		account.Balance = arg.Balance
+		return nil
+	})
+}
+
+func (q *memQueries) AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error) {
+	defer q.lock()()
+
+	return q.updateAccount(arg.ID, func(account *Account) error {
+		account.Balance += arg.Amount
+		return nil
+	})
+}
+
+func (q *memQueries) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	defer q.lock()()
+
+	return q.updateAccount(arg.ID, func(account *Account) error {
+		if arg.Status != AccountStatusClosed {
+			if err := q.checkOwnerCurrency(account.ID, account.Owner, account.Currency); err != nil {
+				return err
+			}
+		}
+		account.Status = arg.Status
+		account.FrozenBy = arg.FrozenBy
+		return nil
+	})
+}
+
+func (q *memQueries) updateAccount(id int64, update func(account *Account) error) (Account, error) {
+	account, err := q.data.accounts.get(id)
+	if err != nil {
+		return account, err
+	}
+
+	if err := update(&account); err != nil {
+		return Account{}, err
+	}
+
+	q.data.accounts.rows[id] = account
+	return account, nil
+}
+
+func (q *memQueries) GetAccountLimit(ctx context.Context, accountID int64) (AccountLimit, error) {
+	defer q.lock()()
+
+	accountLimit, ok := q.data.accountLimits[accountID]
+	if !ok {
+		return accountLimit, ErrRecordNotFound
+	}
+	return accountLimit, nil
+}
+
+func (q *memQueries) UpsertAccountLimit(ctx context.Context, arg UpsertAccountLimitParams) (AccountLimit, error) {
+	defer q.lock()()
+
+	if !q.data.accounts.exists(arg.AccountID) {
+		return AccountLimit{}, foreignKeyViolation("account_limits", "account_limits_account_id_fkey")
+	}
+
+	accountLimit := AccountLimit{
+		AccountID:   arg.AccountID,
+		PerTransfer: arg.PerTransfer,
+		Daily:       arg.Daily,
+		Monthly:     arg.Monthly,
+		UpdatedAt:   memNow(),
+	}
+	q.data.accountLimits[arg.AccountID] = accountLimit
+	return accountLimit, nil
+}
+
+func (q *memQueries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	defer q.lock()()
+
+	account, err := q.updateAccount(arg.AccountID, func(account *Account) error {
+		account.LastEntrySeq++
+		return nil
+	})
+	if err != nil {
+		return Entry{}, foreignKeyViolation("entries", "entries_account_id_fkey")
+	}
+
+	entry := q.data.entries.insert(func(id int64) Entry {
+		return Entry{
+			ID:        id,
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+			CreatedAt: memNow(),
+			Seq:       account.LastEntrySeq,
+		}
+	})
+	return entry, nil
+}
+
+func (q *memQueries) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	defer q.lock()()
+	return q.data.entries.get(id)
+}
+
+func (q *memQueries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	defer q.lock()()
+
+	entries := q.data.entries.list(func(Entry) bool { return true })
+	return page(entries, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) ListAccountEventsAfter(ctx context.Context, arg ListAccountEventsAfterParams) ([]ListAccountEventsAfterRow, error) {
+	defer q.lock()()
+
+	account, err := q.data.accounts.get(arg.AccountID)
+	if err != nil {
+		return []ListAccountEventsAfterRow{}, nil
+	}
+
+	entries := q.data.entries.list(func(entry Entry) bool {
+		return entry.AccountID == arg.AccountID && entry.Seq > arg.AfterSeq
+	})
+
+	// undo the entries after after_seq, then add them back one by one
+	balance := account.Balance
+	for _, entry := range entries {
+		balance -= entry.Amount
+	}
+
+	entries = page(entries, arg.MaxEvents, 0)
+	rows := make([]ListAccountEventsAfterRow, len(entries))
+	for i, entry := range entries {
+		balance += entry.Amount
+		rows[i] = ListAccountEventsAfterRow{
+			ID:        entry.ID,
+			Seq:       entry.Seq,
+			AccountID: entry.AccountID,
+			Amount:    entry.Amount,
+			CreatedAt: entry.CreatedAt,
+			Balance:   balance,
+		}
+	}
+	return rows, nil
+}
+
+// NotifyAccountEvent drops the notification, nothing listens to the in-memory store.
+func (q *memQueries) NotifyAccountEvent(ctx context.Context, arg NotifyAccountEventParams) error {
+	return nil
+}
+
+func (q *memQueries) ListLedgerMismatches(ctx context.Context) ([]ListLedgerMismatchesRow, error) {
+	defer q.lock()()
+
+	totals := make(map[int64]int64)
+	for _, entry := range q.data.entries.rows {
+		totals[entry.AccountID] += entry.Amount
+	}
+
+	rows := []ListLedgerMismatchesRow{}
+	for _, account := range q.data.accounts.list(func(account Account) bool { return account.Balance != totals[account.ID] }) {
+		rows = append(rows, ListLedgerMismatchesRow{
+			ID:           account.ID,
+			Owner:        account.Owner,
+			Currency:     account.Currency,
+			Balance:      account.Balance,
+			EntriesTotal: totals[account.ID],
+		})
+	}
+	return rows, nil
+}
+
+func (q *memQueries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	defer q.lock()()
+
+	if !q.data.accounts.exists(arg.FromAccountID) {
+		return Transfer{}, foreignKeyViolation("transfers", "transfers_from_account_id_fkey")
+	}
+	if !q.data.accounts.exists(arg.ToAccountID) {
+		return Transfer{}, foreignKeyViolation("transfers", "transfers_to_account_id_fkey")
+	}
+
+	transfer := q.data.transfers.insert(func(id int64) Transfer {
+		return Transfer{
+			ID:            id,
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			CreatedAt:     memNow(),
+		}
+	})
+	return transfer, nil
+}
+
+func (q *memQueries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	defer q.lock()()
+	return q.data.transfers.get(id)
+}
+
+func (q *memQueries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	defer q.lock()()
+
+	transfers := q.data.transfers.list(func(Transfer) bool { return true })
+	return page(transfers, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) SumTransfersSince(ctx context.Context, arg SumTransfersSinceParams) (int64, error) {
+	defer q.lock()()
+
+	var sum int64
+	for _, transfer := range q.data.transfers.rows {
+		if transfer.FromAccountID == arg.FromAccountID && !transfer.CreatedAt.Before(arg.Since) {
+			sum += transfer.Amount
+		}
+	}
+	return sum, nil
+}
+
+func (q *memQueries) CountTransfersBetween(ctx context.Context, arg CountTransfersBetweenParams) (int64, error) {
+	defer q.lock()()
+
+	var count int64
+	for _, transfer := range q.data.transfers.rows {
+		if transfer.FromAccountID == arg.FromAccountID && transfer.ToAccountID == arg.ToAccountID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (q *memQueries) CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error) {
+	defer q.lock()()
+
+	var count int64
+	for _, transfer := range q.data.transfers.rows {
+		if transfer.FromAccountID == arg.FromAccountID && !transfer.CreatedAt.Before(arg.Since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (q *memQueries) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	defer q.lock()()
+
+	if !q.data.accounts.exists(arg.AccountID) {
+		return Hold{}, foreignKeyViolation("holds", "holds_account_id_fkey")
+	}
+	if !q.data.accounts.exists(arg.ToAccountID) {
+		return Hold{}, foreignKeyViolation("holds", "holds_to_account_id_fkey")
+	}
+
+	hold := q.data.holds.insert(func(id int64) Hold {
+		return Hold{
+			ID:          id,
+			AccountID:   arg.AccountID,
+			ToAccountID: arg.ToAccountID,
+			Amount:      arg.Amount,
+			Status:      HoldStatusPending,
+			ExpiresAt:   arg.ExpiresAt,
+			CreatedAt:   memNow(),
+		}
+	})
+	return hold, nil
+}
+
+func (q *memQueries) GetHold(ctx context.Context, id int64) (Hold, error) {
+	defer q.lock()()
+	return q.data.holds.get(id)
+}
+
+// GetHoldForUpdate needs no row lock, as transactions run one at a time.
+func (q *memQueries) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	defer q.lock()()
+	return q.data.holds.get(id)
+}
+
+func (q *memQueries) ListHolds(ctx context.Context, arg ListHoldsParams) ([]Hold, error) {
+	defer q.lock()()
+
+	holds := q.data.holds.list(func(hold Hold) bool {
+		return hold.AccountID == arg.AccountID
+	})
+	return page(holds, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error) {
+	defer q.lock()()
+
+	hold, err := q.data.holds.get(arg.ID)
+	if err != nil {
+		return hold, err
+	}
+
+	hold.Status = arg.Status
+	hold.CapturedAmount = arg.CapturedAmount
+	q.data.holds.rows[hold.ID] = hold
+	return hold, nil
+}
+
+func (q *memQueries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.users[arg.Owner]; !ok {
+		return WebhookSubscription{}, foreignKeyViolation("webhook_subscriptions", "webhook_subscriptions_owner_fkey")
+	}
+
+	subscription := q.data.webhookSubscriptions.insert(func(id int64) WebhookSubscription {
+		return WebhookSubscription{
+			ID:         id,
+			Owner:      arg.Owner,
+			Url:        arg.Url,
+			Secret:     arg.Secret,
+			EventTypes: slices.Clone(arg.EventTypes),
+			CreatedAt:  memNow(),
+		}
+	})
+	return subscription, nil
+}
+
+func (q *memQueries) GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error) {
+	defer q.lock()()
+	return q.data.webhookSubscriptions.get(id)
+}
+
+func (q *memQueries) ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscription, error) {
+	defer q.lock()()
+
+	return q.data.webhookSubscriptions.list(func(subscription WebhookSubscription) bool {
+		return subscription.Owner == owner
+	}), nil
+}
+
+func (q *memQueries) ListWebhookSubscriptionsForEvent(ctx context.Context, arg ListWebhookSubscriptionsForEventParams) ([]WebhookSubscription, error) {
+	defer q.lock()()
+
+	return q.data.webhookSubscriptions.list(func(subscription WebhookSubscription) bool {
+		return subscription.Owner == arg.Owner && slices.Contains(subscription.EventTypes, arg.EventType)
+	}), nil
+}
+
+// DeleteWebhookSubscription also deletes the deliveries of the subscription, like ON DELETE CASCADE.
+func (q *memQueries) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	defer q.lock()()
+
+	delete(q.data.webhookSubscriptions.rows, id)
+	for deliveryID, delivery := range q.data.webhookDeliveries.rows {
+		if delivery.SubscriptionID == id {
+			delete(q.data.webhookDeliveries.rows, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (q *memQueries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (WebhookEvent, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.users[arg.Owner]; !ok {
+		return WebhookEvent{}, foreignKeyViolation("webhook_events", "webhook_events_owner_fkey")
+	}
+
+	event := q.data.webhookEvents.insert(func(id int64) WebhookEvent {
+		return WebhookEvent{
+			ID:        id,
+			Owner:     arg.Owner,
+			EventType: arg.EventType,
+			Payload:   slices.Clone(arg.Payload),
+			CreatedAt: memNow(),
+		}
+	})
+	return event, nil
+}
+
+func (q *memQueries) GetWebhookEvent(ctx context.Context, id int64) (WebhookEvent, error) {
+	defer q.lock()()
+	return q.data.webhookEvents.get(id)
+}
+
+func (q *memQueries) ListPendingWebhookEvents(ctx context.Context, limit int32) ([]WebhookEvent, error) {
+	defer q.lock()()
+
+	events := q.data.webhookEvents.list(func(event WebhookEvent) bool {
+		return !event.DispatchedAt.Valid
+	})
+	return page(events, limit, 0), nil
+}
+
+func (q *memQueries) MarkWebhookEventDispatched(ctx context.Context, id int64) error {
+	defer q.lock()()
+
+	if event, ok := q.data.webhookEvents.rows[id]; ok {
+		event.DispatchedAt = pgtype.Timestamptz{Time: memNow(), Valid: true}
+		q.data.webhookEvents.rows[id] = event
+	}
+	return nil
+}
+
+func (q *memQueries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	defer q.lock()()
+
+	if !q.data.webhookSubscriptions.exists(arg.SubscriptionID) {
+		return WebhookDelivery{}, foreignKeyViolation("webhook_deliveries", "webhook_deliveries_subscription_id_fkey")
+	}
+	if !q.data.webhookEvents.exists(arg.EventID) {
+		return WebhookDelivery{}, foreignKeyViolation("webhook_deliveries", "webhook_deliveries_event_id_fkey")
+	}
+
+	delivery := q.data.webhookDeliveries.insert(func(id int64) WebhookDelivery {
+		return WebhookDelivery{
+			ID:             id,
+			SubscriptionID: arg.SubscriptionID,
+			EventID:        arg.EventID,
+			Attempt:        arg.Attempt,
+			StatusCode:     arg.StatusCode,
+			Error:          arg.Error,
+			Succeeded:      arg.Succeeded,
+			CreatedAt:      memNow(),
+		}
+	})
+	return delivery, nil
+}
+
+func (q *memQueries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	defer q.lock()()
+
+	deliveries := q.data.webhookDeliveries.list(func(delivery WebhookDelivery) bool {
+		return delivery.SubscriptionID == arg.SubscriptionID
+	})
+	slices.Reverse(deliveries)
+	return page(deliveries, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) CreateOutboxTask(ctx context.Context, arg CreateOutboxTaskParams) (TaskOutbox, error) {
+	defer q.lock()()
+
+	task := q.data.outboxTasks.insert(func(id int64) TaskOutbox {
+		return TaskOutbox{
+			ID:            id,
+			TaskType:      arg.TaskType,
+			Payload:       slices.Clone(arg.Payload),
+			Queue:         arg.Queue,
+			MaxRetry:      arg.MaxRetry,
+			CreatedAt:     memNow(),
+			NextAttemptAt: memNow(),
+		}
+	})
+	return task, nil
+}
+
+func (q *memQueries) GetOutboxTask(ctx context.Context, id int64) (TaskOutbox, error) {
+	defer q.lock()()
+	return q.data.outboxTasks.get(id)
+}
+
+func (q *memQueries) ClaimOutboxTasks(ctx context.Context, arg ClaimOutboxTasksParams) ([]TaskOutbox, error) {
+	defer q.lock()()
+
+	now := memNow()
+	tasks := q.data.outboxTasks.list(func(task TaskOutbox) bool {
+		return !task.DispatchedAt.Valid && !task.FailedAt.Valid && !task.NextAttemptAt.After(now)
+	})
+	tasks = page(tasks, arg.MaxTasks, 0)
+	for i := range tasks {
+		tasks[i].Attempts++
+		tasks[i].NextAttemptAt = arg.ClaimedUntil
+		q.data.outboxTasks.rows[tasks[i].ID] = tasks[i]
+	}
+	return tasks, nil
+}
+
+func (q *memQueries) RecordOutboxTaskFailure(ctx context.Context, arg RecordOutboxTaskFailureParams) error {
+	defer q.lock()()
+
+	if task, ok := q.data.outboxTasks.rows[arg.ID]; ok {
+		task.LastError = arg.LastError
+		task.NextAttemptAt = arg.NextAttemptAt
+		task.FailedAt = arg.FailedAt
+		q.data.outboxTasks.rows[arg.ID] = task
+	}
+	return nil
+}
+
+func (q *memQueries) MarkOutboxTaskDispatched(ctx context.Context, id int64) error {
+	defer q.lock()()
+
+	if task, ok := q.data.outboxTasks.rows[id]; ok {
+		task.DispatchedAt = pgtype.Timestamptz{Time: memNow(), Valid: true}
+		q.data.outboxTasks.rows[id] = task
+	}
+	return nil
+}
+
+// GetAuditChainHeadForUpdate needs no lock of its own: transactions run one at a time.
+func (q *memQueries) GetAuditChainHeadForUpdate(ctx context.Context) (AuditChainHead, error) {
+	return q.GetAuditChainHead(ctx)
+}
+
+func (q *memQueries) GetAuditChainHead(ctx context.Context) (AuditChainHead, error) {
+	defer q.lock()()
+	return q.data.auditChainHead, nil
+}
+
+func (q *memQueries) UpdateAuditChainHead(ctx context.Context, arg UpdateAuditChainHeadParams) error {
+	defer q.lock()()
+
+	q.data.auditChainHead.EventID = arg.EventID
+	q.data.auditChainHead.Hash = slices.Clone(arg.Hash)
+	return nil
+}
+
+func (q *memQueries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	defer q.lock()()
+
+	for _, event := range q.data.auditEvents.rows {
+		if slices.Equal(event.Hash, arg.Hash) {
+			return AuditEvent{}, uniqueViolation("audit_events_hash_key")
+		}
+	}
+
+	event := q.data.auditEvents.insert(func(id int64) AuditEvent {
+		return AuditEvent{
+			ID:        id,
+			Action:    arg.Action,
+			Actor:     arg.Actor,
+			Subject:   arg.Subject,
+			ClientIp:  arg.ClientIp,
+			UserAgent: arg.UserAgent,
+			Before:    slices.Clone(arg.Before),
+			After:     slices.Clone(arg.After),
+			PrevHash:  slices.Clone(arg.PrevHash),
+			Hash:      slices.Clone(arg.Hash),
+			CreatedAt: arg.CreatedAt,
+		}
+	})
+	return event, nil
+}
+
+func (q *memQueries) CreateStagedAuditEvent(ctx context.Context, arg CreateStagedAuditEventParams) (StagedAuditEvent, error) {
+	defer q.lock()()
+
+	event := q.data.stagedAuditEvents.insert(func(id int64) StagedAuditEvent {
+		return StagedAuditEvent{
+			ID:        id,
+			Action:    arg.Action,
+			Actor:     arg.Actor,
+			Subject:   arg.Subject,
+			ClientIp:  arg.ClientIp,
+			UserAgent: arg.UserAgent,
+			Before:    slices.Clone(arg.Before),
+			After:     slices.Clone(arg.After),
+			CreatedAt: arg.CreatedAt,
+		}
+	})
+	return event, nil
+}
+
+func (q *memQueries) ListStagedAuditEvents(ctx context.Context, limit int32) ([]StagedAuditEvent, error) {
+	defer q.lock()()
+
+	events := q.data.stagedAuditEvents.list(func(StagedAuditEvent) bool { return true })
+	return page(events, limit, 0), nil
+}
+
+func (q *memQueries) DeleteStagedAuditEvent(ctx context.Context, id int64) error {
+	defer q.lock()()
+
+	delete(q.data.stagedAuditEvents.rows, id)
+	return nil
+}
+
+func (q *memQueries) ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error) {
+	defer q.lock()()
+
+	events := q.data.auditEvents.list(func(event AuditEvent) bool {
+		return event.ID > arg.AfterID &&
+			(!arg.Actor.Valid || event.Actor == arg.Actor.String) &&
+			(!arg.Subject.Valid || event.Subject == arg.Subject.String) &&
+			(!arg.Action.Valid || event.Action == arg.Action.String)
+	})
+	return page(events, arg.Limit, 0), nil
+}
+
+func (q *memQueries) CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (PendingTransfer, error) {
+	defer q.lock()()
+
+	if !q.data.accounts.exists(arg.FromAccountID) {
+		return PendingTransfer{}, foreignKeyViolation("pending_transfers", "pending_transfers_from_account_id_fkey")
+	}
+	if !q.data.accounts.exists(arg.ToAccountID) {
+		return PendingTransfer{}, foreignKeyViolation("pending_transfers", "pending_transfers_to_account_id_fkey")
+	}
+	if _, ok := q.data.users[arg.RequestedBy]; !ok {
+		return PendingTransfer{}, foreignKeyViolation("pending_transfers", "pending_transfers_requested_by_fkey")
+	}
+
+	pending := q.data.pendingTransfers.insert(func(id int64) PendingTransfer {
+		return PendingTransfer{
+			ID:            id,
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			RequestedBy:   arg.RequestedBy,
+			Reason:        arg.Reason,
+			Status:        PendingTransferStatusPending,
+			ExpiresAt:     arg.ExpiresAt,
+			CreatedAt:     memNow(),
+		}
+	})
+	return pending, nil
+}
+
+func (q *memQueries) GetPendingTransfer(ctx context.Context, id int64) (PendingTransfer, error) {
+	defer q.lock()()
+	return q.data.pendingTransfers.get(id)
+}
+
+// GetPendingTransferForUpdate needs no row lock, as transactions run one at a time.
+func (q *memQueries) GetPendingTransferForUpdate(ctx context.Context, id int64) (PendingTransfer, error) {
+	defer q.lock()()
+	return q.data.pendingTransfers.get(id)
+}
+
+func (q *memQueries) ListPendingTransfers(ctx context.Context, arg ListPendingTransfersParams) ([]PendingTransfer, error) {
+	defer q.lock()()
+
+	pending := q.data.pendingTransfers.list(func(pending PendingTransfer) bool {
+		return pending.Status == arg.Status
+	})
+	return page(pending, arg.Limit, arg.Offset), nil
+}
+
+// ListExpiredPendingTransfersForUpdate needs no row lock, as transactions run one at a time.
+func (q *memQueries) ListExpiredPendingTransfersForUpdate(ctx context.Context, arg ListExpiredPendingTransfersForUpdateParams) ([]PendingTransfer, error) {
+	defer q.lock()()
+
+	expired := q.data.pendingTransfers.list(func(pending PendingTransfer) bool {
+		return pending.Status == PendingTransferStatusPending && !pending.ExpiresAt.After(arg.Now)
+	})
+	return page(expired, arg.Limit, 0), nil
+}
+
+func (q *memQueries) DecidePendingTransfer(ctx context.Context, arg DecidePendingTransferParams) (PendingTransfer, error) {
+	defer q.lock()()
+
+	pending, err := q.data.pendingTransfers.get(arg.ID)
+	if err != nil {
+		return pending, err
+	}
+	if arg.TransferID.Valid && !q.data.transfers.exists(arg.TransferID.Int64) {
+		return PendingTransfer{}, foreignKeyViolation("pending_transfers", "pending_transfers_transfer_id_fkey")
+	}
+
+	pending.Status = arg.Status
+	pending.DecidedBy = arg.DecidedBy
+	pending.DecidedAt = pgtype.Timestamptz{Time: memNow(), Valid: true}
+	pending.TransferID = arg.TransferID
+	q.data.pendingTransfers.rows[pending.ID] = pending
+	return pending, nil
+}
@@ -5,16 +5,185 @@
 package db
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "active"
+	AccountStatusFrozen AccountStatus = "frozen"
+	AccountStatusClosed AccountStatus = "closed"
+)
+
+func (e *AccountStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AccountStatus(s)
+	case string:
+		*e = AccountStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AccountStatus: %T", src)
+	}
+	return nil
+}
+
+type NullAccountStatus struct {
+	AccountStatus AccountStatus `json:"account_status"`
+	Valid         bool          `json:"valid"` // Valid is true if AccountStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAccountStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.AccountStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AccountStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAccountStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AccountStatus), nil
+}
+
+type HoldStatus string
+
+const (
+	HoldStatusPending  HoldStatus = "pending"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusReleased HoldStatus = "released"
+)
+
+func (e *HoldStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = HoldStatus(s)
+	case string:
+		*e = HoldStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for HoldStatus: %T", src)
+	}
+	return nil
+}
+
+type NullHoldStatus struct {
+	HoldStatus HoldStatus `json:"hold_status"`
+	Valid      bool       `json:"valid"` // Valid is true if HoldStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullHoldStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.HoldStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.HoldStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullHoldStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.HoldStatus), nil
+}
+
+type PendingTransferStatus string
+
+const (
+	PendingTransferStatusPending  PendingTransferStatus = "pending"
+	PendingTransferStatusApproved PendingTransferStatus = "approved"
+	PendingTransferStatusRejected PendingTransferStatus = "rejected"
+	PendingTransferStatusExpired  PendingTransferStatus = "expired"
+)
+
+func (e *PendingTransferStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PendingTransferStatus(s)
+	case string:
+		*e = PendingTransferStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PendingTransferStatus: %T", src)
+	}
+	return nil
+}
+
+type NullPendingTransferStatus struct {
+	PendingTransferStatus PendingTransferStatus `json:"pending_transfer_status"`
+	Valid                 bool                  `json:"valid"` // Valid is true if PendingTransferStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPendingTransferStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.PendingTransferStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PendingTransferStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPendingTransferStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PendingTransferStatus), nil
+}
+
 type Account struct {
-	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
-	Balance   int64     `json:"balance"`
-	Currency  string    `json:"currency"`
+	ID        int64         `json:"id"`
+	Owner     string        `json:"owner"`
+	Balance   int64         `json:"balance"`
+	Currency  string        `json:"currency"`
+	CreatedAt time.Time     `json:"created_at"`
+	Status    AccountStatus `json:"status"`
+	// the user who froze the account, null unless frozen
+	FrozenBy     pgtype.Text `json:"frozen_by"`
+	LastEntrySeq int64       `json:"last_entry_seq"`
+}
+
+type AccountLimit struct {
+	AccountID   int64       `json:"account_id"`
+	PerTransfer pgtype.Int8 `json:"per_transfer"`
+	Daily       pgtype.Int8 `json:"daily"`
+	Monthly     pgtype.Int8 `json:"monthly"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// single row holding the hash of the last audit event, locked by the chainer to append to the chain
+type AuditChainHead struct {
+	ID      bool        `json:"id"`
+	EventID pgtype.Int8 `json:"event_id"`
+	Hash    []byte      `json:"hash"`
+}
+
+type AuditEvent struct {
+	ID        int64  `json:"id"`
+	Action    string `json:"action"`
+	Actor     string `json:"actor"`
+	Subject   string `json:"subject"`
+	ClientIp  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	// kept as json rather than jsonb so the hashed text is stored as is
+	Before   []byte `json:"before"`
+	After    []byte `json:"after"`
+	PrevHash []byte `json:"prev_hash"`
+	// HMAC-SHA256 of prev_hash and the other columns but id, keyed with a key kept out of the database
+	Hash      []byte    `json:"hash"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -24,6 +193,38 @@ type Entry struct {
 	// can be +ve or +ve
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// position of the entry on its account, taken from accounts.last_entry_seq under the account row lock so it follows commit order
+	Seq int64 `json:"seq"`
+}
+
+type Hold struct {
+	ID          int64 `json:"id"`
+	AccountID   int64 `json:"account_id"`
+	ToAccountID int64 `json:"to_account_id"`
+	// it must be positive
+	Amount         int64      `json:"amount"`
+	CapturedAmount int64      `json:"captured_amount"`
+	Status         HoldStatus `json:"status"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type PendingTransfer struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	// it must be positive, in the currency of the source account
+	Amount      int64  `json:"amount"`
+	RequestedBy string `json:"requested_by"`
+	// why the transfer needs approval
+	Reason    string                `json:"reason"`
+	Status    PendingTransferStatus `json:"status"`
+	DecidedBy string                `json:"decided_by"`
+	DecidedAt pgtype.Timestamptz    `json:"decided_at"`
+	// the transfer posted once approved
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	ExpiresAt  time.Time   `json:"expires_at"`
 }
 
 type Session struct {
@@ -37,6 +238,37 @@ type Session struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// the events recorded by the transactions, until the chainer moves them to audit_events
+type StagedAuditEvent struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Subject   string    `json:"subject"`
+	ClientIp  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	Before    []byte    `json:"before"`
+	After     []byte    `json:"after"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TaskOutbox struct {
+	ID       int64           `json:"id"`
+	TaskType string          `json:"task_type"`
+	Payload  json.RawMessage `json:"payload"`
+	Queue    string          `json:"queue"`
+	MaxRetry int32           `json:"max_retry"`
+	// null until the task is enqueued
+	DispatchedAt pgtype.Timestamptz `json:"dispatched_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+	// the number of times a relay claimed the task
+	Attempts int32 `json:"attempts"`
+	// the task is not claimed before, while a relay enqueues it or after it failed
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+	// set once the task failed too many times, it is no longer enqueued
+	FailedAt pgtype.Timestamptz `json:"failed_at"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
@@ -54,3 +286,33 @@ type User struct {
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
+
+type WebhookDelivery struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int64     `json:"subscription_id"`
+	EventID        int64     `json:"event_id"`
+	Attempt        int32     `json:"attempt"`
+	StatusCode     int32     `json:"status_code"`
+	Error          string    `json:"error"`
+	Succeeded      bool      `json:"succeeded"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type WebhookEvent struct {
+	ID        int64           `json:"id"`
+	Owner     string          `json:"owner"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	// null until delivery tasks are enqueued for the event
+	DispatchedAt pgtype.Timestamptz `json:"dispatched_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	Url        string    `json:"url"`
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,250 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: pending_transfer.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createPendingTransfer = `-- name: CreatePendingTransfer :one
+INSERT INTO pending_transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  requested_by,
+  reason,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING id, from_account_id, to_account_id, amount, requested_by, reason, status, decided_by, decided_at, transfer_id, created_at, expires_at
+`
+
+type CreatePendingTransferParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	RequestedBy   string    `json:"requested_by"`
+	Reason        string    `json:"reason"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, createPendingTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.RequestedBy,
+		arg.Reason,
+		arg.ExpiresAt,
+	)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.RequestedBy,
+		&i.Reason,
+		&i.Status,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.TransferID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const decidePendingTransfer = `-- name: DecidePendingTransfer :one
+UPDATE pending_transfers
+SET
+  status = $1,
+  decided_by = $2,
+  decided_at = now(),
+  transfer_id = $3
+WHERE id = $4
+RETURNING id, from_account_id, to_account_id, amount, requested_by, reason, status, decided_by, decided_at, transfer_id, created_at, expires_at
+`
+
+type DecidePendingTransferParams struct {
+	Status     PendingTransferStatus `json:"status"`
+	DecidedBy  string                `json:"decided_by"`
+	TransferID pgtype.Int8           `json:"transfer_id"`
+	ID         int64                 `json:"id"`
+}
+
+func (q *Queries) DecidePendingTransfer(ctx context.Context, arg DecidePendingTransferParams) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, decidePendingTransfer,
+		arg.Status,
+		arg.DecidedBy,
+		arg.TransferID,
+		arg.ID,
+	)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.RequestedBy,
+		&i.Reason,
+		&i.Status,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.TransferID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getPendingTransfer = `-- name: GetPendingTransfer :one
+SELECT id, from_account_id, to_account_id, amount, requested_by, reason, status, decided_by, decided_at, transfer_id, created_at, expires_at FROM pending_transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetPendingTransfer(ctx context.Context, id int64) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, getPendingTransfer, id)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.RequestedBy,
+		&i.Reason,
+		&i.Status,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.TransferID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getPendingTransferForUpdate = `-- name: GetPendingTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, requested_by, reason, status, decided_by, decided_at, transfer_id, created_at, expires_at FROM pending_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetPendingTransferForUpdate(ctx context.Context, id int64) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, getPendingTransferForUpdate, id)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.RequestedBy,
+		&i.Reason,
+		&i.Status,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.TransferID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const listExpiredPendingTransfersForUpdate = `-- name: ListExpiredPendingTransfersForUpdate :many
+SELECT id, from_account_id, to_account_id, amount, requested_by, reason, status, decided_by, decided_at, transfer_id, created_at, expires_at FROM pending_transfers
+WHERE status = 'pending'
+AND expires_at <= $1
+ORDER BY id
+LIMIT $2
+FOR NO KEY UPDATE SKIP LOCKED
+`
+
+type ListExpiredPendingTransfersForUpdateParams struct {
+	Now   time.Time `json:"now"`
+	Limit int32     `json:"limit"`
+}
+
+func (q *Queries) ListExpiredPendingTransfersForUpdate(ctx context.Context, arg ListExpiredPendingTransfersForUpdateParams) ([]PendingTransfer, error) {
+	rows, err := q.db.Query(ctx, listExpiredPendingTransfersForUpdate, arg.Now, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PendingTransfer{}
+	for rows.Next() {
+		var i PendingTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.RequestedBy,
+			&i.Reason,
+			&i.Status,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.TransferID,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingTransfers = `-- name: ListPendingTransfers :many
+SELECT id, from_account_id, to_account_id, amount, requested_by, reason, status, decided_by, decided_at, transfer_id, created_at, expires_at FROM pending_transfers
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListPendingTransfersParams struct {
+	Status PendingTransferStatus `json:"status"`
+	Limit  int32                 `json:"limit"`
+	Offset int32                 `json:"offset"`
+}
+
+func (q *Queries) ListPendingTransfers(ctx context.Context, arg ListPendingTransfersParams) ([]PendingTransfer, error) {
+	rows, err := q.db.Query(ctx, listPendingTransfers, arg.Status, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PendingTransfer{}
+	for rows.Next() {
+		var i PendingTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.RequestedBy,
+			&i.Reason,
+			&i.Status,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.TransferID,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -12,23 +12,78 @@ import (
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	BlockSession(ctx context.Context, id uuid.UUID) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) (int64, error)
+	// A claimed task is not claimed again before claimed_until, so the relay enqueues
+	// the tasks outside of the transaction while the other relays skip them.
+	ClaimOutboxTasks(ctx context.Context, arg ClaimOutboxTasksParams) ([]TaskOutbox, error)
+	CountEarlierSessions(ctx context.Context, arg CountEarlierSessionsParams) (CountEarlierSessionsRow, error)
+	CountTransfersBetween(ctx context.Context, arg CountTransfersBetweenParams) (int64, error)
+	CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
+	// The account row stays locked until the transaction commits, so the entries
+	// of an account commit in the order of their seq.
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateOutboxTask(ctx context.Context, arg CreateOutboxTaskParams) (TaskOutbox, error)
+	CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (PendingTransfer, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateStagedAuditEvent(ctx context.Context, arg CreateStagedAuditEventParams) (StagedAuditEvent, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteAccount(ctx context.Context, id int64) error
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
+	CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (WebhookEvent, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DecidePendingTransfer(ctx context.Context, arg DecidePendingTransferParams) (PendingTransfer, error)
+	DeleteStagedAuditEvent(ctx context.Context, id int64) error
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountBalance(ctx context.Context, id int64) (GetAccountBalanceRow, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountLimit(ctx context.Context, accountID int64) (AccountLimit, error)
+	GetAuditChainHead(ctx context.Context) (AuditChainHead, error)
+	GetAuditChainHeadForUpdate(ctx context.Context) (AuditChainHead, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetLatestSession(ctx context.Context, username string) (Session, error)
+	GetOutboxTask(ctx context.Context, id int64) (TaskOutbox, error)
+	GetPendingTransfer(ctx context.Context, id int64) (PendingTransfer, error)
+	GetPendingTransferForUpdate(ctx context.Context, id int64) (PendingTransfer, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetWebhookEvent(ctx context.Context, id int64) (WebhookEvent, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	// The balance before the first entry is the account balance less the entries after after_seq,
+	// the running sum then gives the balance after each entry. Pages are limited to max_events,
+	// the next page starts after the seq of the last event.
+	ListAccountEventsAfter(ctx context.Context, arg ListAccountEventsAfterParams) ([]ListAccountEventsAfterRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListExpiredPendingTransfersForUpdate(ctx context.Context, arg ListExpiredPendingTransfersForUpdateParams) ([]PendingTransfer, error)
+	ListHolds(ctx context.Context, arg ListHoldsParams) ([]Hold, error)
+	ListLedgerMismatches(ctx context.Context) ([]ListLedgerMismatchesRow, error)
+	ListPendingTransfers(ctx context.Context, arg ListPendingTransfersParams) ([]PendingTransfer, error)
+	ListPendingWebhookEvents(ctx context.Context, limit int32) ([]WebhookEvent, error)
+	ListStagedAuditEvents(ctx context.Context, limit int32) ([]StagedAuditEvent, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscription, error)
+	ListWebhookSubscriptionsForEvent(ctx context.Context, arg ListWebhookSubscriptionsForEventParams) ([]WebhookSubscription, error)
+	MarkOutboxTaskDispatched(ctx context.Context, id int64) error
+	MarkWebhookEventDispatched(ctx context.Context, id int64) error
+	NotifyAccountEvent(ctx context.Context, arg NotifyAccountEventParams) error
+	RecordOutboxTaskFailure(ctx context.Context, arg RecordOutboxTaskFailureParams) error
+	SumTransfersSince(ctx context.Context, arg SumTransfersSinceParams) (int64, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	UpdateAuditChainHead(ctx context.Context, arg UpdateAuditChainHeadParams) error
+	UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpsertAccountLimit(ctx context.Context, arg UpsertAccountLimitParams) (AccountLimit, error)
 }
 
 var _ Querier = (*Queries)(nil)
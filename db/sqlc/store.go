@@ -2,120 +2,362 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"slices"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/fee"
+	"github.com/MElghrbawy/simple_bank/limit"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/risk"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Store interface {
 	// Querier exposes all the query methods
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	GetTransferAllowance(ctx context.Context, accountID int64) (limit.Allowance, error)
+	UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error)
+	PlaceHoldTx(ctx context.Context, arg PlaceHoldTxParams) (PlaceHoldTxResult, error)
+	CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	ReleaseHoldTx(ctx context.Context, holdID int64) (Hold, error)
+	DispatchWebhookEventsTx(ctx context.Context, arg DispatchWebhookEventsTxParams) (int, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	DispatchOutboxTasks(ctx context.Context, arg DispatchOutboxTasksParams) (int, error)
+	DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserParams) (User, error)
+	CreateSessionTx(ctx context.Context, arg CreateSessionParams) (Session, error)
+	RecordAuditEventTx(ctx context.Context, arg RecordAuditEventTxParams) (StagedAuditEvent, error)
+	ChainAuditEventsTx(ctx context.Context, limit int32) (int, error)
+	VerifyAuditChainTx(ctx context.Context) (AuditChainReport, error)
+	GetTransferRiskSignals(ctx context.Context, transfer risk.Transfer, since time.Time) (risk.Signals, error)
+	CreatePendingTransferTx(ctx context.Context, arg CreatePendingTransferParams) (PendingTransfer, error)
+	ApprovePendingTransferTx(ctx context.Context, arg DecidePendingTransferTxParams) (ApprovePendingTransferTxResult, error)
+	RejectPendingTransferTx(ctx context.Context, arg DecidePendingTransferTxParams) (PendingTransfer, error)
+	ExpirePendingTransfersTx(ctx context.Context, limit int32) (int, error)
 }
 
+// SQLStore provides all functions to execute db queries and transactions
 type SQLStore struct {
-	db *sql.DB
+	connPool *pgxpool.Pool
 	*Queries
+	transactions
+	// replica serves the read-only queries routed by reader, nil without a replica.
+	replica *Queries
+}
+
+// transactions implements the transactions of the Store on top of the queries,
+// so every Store implementation runs the same business rules.
+type transactions struct {
+	// runTx runs fn once, atomically and in isolation from concurrent transactions.
+	// Zero options stand for the defaults of the database.
+	runTx     func(ctx context.Context, opts pgx.TxOptions, fn func(q Querier) error) error
+	txOptions pgx.TxOptions
+	txRetry   TxRetryPolicy
+	observeTx func(TxStats)
+	// observeTransfer is called once every TransferTx is over.
+	observeTransfer func(TransferStats)
+	feeSchedule     *fee.Schedule
+	limitDefaults   limit.Defaults
+	auditKey        []byte
+}
+
+// storeOptions holds the settings made by the StoreOptions.
+type storeOptions struct {
+	transactions
+	replica *pgxpool.Pool
+}
+
+// StoreOption configures optional behaviour of a Store.
+type StoreOption func(*storeOptions)
+
+func newStoreOptions(opts []StoreOption) storeOptions {
+	options := storeOptions{
+		transactions: transactions{txRetry: DefaultTxRetryPolicy},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithFeeSchedule makes TransferTx charge fees according to the schedule.
+func WithFeeSchedule(schedule *fee.Schedule) StoreOption {
+	return func(store *storeOptions) {
+		store.feeSchedule = schedule
+	}
 }
 
-func NewStore(db *sql.DB) Store {
-	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+func NewStore(connPool *pgxpool.Pool, opts ...StoreOption) Store {
+	options := newStoreOptions(opts)
+	store := &SQLStore{
+		connPool:     connPool,
+		Queries:      newQueries(connPool),
+		transactions: options.transactions,
+	}
+	store.transactions.runTx = store.runTx
+	if options.replica != nil {
+		store.replica = newQueries(options.replica)
 	}
+	return store
 }
 
-// execTx executes a function within a database transaction.
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+// runTx executes a function within a database transaction.
+func (store *SQLStore) runTx(ctx context.Context, opts pgx.TxOptions, fn func(Querier) error) error {
+	tx, err := store.connPool.BeginTx(ctx, opts)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
-	q := New(tx)
+	q := newQueries(tx)
 	err = fn(q)
 	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx error: %v, rb error: %v", err, rbErr)
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("tx error: %w, rb error: %v", err, rbErr)
 		}
 		return err
 	}
-	return tx.Commit()
+	return translateError(tx.Commit(ctx))
 }
 
-// TransferTxParams contains the input parameters of the transfer transaction
+var ErrCurrencyMismatch = apperr.New(apperr.Validation, "amount currency does not match the account currency")
+
+// ErrRevenueCurrencyMismatch is returned when the fee schedule credits fees to a revenue account in another currency.
+var ErrRevenueCurrencyMismatch = apperr.New(apperr.Internal, "revenue account currency does not match the account currency")
+
+// TransferTxParams contains the input parameters of the transfer transaction.
+// The amount must be in the currency of the source account.
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        money.Amount `json:"amount"`
 }
 
 // TransferTxResult is the output of the transfer transaction
 type TransferTxResult struct {
-	Transfer    Transfer `json:"transfer"`
-	FromAccount Account  `json:"from_account"`
-	ToAccount   Account  `json:"to_account"`
-	FromEntry   Entry    `json:"from_entry"`
-	ToEntry     Entry    `json:"to_entry"`
+	Transfer     Transfer      `json:"transfer"`
+	FromAccount  Account       `json:"from_account"`
+	ToAccount    Account       `json:"to_account"`
+	FromEntry    Entry         `json:"from_entry"`
+	ToEntry      Entry         `json:"to_entry"`
+	Fee          fee.Breakdown `json:"fee"`
+	FeeEntry     *Entry        `json:"fee_entry,omitempty"`
+	RevenueEntry *Entry        `json:"revenue_entry,omitempty"`
 }
 
 // var txKey = struct{}{}
 
 // TransferTx performs a money transfer from one account to the other.
 // It creates a transfer record, add account entries, and update accounts' balance within a single database transaction.
-func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+// Both accounts must hold the currency of the amount.
+// When the store has a fee schedule, the fee is debited from the source account as a separate entry and credited to the revenue account,
+// which must be active and hold the same currency.
+// The transfer is recorded in the audit log.
+// The transfer is refused with limit.ErrExceeded if it breaks the transfer limits of the source account,
+// with ErrInsufficientFunds if the available balance of the source account, net of its pending holds,
+// does not cover the amount and fee, and with ErrAccountFrozen or ErrAccountClosed if an account is not active.
+func (store *transactions) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	start := time.Now()
+
 	var result TransferTxResult
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, func(q Querier) error {
 		var err error
+		result, err = store.transfer(ctx, q, arg, 0)
+		return err
+	})
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
-		if err != nil {
-			return err
+	if store.observeTransfer != nil {
+		store.observeTransfer(TransferStats{
+			Amount:   arg.Amount,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+	return result, err
+}
+
+// transfer posts a transfer within the caller's transaction.
+// held is the amount of the hold the transfer captures, which the available balance of the source account
+// already excludes, or 0.
+func (store *transactions) transfer(ctx context.Context, q Querier, arg TransferTxParams, held int64) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	amount := arg.Amount.Units
+	currency := arg.Amount.Currency.Code
+
+	// both accounts must hold the currency of the amount, so the fee and its revenue account
+	// are known before the accounts are locked, and the revenue account is locked along with them
+	result.Fee = store.feeSchedule.Calculate(amount, currency, currency)
+	ids := []int64{arg.FromAccountID, arg.ToAccountID}
+	if result.Fee.Total > 0 {
+		if result.Fee.RevenueAccountID <= 0 {
+			return result, fee.ErrNoRevenueAccount
+		}
+		ids = append(ids, result.Fee.RevenueAccountID)
+	}
+
+	accounts, err := lockAccounts(ctx, q, ids...)
+	if err != nil {
+		return result, err
+	}
+	fromAccount, toAccount := accounts[arg.FromAccountID], accounts[arg.ToAccountID]
+
+	if err = checkAccountActive(fromAccount); err != nil {
+		return result, err
+	}
+	if err = checkAccountActive(toAccount); err != nil {
+		return result, err
+	}
+
+	for _, account := range []Account{fromAccount, toAccount} {
+		if account.Currency != currency {
+			return result, apperr.Wrapf(ErrCurrencyMismatch, apperr.Validation, "%s: %s vs %s", ErrCurrencyMismatch, currency, account.Currency)
 		}
+	}
+
+	if result.Fee.Total > 0 {
+		revenueAccount := accounts[result.Fee.RevenueAccountID]
+		if err = checkAccountActive(revenueAccount); err != nil {
+			return result, err
+		}
+		if revenueAccount.Currency != currency {
+			return result, apperr.Wrapf(ErrRevenueCurrencyMismatch, apperr.Internal, "%s: %s vs %s", ErrRevenueCurrencyMismatch, revenueAccount.Currency, currency)
+		}
+	}
+
+	err = store.checkTransferLimits(ctx, q, fromAccount, amount)
+	if err != nil {
+		return result, err
+	}
+
+	// the source account is locked, so no hold can be placed on it until the transfer commits
+	balance, err := q.GetAccountBalance(ctx, arg.FromAccountID)
+	if err != nil {
+		return result, err
+	}
+	if balance.AvailableBalance+held < amount+result.Fee.Total {
+		return result, ErrInsufficientFunds
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.FromAccountID,
+		Amount:    -amount,
+	})
+	if err != nil {
+		return result, err
+	}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.ToAccountID,
+		Amount:    amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	changes := map[int64]int64{}
+	changes[arg.FromAccountID] -= amount
+	changes[arg.ToAccountID] += amount
+
+	if result.Fee.Total > 0 {
+		feeEntry, err := q.CreateEntry(ctx, CreateEntryParams{
 			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+			Amount:    -result.Fee.Total,
 		})
 		if err != nil {
-			return err
+			return result, err
 		}
+		result.FeeEntry = &feeEntry
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		revenueEntry, err := q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: result.Fee.RevenueAccountID,
+			Amount:    result.Fee.Total,
 		})
 		if err != nil {
-			return err
+			return result, err
 		}
+		result.RevenueEntry = &revenueEntry
 
-		// update account balance
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-		}
+		changes[arg.FromAccountID] -= result.Fee.Total
+		changes[result.Fee.RevenueAccountID] += result.Fee.Total
+	}
 
-		return err
-	})
+	// update account balance
+	accounts, err = addBalances(ctx, q, changes)
+	if err != nil {
+		return result, err
+	}
+	result.FromAccount = accounts[arg.FromAccountID]
+	result.ToAccount = accounts[arg.ToAccountID]
+
+	entries := []Entry{result.FromEntry, result.ToEntry}
+	if result.FeeEntry != nil {
+		entries = append(entries, *result.FeeEntry, *result.RevenueEntry)
+	}
+	err = notifyAccountEvents(ctx, q, accounts, entries...)
+	if err != nil {
+		return result, err
+	}
+
+	err = recordTransferWebhookEvents(ctx, q, result, arg.Amount.Currency)
+	if err != nil {
+		return result, err
+	}
+
+	_, err = recordAuditEvent(ctx, q, AuditTransferCreated, AuditSubject("transfer", result.Transfer.ID),
+		auditTransfer{FromAccount: fromAccount, ToAccount: toAccount},
+		auditTransfer{Transfer: &result.Transfer, FromAccount: result.FromAccount, ToAccount: result.ToAccount, Fee: &result.Fee},
+	)
 	return result, err
 }
 
-func addMoney(
-	ctx context.Context,
-	q *Queries,
-	accountID1 int64,
-	amount1 int64,
-	accountId2 int64,
-	amount2 int64,
-) (account1 Account, account2 Account, err error) {
-	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID1,
-		Amount: amount1})
-	if err != nil {
-		return
+// lockAccounts selects the accounts for update in ascending id order,
+// so concurrent transactions always lock the account rows in the same order and cannot deadlock.
+func lockAccounts(ctx context.Context, q Querier, ids ...int64) (map[int64]Account, error) {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range slices.Compact(ids) {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
 	}
-	account2, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountId2,
-		Amount: amount2})
-	return
+	return accounts, nil
+}
 
+// addBalances applies the balance changes in ascending account id order.
+func addBalances(ctx context.Context, q Querier, changes map[int64]int64) (map[int64]Account, error) {
+	ids := make([]int64, 0, len(changes))
+	for id := range changes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		account, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     id,
+			Amount: changes[id],
+		})
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
 }
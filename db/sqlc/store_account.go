@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/webhook"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrAccountFrozen           = apperr.New(apperr.FailedPrecondition, "account is frozen")
+	ErrAccountClosed           = apperr.New(apperr.FailedPrecondition, "account is closed")
+	ErrAccountNotEmpty         = apperr.New(apperr.FailedPrecondition, "account balance must be zero to close it")
+	ErrInvalidStatusTransition = apperr.New(apperr.FailedPrecondition, "invalid account status transition")
+)
+
+// accountTransitions lists the statuses an account can move to from each status.
+var accountTransitions = map[AccountStatus][]AccountStatus{
+	AccountStatusActive: {AccountStatusFrozen, AccountStatusClosed},
+	AccountStatusFrozen: {AccountStatusActive, AccountStatusClosed},
+}
+
+// CreateAccountTx opens an account and records it in the audit log.
+func (store *transactions) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditAccountCreated, AuditSubject("account", account.ID), nil, account)
+		return err
+	})
+	return account, err
+}
+
+// UpdateAccountStatusTxParams contains the input parameters of the account status transaction
+type UpdateAccountStatusTxParams struct {
+	AccountID int64         `json:"account_id"`
+	Status    AccountStatus `json:"status"`
+	// ChangedBy is the username of the user changing the status, kept as the one who froze the account.
+	ChangedBy string `json:"changed_by"`
+}
+
+// UpdateAccountStatusTx moves an account to a new status.
+// Closed accounts are kept so their entries and transfers stay in the history, and can only be closed with a zero balance.
+// Who may change the status is up to the caller.
+func (store *transactions) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error) {
+	var account Account
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+
+		account, err = q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if !canTransition(account.Status, arg.Status) {
+			return apperr.Wrapf(ErrInvalidStatusTransition, apperr.FailedPrecondition, "%s: from %s to %s", ErrInvalidStatusTransition, account.Status, arg.Status)
+		}
+
+		if arg.Status == AccountStatusClosed && account.Balance != 0 {
+			return ErrAccountNotEmpty
+		}
+
+		before := account
+		account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:       arg.AccountID,
+			Status:   arg.Status,
+			FrozenBy: pgtype.Text{String: arg.ChangedBy, Valid: arg.Status == AccountStatusFrozen},
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditAccountStatusChanged, AuditSubject("account", account.ID), before, account)
+		if err != nil {
+			return err
+		}
+
+		return recordWebhookEvent(ctx, q, account.Owner, webhook.EventAccountStatusChanged, webhook.AccountStatusData{
+			AccountID: account.ID,
+			Status:    string(account.Status),
+		})
+	})
+	return account, err
+}
+
+func canTransition(from AccountStatus, to AccountStatus) bool {
+	for _, status := range accountTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAccountActive returns an error if money cannot move in or out of the account.
+func checkAccountActive(account Account) error {
+	switch account.Status {
+	case AccountStatusFrozen:
+		return apperr.Wrapf(ErrAccountFrozen, apperr.FailedPrecondition, "account %d: %s", account.ID, ErrAccountFrozen)
+	case AccountStatusClosed:
+		return apperr.Wrapf(ErrAccountClosed, apperr.FailedPrecondition, "account %d: %s", account.ID, ErrAccountClosed)
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/fee"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The actions recorded in the audit log.
+const (
+	AuditUserCreated          = "user.created"
+	AuditUserUpdated          = "user.updated"
+	AuditUserLoggedIn         = "user.logged_in"
+	AuditUserLoginFailed      = "user.login_failed"
+	AuditAccountCreated       = "account.created"
+	AuditAccountStatusChanged = "account.status_changed"
+	AuditTransferCreated      = "transfer.created"
+	AuditTransferDeclined     = "transfer.declined"
+	AuditTransferPending      = "transfer.pending"
+	AuditTransferApproved     = "transfer.approved"
+	AuditTransferRejected     = "transfer.rejected"
+	AuditTransferExpired      = "transfer.expired"
+	AuditDepositCreated       = "deposit.created"
+	AuditHoldPlaced           = "hold.placed"
+	AuditHoldCaptured         = "hold.captured"
+	AuditHoldReleased         = "hold.released"
+)
+
+// SystemActor is the actor of the actions performed outside of a request, such as by background tasks.
+const SystemActor = "system"
+
+// auditPageSize is the number of events VerifyAuditChainTx reads at once.
+const auditPageSize = 1000
+
+// ErrNoAuditKey is returned when chaining or verifying the audit log of a store without WithAuditKey.
+var ErrNoAuditKey = apperr.New(apperr.Internal, "the store has no audit key")
+
+// WithAuditKey sets the HMAC key the audit events are chained with.
+// It must be kept out of the database, so whoever can write to the database cannot forge the chain.
+func WithAuditKey(key []byte) StoreOption {
+	return func(store *storeOptions) {
+		store.auditKey = key
+	}
+}
+
+// Actor identifies who performs the actions audited within a context.
+type Actor struct {
+	Username  string
+	ClientIP  string
+	UserAgent string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose audited actions are attributed to actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor of ctx, or SystemActor if it has none.
+func ActorFromContext(ctx context.Context) Actor {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	if !ok {
+		return Actor{Username: SystemActor}
+	}
+	return actor
+}
+
+// RecordAuditEventTxParams contains the input parameters of the record audit event transaction.
+// Before and After are the snapshots of the subject, marshaled to JSON; nil ones are left out.
+type RecordAuditEventTxParams struct {
+	Action  string
+	Subject string
+	Before  any
+	After   any
+}
+
+// RecordAuditEventTx stages an event for the audit log on its own, for the actions that write nothing else,
+// such as a failed login. The other actions are audited by the transactions that perform them.
+func (store *transactions) RecordAuditEventTx(ctx context.Context, arg RecordAuditEventTxParams) (StagedAuditEvent, error) {
+	var event StagedAuditEvent
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		event, err = recordAuditEvent(ctx, q, arg.Action, arg.Subject, arg.Before, arg.After)
+		return err
+	})
+	return event, err
+}
+
+// ChainAuditEventsTx appends up to limit staged events to the audit log, in the order they were staged,
+// and returns how many. Only the chainer locks the head of the chain, so the transactions staging events
+// never wait for each other on it.
+func (store *transactions) ChainAuditEventsTx(ctx context.Context, limit int32) (int, error) {
+	if len(store.auditKey) == 0 {
+		return 0, ErrNoAuditKey
+	}
+
+	var chained int
+	err := store.execTx(ctx, func(q Querier) error {
+		chained = 0
+
+		head, err := q.GetAuditChainHeadForUpdate(ctx)
+		if err != nil {
+			return err
+		}
+
+		staged, err := q.ListStagedAuditEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+		if len(staged) == 0 {
+			return nil
+		}
+
+		for _, event := range staged {
+			arg := CreateAuditEventParams{
+				Action:    event.Action,
+				Actor:     event.Actor,
+				Subject:   event.Subject,
+				ClientIp:  event.ClientIp,
+				UserAgent: event.UserAgent,
+				Before:    event.Before,
+				After:     event.After,
+				PrevHash:  head.Hash,
+				CreatedAt: event.CreatedAt,
+			}
+			arg.Hash = auditEventHash(store.auditKey, arg)
+
+			chainedEvent, err := q.CreateAuditEvent(ctx, arg)
+			if err != nil {
+				return err
+			}
+			if err = q.DeleteStagedAuditEvent(ctx, event.ID); err != nil {
+				return err
+			}
+			head.EventID = pgtype.Int8{Int64: chainedEvent.ID, Valid: true}
+			head.Hash = chainedEvent.Hash
+			chained++
+		}
+
+		return q.UpdateAuditChainHead(ctx, UpdateAuditChainHeadParams{
+			EventID: head.EventID,
+			Hash:    head.Hash,
+		})
+	})
+	return chained, err
+}
+
+// AuditChainReport is the outcome of VerifyAuditChainTx.
+type AuditChainReport struct {
+	Events int64 `json:"events"`
+	// BrokenAt is the id of the first event that does not chain to the previous one, 0 if the chain is intact.
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+// Intact reports whether the audit log was not tampered with.
+func (report AuditChainReport) Intact() bool {
+	return report.Problem == ""
+}
+
+// VerifyAuditChainTx checks every event of the audit log chains to the previous one and matches its hash,
+// and that the last one is the head of the chain, so no event was changed, inserted or removed.
+// The staged events are not chained yet, so they are not checked.
+func (store *transactions) VerifyAuditChainTx(ctx context.Context) (AuditChainReport, error) {
+	var report AuditChainReport
+	if len(store.auditKey) == 0 {
+		return report, ErrNoAuditKey
+	}
+
+	// a snapshot of the whole log, so the events appended meanwhile do not move the head
+	opts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	err := store.execTxWithOptions(ctx, opts, func(q Querier) error {
+		report = AuditChainReport{}
+
+		var lastID int64
+		lastHash := []byte{}
+		for {
+			events, err := q.ListAuditEvents(ctx, ListAuditEventsParams{AfterID: lastID, Limit: auditPageSize})
+			if err != nil {
+				return err
+			}
+
+			for _, event := range events {
+				report.Events++
+				if !bytes.Equal(event.PrevHash, lastHash) {
+					report.BrokenAt, report.Problem = event.ID, "the event does not follow the previous one"
+					return nil
+				}
+				if !hmac.Equal(event.Hash, auditEventHash(store.auditKey, auditEventParams(event))) {
+					report.BrokenAt, report.Problem = event.ID, "the event does not match its hash"
+					return nil
+				}
+				lastID, lastHash = event.ID, event.Hash
+			}
+
+			if len(events) < auditPageSize {
+				break
+			}
+		}
+
+		head, err := q.GetAuditChainHead(ctx)
+		if err != nil {
+			return err
+		}
+		if head.EventID.Int64 != lastID || !bytes.Equal(head.Hash, lastHash) {
+			report.BrokenAt = head.EventID.Int64
+			report.Problem = fmt.Sprintf("the log ends at event %d but the chain at event %d", lastID, head.EventID.Int64)
+		}
+		return nil
+	})
+	return report, err
+}
+
+// recordAuditEvent stages an event for the audit log within the caller's transaction.
+// ChainAuditEventsTx appends it to the chain once the transaction commits.
+func recordAuditEvent(ctx context.Context, q Querier, action string, subject string, before any, after any) (StagedAuditEvent, error) {
+	beforeJSON, err := marshalSnapshot(before)
+	if err != nil {
+		return StagedAuditEvent{}, err
+	}
+	afterJSON, err := marshalSnapshot(after)
+	if err != nil {
+		return StagedAuditEvent{}, err
+	}
+
+	actor := ActorFromContext(ctx)
+	return q.CreateStagedAuditEvent(ctx, CreateStagedAuditEventParams{
+		Action:    action,
+		Actor:     actor.Username,
+		Subject:   subject,
+		ClientIp:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Before:    beforeJSON,
+		After:     afterJSON,
+		// the precision Postgres stores timestamps with, so the hash still matches once read back
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	})
+}
+
+func marshalSnapshot(snapshot any) ([]byte, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	return json.Marshal(snapshot)
+}
+
+// auditEventHash computes the HMAC of the hash of the previous event along with the columns of the event,
+// each prefixed with its length so different events cannot encode to the same bytes.
+func auditEventHash(key []byte, arg CreateAuditEventParams) []byte {
+	hash := hmac.New(sha256.New, key)
+	hash.Write(arg.PrevHash)
+
+	fields := [][]byte{
+		[]byte(arg.Action),
+		[]byte(arg.Actor),
+		[]byte(arg.Subject),
+		[]byte(arg.ClientIp),
+		[]byte(arg.UserAgent),
+		arg.Before,
+		arg.After,
+		[]byte(arg.CreatedAt.UTC().Format(time.RFC3339Nano)),
+	}
+	for _, field := range fields {
+		binary.Write(hash, binary.BigEndian, uint64(len(field)))
+		hash.Write(field)
+	}
+	return hash.Sum(nil)
+}
+
+func auditEventParams(event AuditEvent) CreateAuditEventParams {
+	return CreateAuditEventParams{
+		Action:    event.Action,
+		Actor:     event.Actor,
+		Subject:   event.Subject,
+		ClientIp:  event.ClientIp,
+		UserAgent: event.UserAgent,
+		Before:    event.Before,
+		After:     event.After,
+		PrevHash:  event.PrevHash,
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+// AuditSubject names the row an action applies to, such as "account:7".
+func AuditSubject(table string, id any) string {
+	return fmt.Sprintf("%s:%v", table, id)
+}
+
+// auditUser is the snapshot of a user in the audit log, which leaves the password hash out.
+type auditUser struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+// auditTransfer is the snapshot of the accounts of a transfer, before and after it.
+type auditTransfer struct {
+	Transfer    *Transfer      `json:"transfer,omitempty"`
+	FromAccount Account        `json:"from_account"`
+	ToAccount   Account        `json:"to_account"`
+	Fee         *fee.Breakdown `json:"fee,omitempty"`
+}
+
+// auditSession is the snapshot of a session in the audit log, which leaves the refresh token out.
+type auditSession struct {
+	SessionID string    `json:"session_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newAuditUser(user User) auditUser {
+	return auditUser{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		PasswordChangedAt: user.PasswordChangedAt,
+	}
+}
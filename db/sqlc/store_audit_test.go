@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemStoreAuditChainTampering(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tamper   func(data *memData)
+		brokenAt int64
+	}{
+		{
+			name: "Changed",
+			tamper: func(data *memData) {
+				event := data.auditEvents.rows[2]
+				event.After = []byte(`{"balance":1000000}`)
+				data.auditEvents.rows[2] = event
+			},
+			brokenAt: 2,
+		},
+		{
+			// rewriting the chain from the changed event on takes the key, not just write access
+			name: "Rehashed",
+			tamper: func(data *memData) {
+				for id := int64(2); id <= 3; id++ {
+					event := data.auditEvents.rows[id]
+					event.After = []byte(`{"balance":1000000}`)
+					event.PrevHash = data.auditEvents.rows[id-1].Hash
+					event.Hash = auditEventHash([]byte("guessed key"), auditEventParams(event))
+					data.auditEvents.rows[id] = event
+				}
+				data.auditChainHead.Hash = data.auditEvents.rows[3].Hash
+			},
+			brokenAt: 2,
+		},
+		{
+			name: "Removed",
+			tamper: func(data *memData) {
+				delete(data.auditEvents.rows, 2)
+			},
+			brokenAt: 3,
+		},
+		{
+			name: "LastRemoved",
+			tamper: func(data *memData) {
+				delete(data.auditEvents.rows, 3)
+			},
+			brokenAt: 3,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewMemStore(WithAuditKey(testAuditKey))
+			ctx := WithActor(context.Background(), Actor{Username: "alice"})
+			for i := 0; i < 3; i++ {
+				_, err := store.RecordAuditEventTx(ctx, RecordAuditEventTxParams{
+					Action:  AuditUserLoginFailed,
+					Subject: AuditSubject("user", "alice"),
+					After:   map[string]int{"attempt": i},
+				})
+				require.NoError(t, err)
+			}
+			chainAuditEvents(t, store)
+
+			report, err := store.VerifyAuditChainTx(ctx)
+			require.NoError(t, err)
+			require.Equal(t, AuditChainReport{Events: 3}, report)
+
+			tc.tamper(store.(*MemStore).data)
+
+			report, err = store.VerifyAuditChainTx(ctx)
+			require.NoError(t, err)
+			require.False(t, report.Intact())
+			require.Equal(t, tc.brokenAt, report.BrokenAt)
+		})
+	}
+}
+
+func TestMemStoreAuditChainWithoutKey(t *testing.T) {
+	store := NewMemStore()
+
+	// the events are still staged, to be chained once the key is set
+	_, err := store.RecordAuditEventTx(context.Background(), RecordAuditEventTxParams{
+		Action:  AuditUserLoginFailed,
+		Subject: AuditSubject("user", "alice"),
+	})
+	require.NoError(t, err)
+
+	_, err = store.ChainAuditEventsTx(context.Background(), 10)
+	require.ErrorIs(t, err, ErrNoAuditKey)
+	_, err = store.VerifyAuditChainTx(context.Background())
+	require.ErrorIs(t, err, ErrNoAuditKey)
+}
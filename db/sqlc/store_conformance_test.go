@@ -0,0 +1,818 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/limit"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/risk"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// testStoreConformance checks the behaviour every Store implementation must share.
+// Other tests may write to the same store, so each test works on its own users and accounts.
+func testStoreConformance(t *testing.T, store Store) {
+	t.Run("Users", func(t *testing.T) { testConformanceUsers(t, store) })
+	t.Run("Accounts", func(t *testing.T) { testConformanceAccounts(t, store) })
+	t.Run("TransferTx", func(t *testing.T) { testConformanceTransferTx(t, store) })
+	t.Run("TransferTxRollback", func(t *testing.T) { testConformanceTransferTxRollback(t, store) })
+	t.Run("Holds", func(t *testing.T) { testConformanceHolds(t, store) })
+	t.Run("AccountEvents", func(t *testing.T) { testConformanceAccountEvents(t, store) })
+	t.Run("Outbox", func(t *testing.T) { testConformanceOutbox(t, store) })
+	t.Run("Sessions", func(t *testing.T) { testConformanceSessions(t, store) })
+	t.Run("DepositTx", func(t *testing.T) { testConformanceDepositTx(t, store) })
+	t.Run("AuditLog", func(t *testing.T) { testConformanceAuditLog(t, store) })
+	t.Run("PendingTransfers", func(t *testing.T) { testConformancePendingTransfers(t, store) })
+	t.Run("TransferApprovals", func(t *testing.T) { testConformanceTransferApprovals(t, store) })
+	t.Run("RiskSignals", func(t *testing.T) { testConformanceRiskSignals(t, store) })
+}
+
+// testAuditKey is the key the audit events of the tests are chained with.
+var testAuditKey = []byte("audit-key-of-the-tests")
+
+func TestSQLStoreConformance(t *testing.T) {
+	testStoreConformance(t, NewStore(testPool, WithAuditKey(testAuditKey)))
+}
+
+func TestMemStoreConformance(t *testing.T) {
+	testStoreConformance(t, NewMemStore(WithAuditKey(testAuditKey)))
+}
+
+func conformanceUser(t *testing.T, store Store) User {
+	user, err := store.CreateUser(context.Background(), CreateUserParams{
+		Username:       util.RandomOwner() + util.RandomString(6),
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomString(12) + "@email.com",
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func conformanceAccount(t *testing.T, store Store, owner string, currency string, balance int64) Account {
+	account, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    owner,
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+// pgErrorKinds are the kinds the stores report the Postgres errors with.
+var pgErrorKinds = map[string]apperr.Kind{
+	UniqueViolation:     apperr.AlreadyExists,
+	ForeignKeyViolation: apperr.FailedPrecondition,
+}
+
+func requirePgError(t *testing.T, err error, code string) {
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, code, pgErr.Code)
+	require.Equal(t, pgErrorKinds[code], apperr.KindOf(err))
+}
+
+func testConformanceUsers(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+	require.True(t, user.PasswordChangedAt.IsZero())
+	require.NotZero(t, user.CreatedAt)
+
+	got, err := store.GetUser(ctx, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.Email, got.Email)
+	require.WithinDuration(t, user.CreatedAt, got.CreatedAt, time.Second)
+
+	_, err = store.GetUser(ctx, util.RandomString(20))
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = store.CreateUser(ctx, CreateUserParams{
+		Username:       user.Username,
+		HashedPassword: user.HashedPassword,
+		FullName:       user.FullName,
+		Email:          util.RandomString(12) + "@email.com",
+	})
+	requirePgError(t, err, UniqueViolation)
+
+	_, err = store.CreateUser(ctx, CreateUserParams{
+		Username:       util.RandomOwner() + util.RandomString(6),
+		HashedPassword: user.HashedPassword,
+		FullName:       user.FullName,
+		Email:          user.Email,
+	})
+	requirePgError(t, err, UniqueViolation)
+
+	fullName := util.RandomOwner()
+	updated, err := store.UpdateUser(ctx, UpdateUserParams{
+		Username: user.Username,
+		FullName: pgtype.Text{String: fullName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, fullName, updated.FullName)
+	require.Equal(t, user.Email, updated.Email)
+
+	_, err = store.UpdateUser(ctx, UpdateUserParams{Username: util.RandomString(20)})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func testConformanceAccounts(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+
+	account := conformanceAccount(t, store, user.Username, util.USD, 100)
+	require.Equal(t, AccountStatusActive, account.Status)
+
+	_, err := store.CreateAccount(ctx, CreateAccountParams{Owner: user.Username, Currency: util.USD})
+	requirePgError(t, err, UniqueViolation)
+
+	_, err = store.CreateAccount(ctx, CreateAccountParams{Owner: util.RandomString(20), Currency: util.USD})
+	requirePgError(t, err, ForeignKeyViolation)
+
+	_, err = store.GetAccount(ctx, account.ID+1_000_000)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// closed accounts free their currency for a new account
+	_, err = store.UpdateAccountStatus(ctx, UpdateAccountStatusParams{ID: account.ID, Status: AccountStatusClosed})
+	require.NoError(t, err)
+	conformanceAccount(t, store, user.Username, util.USD, 0)
+
+	conformanceAccount(t, store, user.Username, util.EUR, 0)
+	accounts, err := store.ListAccounts(ctx, ListAccountsParams{Owner: user.Username, Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Less(t, accounts[0].ID, accounts[1].ID)
+
+	updated, err := store.AddAccountBalance(ctx, AddAccountBalanceParams{ID: account.ID, Amount: -30})
+	require.NoError(t, err)
+	require.Equal(t, int64(70), updated.Balance)
+
+	_, err = store.CreateEntry(ctx, CreateEntryParams{AccountID: account.ID + 1_000_000, Amount: 1})
+	requirePgError(t, err, ForeignKeyViolation)
+}
+
+func testConformanceTransferTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 1000)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 1000)
+
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+
+	// transfer both ways concurrently
+	for i := 0; i < n; i++ {
+		from, to := account1, account2
+		if i%2 == 1 {
+			from, to = account2, account1
+		}
+
+		go func() {
+			_, err := store.TransferTx(ctx, TransferTxParams{
+				FromAccountID: from.ID,
+				ToAccountID:   to.ID,
+				Amount:        money.MustNew(amount, from.Currency),
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updated1, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	updated2, err := store.GetAccount(ctx, account2.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, account1.Balance, updated1.Balance)
+	require.Equal(t, account2.Balance, updated2.Balance)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(amount, account1.Currency),
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+amount, result.ToAccount.Balance)
+	require.Equal(t, -amount, result.FromEntry.Amount)
+	require.Equal(t, amount, result.ToEntry.Amount)
+
+	transfer, err := store.GetTransfer(ctx, result.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, amount, transfer.Amount)
+}
+
+func testConformanceTransferTxRollback(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 1000)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 1000)
+
+	_, err := store.UpsertAccountLimit(ctx, UpsertAccountLimitParams{
+		AccountID:   account1.ID,
+		PerTransfer: pgtype.Int8{Int64: 50, Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(51, account1.Currency),
+	})
+	require.ErrorIs(t, err, limit.ErrExceeded)
+
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{AccountID: account2.ID, Status: AccountStatusFrozen})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(10, account1.Currency),
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID + 1_000_000,
+		Amount:        money.MustNew(10, account1.Currency),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	updated1, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updated1.Balance)
+
+	allowance, err := store.GetTransferAllowance(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), allowance.Usage.Daily)
+}
+
+func testConformanceHolds(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+
+	placed, err := store.PlaceHoldTx(ctx, PlaceHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      80,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(20), placed.AvailableBalance)
+
+	_, err = store.PlaceHoldTx(ctx, PlaceHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      21,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// nor can a transfer spend the held funds
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(21, util.USD),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	captured, err := store.CaptureHoldTx(ctx, CaptureHoldTxParams{HoldID: placed.Hold.ID, Amount: 50})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusCaptured, captured.Hold.Status)
+	require.Equal(t, int64(50), captured.Transfer.ToAccount.Balance)
+
+	balance, err := store.GetAccountBalance(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(50), balance.Balance)
+	require.Equal(t, int64(50), balance.AvailableBalance)
+
+	_, err = store.ReleaseHoldTx(ctx, placed.Hold.ID)
+	require.ErrorIs(t, err, ErrHoldNotPending)
+
+	// a hold can only be captured into an account in the same currency
+	euroAccount := conformanceAccount(t, store, conformanceUser(t, store).Username, util.EUR, 0)
+	_, err = store.PlaceHoldTx(ctx, PlaceHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: euroAccount.ID,
+		Amount:      10,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	released, err := store.PlaceHoldTx(ctx, PlaceHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      10,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	hold, err := store.ReleaseHoldTx(ctx, released.Hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusReleased, hold.Status)
+
+	chainAuditEvents(t, store)
+	for holdID, action := range map[int64]string{placed.Hold.ID: AuditHoldCaptured, released.Hold.ID: AuditHoldReleased} {
+		events, err := store.ListAuditEvents(ctx, ListAuditEventsParams{
+			Subject: pgtype.Text{String: AuditSubject("hold", holdID), Valid: true},
+			Limit:   10,
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, AuditHoldPlaced, events[0].Action)
+		require.Nil(t, events[0].Before)
+		require.Equal(t, action, events[1].Action)
+	}
+}
+
+func testConformanceAccountEvents(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+
+	var results []TransferTxResult
+	for i := 0; i < 3; i++ {
+		result, err := store.TransferTx(ctx, TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        money.MustNew(10, account1.Currency),
+		})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+
+	events, err := store.ListAccountEventsAfter(ctx, ListAccountEventsAfterParams{
+		AccountID: account2.ID,
+		AfterSeq:  results[0].ToEntry.Seq,
+		MaxEvents: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	for i, event := range events {
+		require.Equal(t, results[i+1].ToEntry.ID, event.ID)
+		require.Equal(t, int64(i+2), event.Seq)
+		require.Equal(t, results[i+1].ToAccount.Balance, event.Balance)
+	}
+
+	// a page ends at max_events, with the balances of the entries it holds
+	events, err = store.ListAccountEventsAfter(ctx, ListAccountEventsAfterParams{
+		AccountID: account2.ID,
+		AfterSeq:  0,
+		MaxEvents: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, results[0].ToEntry.ID, events[0].ID)
+	require.Equal(t, results[0].ToAccount.Balance, events[0].Balance)
+}
+
+func testConformanceOutbox(t *testing.T, store Store) {
+	ctx := context.Background()
+	existing := conformanceUser(t, store)
+	taskType := "task:conformance_" + util.RandomString(6)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwner() + util.RandomString(6),
+			HashedPassword: util.RandomString(32),
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomString(12) + "@email.com",
+		},
+		Tasks: []CreateOutboxTaskParams{{TaskType: taskType, Payload: []byte(`{}`), Queue: "default", MaxRetry: 1}},
+	}
+
+	_, err := store.CreateUserTx(ctx, arg)
+	require.NoError(t, err)
+
+	duplicate := arg
+	duplicate.Username = existing.Username
+	_, err = store.CreateUserTx(ctx, duplicate)
+	requirePgError(t, err, UniqueViolation)
+
+	var dispatched int
+	for {
+		n, err := store.DispatchOutboxTasks(ctx, DispatchOutboxTasksParams{
+			Limit: 100,
+			Dispatch: func(task TaskOutbox) error {
+				if task.TaskType == taskType {
+					dispatched++
+				}
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+	require.Equal(t, 1, dispatched)
+
+	// a task that cannot be enqueued is tried again later, without holding up the others
+	poisoned, err := store.CreateOutboxTask(ctx, CreateOutboxTaskParams{TaskType: taskType + "_poisoned", Payload: []byte(`{}`), Queue: "default"})
+	require.NoError(t, err)
+	healthy, err := store.CreateOutboxTask(ctx, CreateOutboxTaskParams{TaskType: taskType, Payload: []byte(`{}`), Queue: "default"})
+	require.NoError(t, err)
+
+	var claimed []int64
+	var errs []error
+	for {
+		n, err := store.DispatchOutboxTasks(ctx, DispatchOutboxTasksParams{
+			Limit: 100,
+			Dispatch: func(task TaskOutbox) error {
+				claimed = append(claimed, task.ID)
+				if task.ID == poisoned.ID {
+					return errors.New("cannot enqueue")
+				}
+				return nil
+			},
+		})
+		errs = append(errs, err)
+		if n == 0 {
+			break
+		}
+	}
+	require.ErrorContains(t, errors.Join(errs...), "cannot enqueue")
+	// the failed task waits for its next attempt instead of being claimed again right away
+	require.Equal(t, 1, countOf(claimed, poisoned.ID))
+	require.Equal(t, 1, countOf(claimed, healthy.ID))
+
+	got, err := store.GetOutboxTask(ctx, poisoned.ID)
+	require.NoError(t, err)
+	require.False(t, got.DispatchedAt.Valid)
+	require.False(t, got.FailedAt.Valid)
+	require.Equal(t, int32(1), got.Attempts)
+	require.Equal(t, "cannot enqueue", got.LastError)
+	require.True(t, got.NextAttemptAt.After(time.Now()))
+
+	got, err = store.GetOutboxTask(ctx, healthy.ID)
+	require.NoError(t, err)
+	require.True(t, got.DispatchedAt.Valid)
+}
+
+func testConformanceSessions(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+
+	sessions := make([]Session, 2)
+	for i := range sessions {
+		session, err := store.CreateSession(ctx, CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     user.Username,
+			RefreshToken: util.RandomString(32),
+			ExpiresAt:    time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		sessions[i] = session
+	}
+
+	blocked, err := store.BlockSession(ctx, sessions[0].ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	count, err := store.BlockUserSessions(ctx, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	got, err := store.GetSession(ctx, sessions[1].ID)
+	require.NoError(t, err)
+	require.True(t, got.IsBlocked)
+
+	_, err = store.BlockSession(ctx, uuid.New())
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func testConformanceDepositTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+	account := conformanceAccount(t, store, user.Username, util.USD, 0)
+
+	result, err := store.DepositTx(ctx, DepositTxParams{
+		AccountID: account.ID,
+		Amount:    money.MustNew(250, util.USD),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(250), result.Account.Balance)
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, int64(250), result.Entry.Amount)
+
+	_, err = store.DepositTx(ctx, DepositTxParams{AccountID: account.ID, Amount: money.MustNew(0, util.USD)})
+	require.Equal(t, apperr.Validation, apperr.KindOf(err))
+
+	_, err = store.DepositTx(ctx, DepositTxParams{AccountID: account.ID, Amount: money.MustNew(10, util.EUR)})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = store.UpdateAccountStatus(ctx, UpdateAccountStatusParams{ID: account.ID, Status: AccountStatusFrozen})
+	require.NoError(t, err)
+	_, err = store.DepositTx(ctx, DepositTxParams{AccountID: account.ID, Amount: money.MustNew(10, util.USD)})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// the deposit keeps the account balance equal to its entries, a balance set directly does not
+	unbalanced := conformanceAccount(t, store, user.Username, util.EUR, 0)
+	_, err = store.AddAccountBalance(ctx, AddAccountBalanceParams{ID: unbalanced.ID, Amount: 40})
+	require.NoError(t, err)
+
+	mismatches, err := store.ListLedgerMismatches(ctx)
+	require.NoError(t, err)
+
+	var found []ListLedgerMismatchesRow
+	for _, mismatch := range mismatches {
+		if mismatch.Owner == user.Username {
+			found = append(found, mismatch)
+		}
+	}
+	require.Equal(t, []ListLedgerMismatchesRow{{
+		ID:           unbalanced.ID,
+		Owner:        user.Username,
+		Currency:     util.EUR,
+		Balance:      40,
+		EntriesTotal: 0,
+	}}, found)
+}
+
+func testConformanceAuditLog(t *testing.T, store Store) {
+	user := conformanceUser(t, store)
+	actor := Actor{Username: user.Username, ClientIP: "10.0.0.1", UserAgent: "test"}
+	ctx := WithActor(context.Background(), actor)
+
+	account, err := store.CreateAccountTx(ctx, CreateAccountParams{Owner: user.Username, Currency: util.USD})
+	require.NoError(t, err)
+	frozen, err := store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{AccountID: account.ID, Status: AccountStatusFrozen})
+	require.NoError(t, err)
+
+	// the events are staged until chained
+	subject := AuditSubject("account", account.ID)
+	arg := ListAuditEventsParams{
+		Subject: pgtype.Text{String: subject, Valid: true},
+		Limit:   10,
+	}
+	events, err := store.ListAuditEvents(ctx, arg)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	chainAuditEvents(t, store)
+	events, err = store.ListAuditEvents(ctx, arg)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	require.Equal(t, AuditAccountCreated, events[0].Action)
+	require.Nil(t, events[0].Before)
+	require.JSONEq(t, string(mustMarshal(t, account)), string(events[0].After))
+
+	require.Equal(t, AuditAccountStatusChanged, events[1].Action)
+	require.Equal(t, actor, Actor{Username: events[1].Actor, ClientIP: events[1].ClientIp, UserAgent: events[1].UserAgent})
+	require.JSONEq(t, string(mustMarshal(t, account)), string(events[1].Before))
+	require.JSONEq(t, string(mustMarshal(t, frozen)), string(events[1].After))
+
+	// the password hash is never recorded
+	_, err = store.UpdateUserTx(ctx, UpdateUserParams{
+		Username:       user.Username,
+		HashedPassword: pgtype.Text{String: util.RandomString(32), Valid: true},
+	})
+	require.NoError(t, err)
+	failed, err := store.RecordAuditEventTx(context.Background(), RecordAuditEventTxParams{
+		Action:  AuditUserLoginFailed,
+		Subject: AuditSubject("user", user.Username),
+	})
+	require.NoError(t, err)
+	require.Equal(t, SystemActor, failed.Actor)
+
+	chainAuditEvents(t, store)
+	events, err = store.ListAuditEvents(ctx, ListAuditEventsParams{
+		Subject: pgtype.Text{String: AuditSubject("user", user.Username), Valid: true},
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, AuditUserUpdated, events[0].Action)
+	require.NotContains(t, string(events[0].Before)+string(events[0].After), "hashed_password")
+	require.Equal(t, AuditUserLoginFailed, events[1].Action)
+
+	// the failed operations leave no event
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{AccountID: account.ID, Status: AccountStatusFrozen})
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
+	chainAuditEvents(t, store)
+	events, err = store.ListAuditEvents(ctx, ListAuditEventsParams{AfterID: events[1].ID, Actor: pgtype.Text{String: user.Username, Valid: true}, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	report, err := store.VerifyAuditChainTx(ctx)
+	require.NoError(t, err)
+	require.True(t, report.Intact(), report.Problem)
+	require.GreaterOrEqual(t, report.Events, int64(4))
+}
+
+// chainAuditEvents chains all the staged audit events, including the ones other tests left behind.
+func chainAuditEvents(t *testing.T, store Store) {
+	for {
+		chained, err := store.ChainAuditEventsTx(context.Background(), 100)
+		require.NoError(t, err)
+		if chained == 0 {
+			return
+		}
+	}
+}
+
+func countOf[T comparable](values []T, value T) int {
+	count := 0
+	for _, v := range values {
+		if v == value {
+			count++
+		}
+	}
+	return count
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func testConformancePendingTransfers(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+	account1 := conformanceAccount(t, store, user.Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+
+	park := func(amount int64) PendingTransfer {
+		pending, err := store.CreatePendingTransferTx(ctx, CreatePendingTransferParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+			RequestedBy:   user.Username,
+			Reason:        "unusual client IP",
+			ExpiresAt:     time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		require.Equal(t, PendingTransferStatusPending, pending.Status)
+		return pending
+	}
+
+	// nothing moves until the transfer is approved
+	approved := park(30)
+	rejected := park(40)
+	balance, err := store.GetAccountBalance(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), balance.Balance)
+
+	pending, err := store.ListPendingTransfers(ctx, ListPendingTransfersParams{Status: PendingTransferStatusPending, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []PendingTransfer{approved, rejected}, pending)
+
+	result, err := store.ApprovePendingTransferTx(ctx, DecidePendingTransferTxParams{ID: approved.ID, DecidedBy: "banker"})
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusApproved, result.PendingTransfer.Status)
+	require.Equal(t, "banker", result.PendingTransfer.DecidedBy)
+	require.True(t, result.PendingTransfer.DecidedAt.Valid)
+	require.Equal(t, result.Transfer.Transfer.ID, result.PendingTransfer.TransferID.Int64)
+	require.Equal(t, int64(70), result.Transfer.FromAccount.Balance)
+	require.Equal(t, int64(30), result.Transfer.ToAccount.Balance)
+
+	decided, err := store.RejectPendingTransferTx(ctx, DecidePendingTransferTxParams{ID: rejected.ID, DecidedBy: "banker"})
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusRejected, decided.Status)
+	require.False(t, decided.TransferID.Valid)
+
+	// a transfer is decided once
+	_, err = store.ApprovePendingTransferTx(ctx, DecidePendingTransferTxParams{ID: rejected.ID, DecidedBy: "banker"})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+	_, err = store.RejectPendingTransferTx(ctx, DecidePendingTransferTxParams{ID: approved.ID, DecidedBy: "banker"})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	// an approval failing the checks of TransferTx leaves the transfer pending
+	blocked := park(10)
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{AccountID: account2.ID, Status: AccountStatusFrozen})
+	require.NoError(t, err)
+	_, err = store.ApprovePendingTransferTx(ctx, DecidePendingTransferTxParams{ID: blocked.ID, DecidedBy: "banker"})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+	got, err := store.GetPendingTransfer(ctx, blocked.ID)
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusPending, got.Status)
+
+	_, err = store.ApprovePendingTransferTx(ctx, DecidePendingTransferTxParams{ID: blocked.ID + 1000, DecidedBy: "banker"})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func testConformanceTransferApprovals(t *testing.T, store Store) {
+	ctx := context.Background()
+	requester := conformanceUser(t, store)
+	account1 := conformanceAccount(t, store, requester.Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+
+	park := func(ttl time.Duration) PendingTransfer {
+		pending, err := store.CreatePendingTransferTx(ctx, CreatePendingTransferParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+			RequestedBy:   requester.Username,
+			Reason:        "amount above the approval threshold",
+			ExpiresAt:     time.Now().Add(ttl),
+		})
+		require.NoError(t, err)
+		return pending
+	}
+
+	// the requester cannot decide on their own transfer, even as a banker
+	pending := park(time.Hour)
+	_, err := store.ApprovePendingTransferTx(ctx, DecidePendingTransferTxParams{ID: pending.ID, DecidedBy: requester.Username})
+	require.ErrorIs(t, err, ErrSelfApproval)
+	_, err = store.RejectPendingTransferTx(ctx, DecidePendingTransferTxParams{ID: pending.ID, DecidedBy: requester.Username})
+	require.ErrorIs(t, err, ErrSelfApproval)
+
+	// an expired transfer cannot be approved, and is marked expired by the sweep
+	expiring := park(50 * time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	_, err = store.ApprovePendingTransferTx(ctx, DecidePendingTransferTxParams{ID: expiring.ID, DecidedBy: "banker"})
+	require.ErrorIs(t, err, ErrPendingTransferExpired)
+
+	expired, err := store.ExpirePendingTransfersTx(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, expired)
+
+	got, err := store.GetPendingTransfer(ctx, expiring.ID)
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusExpired, got.Status)
+	require.Equal(t, SystemActor, got.DecidedBy)
+
+	got, err = store.GetPendingTransfer(ctx, pending.ID)
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusPending, got.Status)
+
+	expired, err = store.ExpirePendingTransfersTx(ctx, 10)
+	require.NoError(t, err)
+	require.Zero(t, expired)
+
+	_, err = store.CreatePendingTransferTx(ctx, CreatePendingTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		RequestedBy:   requester.Username,
+		ExpiresAt:     time.Now(),
+	})
+	require.Error(t, err)
+}
+
+func testConformanceRiskSignals(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+	account1 := conformanceAccount(t, store, user.Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+	transfer := risk.Transfer{
+		Username:      user.Username,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(10, util.USD),
+		ClientIP:      "203.0.113.5",
+	}
+
+	signals, err := store.GetTransferRiskSignals(ctx, transfer, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, risk.Signals{}, signals)
+
+	login := func(clientIP string) Session {
+		// sessions are told apart by their creation time
+		time.Sleep(time.Millisecond)
+		session, err := store.CreateSession(ctx, CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     user.Username,
+			RefreshToken: util.RandomString(32),
+			ClientIp:     clientIP,
+			ExpiresAt:    time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		return session
+	}
+
+	login("203.0.113.5")
+	session := login("198.51.100.1")
+	_, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: money.MustNew(10, util.USD)})
+	require.NoError(t, err)
+
+	signals, err = store.GetTransferRiskSignals(ctx, transfer, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), signals.PayeeTransfers)
+	require.Equal(t, int64(1), signals.RecentTransfers)
+	require.WithinDuration(t, session.CreatedAt, signals.SessionCreatedAt, 0)
+	require.Equal(t, int64(1), signals.EarlierLogins)
+	require.True(t, signals.KnownClientIP)
+
+	// the last login does not make its client IP known
+	transfer.ClientIP = "198.51.100.1"
+	transfer.ToAccountID = account1.ID
+	signals, err = store.GetTransferRiskSignals(ctx, transfer, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, signals.PayeeTransfers)
+	require.Zero(t, signals.RecentTransfers)
+	require.False(t, signals.KnownClientIP)
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/money"
+)
+
+// DepositTxParams contains the input parameters of the deposit transaction.
+// The amount must be in the currency of the account.
+type DepositTxParams struct {
+	AccountID int64        `json:"account_id"`
+	Amount    money.Amount `json:"amount"`
+}
+
+// DepositTxResult is the output of the deposit transaction
+type DepositTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// DepositTx credits money brought into the bank from outside, such as a cash deposit, to an active account.
+// The entry keeps the account balance equal to the sum of its entries.
+func (store *transactions) DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error) {
+	var result DepositTxResult
+	err := store.execTx(ctx, func(q Querier) error {
+		if !arg.Amount.IsPositive() {
+			return apperr.New(apperr.Validation, "deposit amount must be positive")
+		}
+
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if err = checkAccountActive(account); err != nil {
+			return err
+		}
+
+		if arg.Amount.Currency.Code != account.Currency {
+			return apperr.Wrapf(ErrCurrencyMismatch, apperr.Validation, "%s: %s vs %s", ErrCurrencyMismatch, arg.Amount.Currency.Code, account.Currency)
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount.Units,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     arg.AccountID,
+			Amount: arg.Amount.Units,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditDepositCreated, AuditSubject("account", account.ID), account, result)
+		if err != nil {
+			return err
+		}
+
+		accounts := map[int64]Account{result.Account.ID: result.Account}
+		return notifyAccountEvents(ctx, q, accounts, result.Entry)
+	})
+	return result, err
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/MElghrbawy/simple_bank/event"
+)
+
+// notifyAccountEvents notifies an account event for each entry, in the order the
+// entries were created. The balance of each event is derived from the updated
+// account balance, so it accounts only for the entries before it. Postgres
+// delivers the notifications when the transaction commits.
+func notifyAccountEvents(ctx context.Context, q Querier, accounts map[int64]Account, entries ...Entry) error {
+	balances := make(map[int64]int64, len(accounts))
+	for id, account := range accounts {
+		balances[id] = account.Balance
+	}
+
+	events := make([]event.AccountEvent, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		events[i] = event.AccountEvent{
+			EventID:   entry.Seq,
+			AccountID: entry.AccountID,
+			Amount:    entry.Amount,
+			Balance:   balances[entry.AccountID],
+			CreatedAt: entry.CreatedAt,
+		}
+		balances[entry.AccountID] -= entry.Amount
+	}
+
+	for _, ev := range events {
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+
+		err = q.NotifyAccountEvent(ctx, NotifyAccountEventParams{
+			Channel: event.AccountChannel,
+			Payload: string(payload),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/money"
+)
+
+var (
+	ErrInsufficientFunds  = apperr.New(apperr.InsufficientFunds, "insufficient available balance")
+	ErrHoldNotPending     = apperr.New(apperr.FailedPrecondition, "hold is not pending")
+	ErrHoldExpired        = apperr.New(apperr.FailedPrecondition, "hold has expired")
+	ErrCaptureExceedsHold = apperr.New(apperr.Validation, "capture amount exceeds the held amount")
+)
+
+// PlaceHoldTxParams contains the input parameters of the place hold transaction
+type PlaceHoldTxParams struct {
+	AccountID   int64     `json:"account_id"`
+	ToAccountID int64     `json:"to_account_id"`
+	Amount      int64     `json:"amount"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// PlaceHoldTxResult is the output of the place hold transaction
+type PlaceHoldTxResult struct {
+	Hold             Hold  `json:"hold"`
+	AvailableBalance int64 `json:"available_balance"`
+}
+
+// PlaceHoldTx reserves funds of an account for a later transfer to an account in the same currency.
+// The hold reduces the available balance of the account until it is captured, released or expires,
+// but no entries are posted until it is captured.
+func (store *transactions) PlaceHoldTx(ctx context.Context, arg PlaceHoldTxParams) (PlaceHoldTxResult, error) {
+	var result PlaceHoldTxResult
+
+	if arg.Amount <= 0 {
+		return result, apperr.New(apperr.Validation, "hold amount must be positive")
+	}
+	if !arg.ExpiresAt.After(time.Now()) {
+		return result, apperr.New(apperr.Validation, "hold must expire in the future")
+	}
+
+	err := store.execTx(ctx, func(q Querier) error {
+		accounts, err := lockAccounts(ctx, q, arg.AccountID, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		account, toAccount := accounts[arg.AccountID], accounts[arg.ToAccountID]
+		if err = checkAccountActive(account); err != nil {
+			return err
+		}
+		if err = checkAccountActive(toAccount); err != nil {
+			return err
+		}
+		// the capture transfers the held amount, which both accounts must hold the currency of
+		if toAccount.Currency != account.Currency {
+			return apperr.Wrapf(ErrCurrencyMismatch, apperr.Validation, "%s: %s vs %s", ErrCurrencyMismatch, account.Currency, toAccount.Currency)
+		}
+
+		balance, err := q.GetAccountBalance(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		if balance.AvailableBalance < arg.Amount {
+			return ErrInsufficientFunds
+		}
+
+		result.Hold, err = q.CreateHold(ctx, CreateHoldParams(arg))
+		if err != nil {
+			return err
+		}
+
+		result.AvailableBalance = balance.AvailableBalance - arg.Amount
+
+		_, err = recordAuditEvent(ctx, q, AuditHoldPlaced, AuditSubject("hold", result.Hold.ID), nil, result.Hold)
+		return err
+	})
+	return result, err
+}
+
+// CaptureHoldTxParams contains the input parameters of the capture hold transaction.
+// A zero amount captures the whole hold.
+type CaptureHoldTxParams struct {
+	HoldID int64 `json:"hold_id"`
+	Amount int64 `json:"amount"`
+}
+
+// CaptureHoldTxResult is the output of the capture hold transaction
+type CaptureHoldTxResult struct {
+	Hold     Hold             `json:"hold"`
+	Transfer TransferTxResult `json:"transfer"`
+}
+
+// CaptureHoldTx settles a pending hold by posting the transfer for all or part of the held amount.
+// Whatever is not captured is released back to the available balance.
+func (store *transactions) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+	err := store.execTx(ctx, func(q Querier) error {
+		hold, err := lockPendingHold(ctx, q, arg.HoldID)
+		if err != nil {
+			return err
+		}
+
+		if !hold.ExpiresAt.After(time.Now()) {
+			return ErrHoldExpired
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount < 0 {
+			return apperr.New(apperr.Validation, "capture amount must be positive")
+		}
+		if amount > hold.Amount {
+			return ErrCaptureExceedsHold
+		}
+
+		account, err := q.GetAccount(ctx, hold.AccountID)
+		if err != nil {
+			return err
+		}
+
+		transferAmount, err := money.New(amount, account.Currency)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = store.transfer(ctx, q, TransferTxParams{
+			FromAccountID: hold.AccountID,
+			ToAccountID:   hold.ToAccountID,
+			Amount:        transferAmount,
+		}, hold.Amount)
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			ID:             hold.ID,
+			Status:         HoldStatusCaptured,
+			CapturedAmount: amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditHoldCaptured, AuditSubject("hold", hold.ID), hold, result.Hold)
+		return err
+	})
+	return result, err
+}
+
+// ReleaseHoldTx cancels a pending hold and makes its funds available again.
+func (store *transactions) ReleaseHoldTx(ctx context.Context, holdID int64) (Hold, error) {
+	var hold Hold
+	err := store.execTx(ctx, func(q Querier) error {
+		pending, err := lockPendingHold(ctx, q, holdID)
+		if err != nil {
+			return err
+		}
+
+		hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			ID:     holdID,
+			Status: HoldStatusReleased,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditHoldReleased, AuditSubject("hold", hold.ID), pending, hold)
+		return err
+	})
+	return hold, err
+}
+
+func lockPendingHold(ctx context.Context, q Querier, holdID int64) (Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return hold, err
+	}
+
+	if hold.Status != HoldStatusPending {
+		return hold, apperr.Wrapf(ErrHoldNotPending, apperr.FailedPrecondition, "hold %d is %s: %s", hold.ID, hold.Status, ErrHoldNotPending)
+	}
+	return hold, nil
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/limit"
+	"github.com/jackc/pgx/v5"
+)
+
+// WithTransferLimits sets the limits applied to accounts that have none of their own.
+func WithTransferLimits(defaults limit.Defaults) StoreOption {
+	return func(store *storeOptions) {
+		store.limitDefaults = defaults
+	}
+}
+
+// allowanceTxOptions reads the limits and the usage of an account from a single snapshot.
+var allowanceTxOptions = pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+
+// GetTransferAllowance returns the transfer limits of an account and how much of them is left.
+func (store *transactions) GetTransferAllowance(ctx context.Context, accountID int64) (limit.Allowance, error) {
+	var allowance limit.Allowance
+	err := store.execTxWithOptions(ctx, allowanceTxOptions, func(q Querier) error {
+		account, err := q.GetAccount(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		limits, err := store.transferLimits(ctx, q, account)
+		if err != nil {
+			return err
+		}
+
+		usage, err := transferUsage(ctx, q, account.ID, time.Now())
+		if err != nil {
+			return err
+		}
+
+		allowance = limits.Allowance(account.ID, usage)
+		return nil
+	})
+	return allowance, err
+}
+
+// checkTransferLimits makes sure the transfer fits in the limits of the source account.
+// The account row must be locked by the caller so concurrent transfers see each other's usage.
+func (store *transactions) checkTransferLimits(ctx context.Context, q Querier, account Account, amount int64) error {
+	limits, err := store.transferLimits(ctx, q, account)
+	if err != nil {
+		return err
+	}
+
+	if limits == (limit.Limits{}) {
+		return nil
+	}
+
+	usage, err := transferUsage(ctx, q, account.ID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := limits.Check(amount, usage); err != nil {
+		return apperr.Wrap(err, apperr.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+// transferLimits returns the limits of the account, falling back to the currency defaults
+// for every limit the account does not override.
+func (store *transactions) transferLimits(ctx context.Context, q Querier, account Account) (limit.Limits, error) {
+	limits := store.limitDefaults[account.Currency]
+
+	accountLimit, err := q.GetAccountLimit(ctx, account.ID)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return limits, nil
+		}
+		return limit.Limits{}, err
+	}
+
+	if accountLimit.PerTransfer.Valid {
+		limits.PerTransfer = accountLimit.PerTransfer.Int64
+	}
+	if accountLimit.Daily.Valid {
+		limits.Daily = accountLimit.Daily.Int64
+	}
+	if accountLimit.Monthly.Valid {
+		limits.Monthly = accountLimit.Monthly.Int64
+	}
+	return limits, nil
+}
+
+func transferUsage(ctx context.Context, q Querier, accountID int64, now time.Time) (limit.Usage, error) {
+	daily, err := q.SumTransfersSince(ctx, SumTransfersSinceParams{
+		FromAccountID: accountID,
+		Since:         limit.DayStart(now),
+	})
+	if err != nil {
+		return limit.Usage{}, err
+	}
+
+	monthly, err := q.SumTransfersSince(ctx, SumTransfersSinceParams{
+		FromAccountID: accountID,
+		Since:         limit.MonthStart(now),
+	})
+	if err != nil {
+		return limit.Usage{}, err
+	}
+
+	return limit.Usage{Daily: daily, Monthly: monthly}, nil
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CreateUserTxParams contains the input parameters of the create user transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	// Tasks are written to the task outbox in the same transaction as the user,
+	// so they are only enqueued if the user is created.
+	Tasks []CreateOutboxTaskParams
+}
+
+// CreateUserTxResult is the result of the create user transaction
+type CreateUserTxResult struct {
+	User User
+}
+
+// CreateUserTx creates a user, records its tasks in the outbox and the creation in the audit log.
+func (store *transactions) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditUserCreated, AuditSubject("user", result.User.Username), nil, newAuditUser(result.User))
+		if err != nil {
+			return err
+		}
+
+		for _, task := range arg.Tasks {
+			if _, err := q.CreateOutboxTask(ctx, task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+const (
+	// outboxClaimLease is how long a claimed task is left to the relay enqueuing it,
+	// before another relay may claim it again.
+	outboxClaimLease = 5 * time.Minute
+	// outboxRetryDelay is how long a task that failed to be enqueued waits for its next attempt.
+	outboxRetryDelay = 30 * time.Second
+	// outboxMaxAttempts is the number of attempts after which a task is set aside with failed_at.
+	outboxMaxAttempts = 10
+)
+
+// DispatchOutboxTasksParams contains the input parameters of DispatchOutboxTasks
+type DispatchOutboxTasksParams struct {
+	Limit int32
+	// Dispatch is called for every claimed task, outside of any transaction.
+	Dispatch func(task TaskOutbox) error
+}
+
+// DispatchOutboxTasks claims pending outbox tasks, hands them over to Dispatch and marks them dispatched.
+// The tasks are claimed in a short transaction and dispatched once it commits, so no row stays locked while
+// Dispatch reaches the queue. A task whose Dispatch fails is tried again after outboxRetryDelay, and set aside
+// after outboxMaxAttempts attempts, so a task that cannot be enqueued does not hold the others up.
+// It returns the number of dispatched tasks, along with the errors of the tasks that failed.
+func (store *transactions) DispatchOutboxTasks(ctx context.Context, arg DispatchOutboxTasksParams) (int, error) {
+	var tasks []TaskOutbox
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		tasks, err = q.ClaimOutboxTasks(ctx, ClaimOutboxTasksParams{
+			ClaimedUntil: time.Now().Add(outboxClaimLease),
+			MaxTasks:     arg.Limit,
+		})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var dispatched int
+	var errs []error
+	for _, task := range tasks {
+		if dispatchErr := arg.Dispatch(task); dispatchErr != nil {
+			errs = append(errs, fmt.Errorf("outbox task %d: %w", task.ID, dispatchErr))
+			err = store.execTx(ctx, func(q Querier) error {
+				return q.RecordOutboxTaskFailure(ctx, outboxTaskFailure(task, dispatchErr))
+			})
+		} else {
+			err = store.execTx(ctx, func(q Querier) error {
+				return q.MarkOutboxTaskDispatched(ctx, task.ID)
+			})
+			if err == nil {
+				dispatched++
+			}
+		}
+		// the claim of the task runs out, and another attempt is made
+		if err != nil {
+			errs = append(errs, fmt.Errorf("outbox task %d: %w", task.ID, err))
+		}
+	}
+	return dispatched, errors.Join(errs...)
+}
+
+// outboxTaskFailure schedules the next attempt of a task whose dispatch failed, or sets it aside
+// once it has no attempts left.
+func outboxTaskFailure(task TaskOutbox, err error) RecordOutboxTaskFailureParams {
+	now := time.Now()
+	arg := RecordOutboxTaskFailureParams{
+		ID:            task.ID,
+		LastError:     err.Error(),
+		NextAttemptAt: now.Add(outboxRetryDelay),
+	}
+	if task.Attempts >= outboxMaxAttempts {
+		arg.FailedAt = pgtype.Timestamptz{Time: now, Valid: true}
+	}
+	return arg
+}
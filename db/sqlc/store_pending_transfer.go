@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrTransferNotPending     = apperr.New(apperr.FailedPrecondition, "transfer is not pending")
+	ErrPendingTransferExpired = apperr.New(apperr.FailedPrecondition, "pending transfer has expired")
+	ErrSelfApproval           = apperr.New(apperr.PermissionDenied, "the requester of a transfer cannot decide on it")
+)
+
+// CreatePendingTransferTx parks a transfer until a banker other than the requester approves or rejects it,
+// or until it expires. No money moves until it is approved.
+func (store *transactions) CreatePendingTransferTx(ctx context.Context, arg CreatePendingTransferParams) (PendingTransfer, error) {
+	var pending PendingTransfer
+
+	if arg.Amount <= 0 {
+		return pending, apperr.New(apperr.Validation, "transfer amount must be positive")
+	}
+	if !arg.ExpiresAt.After(time.Now()) {
+		return pending, apperr.New(apperr.Validation, "pending transfer must expire in the future")
+	}
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		pending, err = q.CreatePendingTransfer(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditTransferPending, AuditSubject("pending_transfer", pending.ID), nil, pending)
+		return err
+	})
+	return pending, err
+}
+
+// DecidePendingTransferTxParams contains the input parameters of the approve and reject pending transfer transactions.
+type DecidePendingTransferTxParams struct {
+	ID int64 `json:"id"`
+	// DecidedBy is the username of the banker approving or rejecting the transfer.
+	DecidedBy string `json:"decided_by"`
+}
+
+// ApprovePendingTransferTxResult is the output of the approve pending transfer transaction.
+type ApprovePendingTransferTxResult struct {
+	PendingTransfer PendingTransfer  `json:"pending_transfer"`
+	Transfer        TransferTxResult `json:"transfer"`
+}
+
+// ApprovePendingTransferTx makes a pending transfer, with the same checks as TransferTx,
+// and marks it approved within a single database transaction.
+// The transfer is refused with ErrPendingTransferExpired once it expired,
+// and with ErrSelfApproval if arg.DecidedBy requested it.
+func (store *transactions) ApprovePendingTransferTx(ctx context.Context, arg DecidePendingTransferTxParams) (ApprovePendingTransferTxResult, error) {
+	var result ApprovePendingTransferTxResult
+	err := store.execTx(ctx, func(q Querier) error {
+		pending, err := lockPendingTransfer(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		if !pending.ExpiresAt.After(time.Now()) {
+			return apperr.Wrapf(ErrPendingTransferExpired, apperr.FailedPrecondition, "pending transfer %d: %s", pending.ID, ErrPendingTransferExpired)
+		}
+
+		account, err := q.GetAccount(ctx, pending.FromAccountID)
+		if err != nil {
+			return err
+		}
+
+		amount, err := money.New(pending.Amount, account.Currency)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = store.transfer(ctx, q, TransferTxParams{
+			FromAccountID: pending.FromAccountID,
+			ToAccountID:   pending.ToAccountID,
+			Amount:        amount,
+		}, 0)
+		if err != nil {
+			return err
+		}
+
+		result.PendingTransfer, err = q.DecidePendingTransfer(ctx, DecidePendingTransferParams{
+			ID:         pending.ID,
+			Status:     PendingTransferStatusApproved,
+			DecidedBy:  arg.DecidedBy,
+			TransferID: pgtype.Int8{Int64: result.Transfer.Transfer.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditTransferApproved, AuditSubject("pending_transfer", pending.ID), pending, result.PendingTransfer)
+		return err
+	})
+	return result, err
+}
+
+// RejectPendingTransferTx marks a pending transfer rejected, so it is never made.
+// As for ApprovePendingTransferTx, the requester cannot reject it.
+func (store *transactions) RejectPendingTransferTx(ctx context.Context, arg DecidePendingTransferTxParams) (PendingTransfer, error) {
+	var rejected PendingTransfer
+	err := store.execTx(ctx, func(q Querier) error {
+		pending, err := lockPendingTransfer(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		rejected, err = q.DecidePendingTransfer(ctx, DecidePendingTransferParams{
+			ID:        pending.ID,
+			Status:    PendingTransferStatusRejected,
+			DecidedBy: arg.DecidedBy,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditTransferRejected, AuditSubject("pending_transfer", pending.ID), pending, rejected)
+		return err
+	})
+	return rejected, err
+}
+
+// ExpirePendingTransfersTx marks expired up to limit pending transfers past their expiry, and returns how many.
+// The transfers locked by a concurrent decision are skipped, to be expired on the next call if still pending.
+func (store *transactions) ExpirePendingTransfersTx(ctx context.Context, limit int32) (int, error) {
+	var expired int
+	err := store.execTx(ctx, func(q Querier) error {
+		expired = 0
+
+		pendingTransfers, err := q.ListExpiredPendingTransfersForUpdate(ctx, ListExpiredPendingTransfersForUpdateParams{
+			Now:   time.Now(),
+			Limit: limit,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, pending := range pendingTransfers {
+			decided, err := q.DecidePendingTransfer(ctx, DecidePendingTransferParams{
+				ID:        pending.ID,
+				Status:    PendingTransferStatusExpired,
+				DecidedBy: SystemActor,
+			})
+			if err != nil {
+				return err
+			}
+
+			_, err = recordAuditEvent(ctx, q, AuditTransferExpired, AuditSubject("pending_transfer", pending.ID), pending, decided)
+			if err != nil {
+				return err
+			}
+			expired++
+		}
+		return nil
+	})
+	return expired, err
+}
+
+// lockPendingTransfer locks a pending transfer arg.DecidedBy is about to decide on.
+func lockPendingTransfer(ctx context.Context, q Querier, arg DecidePendingTransferTxParams) (PendingTransfer, error) {
+	pending, err := q.GetPendingTransferForUpdate(ctx, arg.ID)
+	if err != nil {
+		return pending, err
+	}
+
+	if pending.Status != PendingTransferStatusPending {
+		return pending, apperr.Wrapf(ErrTransferNotPending, apperr.FailedPrecondition, "pending transfer %d is %s: %s", pending.ID, pending.Status, ErrTransferNotPending)
+	}
+
+	// maker-checker: a second person has to decide on the transfer
+	if arg.DecidedBy == pending.RequestedBy {
+		return pending, ErrSelfApproval
+	}
+	return pending, nil
+}
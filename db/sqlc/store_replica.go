@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WithReplica makes the SQLStore serve the read-only queries listed below from a read replica.
+// Transactions, row locks and every other query keep running on the primary. Users are read
+// from the primary too, as logins and the verify email task read them right after signup.
+// The in-memory store has no replica and ignores the option.
+func WithReplica(replica *pgxpool.Pool) StoreOption {
+	return func(store *storeOptions) {
+		store.replica = replica
+	}
+}
+
+type primaryReadsKey struct{}
+
+// WithPrimaryReads returns a context whose reads are served by the primary,
+// for requests that must see writes the replica may not have replayed yet.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// PrimaryReads reports whether the reads of ctx are served by the primary.
+func PrimaryReads(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryReadsKey{}).(bool)
+	return primary
+}
+
+// reader returns the queries serving the read-only queries of ctx.
+func (store *SQLStore) reader(ctx context.Context) *Queries {
+	if store.replica == nil || PrimaryReads(ctx) {
+		return store.Queries
+	}
+	return store.replica
+}
+
+func (store *SQLStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	return store.reader(ctx).GetAccount(ctx, id)
+}
+
+func (store *SQLStore) GetAccountBalance(ctx context.Context, id int64) (GetAccountBalanceRow, error) {
+	return store.reader(ctx).GetAccountBalance(ctx, id)
+}
+
+func (store *SQLStore) GetAccountLimit(ctx context.Context, accountID int64) (AccountLimit, error) {
+	return store.reader(ctx).GetAccountLimit(ctx, accountID)
+}
+
+func (store *SQLStore) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	return store.reader(ctx).GetEntry(ctx, id)
+}
+
+func (store *SQLStore) GetHold(ctx context.Context, id int64) (Hold, error) {
+	return store.reader(ctx).GetHold(ctx, id)
+}
+
+func (store *SQLStore) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	return store.reader(ctx).GetTransfer(ctx, id)
+}
+
+func (store *SQLStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	return store.reader(ctx).ListAccounts(ctx, arg)
+}
+
+func (store *SQLStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	return store.reader(ctx).ListEntries(ctx, arg)
+}
+
+func (store *SQLStore) ListHolds(ctx context.Context, arg ListHoldsParams) ([]Hold, error) {
+	return store.reader(ctx).ListHolds(ctx, arg)
+}
+
+func (store *SQLStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	return store.reader(ctx).ListTransfers(ctx, arg)
+}
+
+func (store *SQLStore) ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscription, error) {
+	return store.reader(ctx).ListWebhookSubscriptions(ctx, owner)
+}
+
+func (store *SQLStore) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	return store.reader(ctx).ListWebhookDeliveries(ctx, arg)
+}
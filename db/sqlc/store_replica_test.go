@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStore(testPool).(*SQLStore)
+	require.Same(t, store.Queries, store.reader(ctx))
+
+	store = NewStore(testPool, WithReplica(testPool)).(*SQLStore)
+	require.NotNil(t, store.replica)
+	require.Same(t, store.replica, store.reader(ctx))
+	require.Same(t, store.Queries, store.reader(WithPrimaryReads(ctx)))
+}
+
+func TestReplicaReads(t *testing.T) {
+	// the test database stands in for its own replica
+	store := NewStore(testPool, WithReplica(testPool))
+	account := createRandomAccount(t)
+
+	got, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, got.ID)
+
+	got, err = store.GetAccount(WithPrimaryReads(context.Background()), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, got.ID)
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/risk"
+	"github.com/jackc/pgx/v5"
+)
+
+// riskSignalsTxOptions reads the signals of a transfer from a single snapshot.
+var riskSignalsTxOptions = pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+
+// GetTransferRiskSignals looks up the history the risk rules score a transfer with.
+// It implements risk.History.
+func (store *transactions) GetTransferRiskSignals(ctx context.Context, transfer risk.Transfer, since time.Time) (risk.Signals, error) {
+	var signals risk.Signals
+	err := store.execTxWithOptions(ctx, riskSignalsTxOptions, func(q Querier) error {
+		var err error
+		signals = risk.Signals{}
+
+		signals.PayeeTransfers, err = q.CountTransfersBetween(ctx, CountTransfersBetweenParams{
+			FromAccountID: transfer.FromAccountID,
+			ToAccountID:   transfer.ToAccountID,
+		})
+		if err != nil {
+			return err
+		}
+
+		signals.RecentTransfers, err = q.CountTransfersSince(ctx, CountTransfersSinceParams{
+			FromAccountID: transfer.FromAccountID,
+			Since:         since,
+		})
+		if err != nil {
+			return err
+		}
+
+		session, err := q.GetLatestSession(ctx, transfer.Username)
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		signals.SessionCreatedAt = session.CreatedAt
+
+		// the last login is made from the client IP most of the time, so only the earlier ones tell it is known
+		logins, err := q.CountEarlierSessions(ctx, CountEarlierSessionsParams{
+			Username: transfer.Username,
+			ClientIp: transfer.ClientIP,
+			Before:   session.CreatedAt,
+		})
+		signals.EarlierLogins = logins.Logins
+		signals.KnownClientIP = logins.LoginsFromClientIp > 0
+		return err
+	})
+	return signals, err
+}
@@ -2,18 +2,29 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/MElghrbawy/simple_bank/fee"
+	"github.com/MElghrbawy/simple_bank/limit"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/MElghrbawy/simple_bank/webhook"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTransferTx(t *testing.T) {
-	store := NewStore(testDB)
+	store := NewStore(testPool)
 
 	// create two accounts
 	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+	account1 = fundAccount(t, account1, 1000)
 
 	fmt.Println(">> before:", account1.Balance, account2.Balance)
 
@@ -28,7 +39,7 @@ func TestTransferTx(t *testing.T) {
 			result, err := store.TransferTx(context.Background(), TransferTxParams{
 				FromAccountID: account1.ID,
 				ToAccountID:   account2.ID,
-				Amount:        amount,
+				Amount:        money.MustNew(amount, account1.Currency),
 			})
 			errs <- err
 			results <- result
@@ -116,3 +127,441 @@ func TestTransferTx(t *testing.T) {
 	require.Equal(t, account2.Balance+int64(n)*amount, updatedAccount2.Balance)
 
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+	account1 = fundAccount(t, account1, 100)
+
+	_, err := store.PlaceHoldTx(context.Background(), PlaceHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      70,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// the balance covers the transfer, but not once the held funds are set aside
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(40, account1.Currency),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(30, account1.Currency),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(70), result.FromAccount.Balance)
+}
+
+func TestTransferTxWithFee(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+	account1 = fundAccount(t, account1, 1000)
+	amount := int64(100)
+
+	revenueAccount, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: account1.Currency,
+	})
+	require.NoError(t, err)
+	schedule := &fee.Schedule{
+		Default: fee.Rule{
+			Flat:                     2,
+			BasisPoints:              100,
+			CrossCurrencyBasisPoints: 50,
+			RevenueAccountID:         revenueAccount.ID,
+		},
+	}
+	store := NewStore(testPool, WithFeeSchedule(schedule))
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(amount, account1.Currency),
+	})
+	require.NoError(t, err)
+
+	expectedFee := schedule.Calculate(amount, account1.Currency, account2.Currency)
+	require.Equal(t, expectedFee, result.Fee)
+	require.Positive(t, result.Fee.Total)
+
+	require.NotNil(t, result.FeeEntry)
+	require.Equal(t, account1.ID, result.FeeEntry.AccountID)
+	require.Equal(t, -expectedFee.Total, result.FeeEntry.Amount)
+
+	require.NotNil(t, result.RevenueEntry)
+	require.Equal(t, revenueAccount.ID, result.RevenueEntry.AccountID)
+	require.Equal(t, expectedFee.Total, result.RevenueEntry.Amount)
+
+	require.Equal(t, account1.Balance-amount-expectedFee.Total, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+amount, result.ToAccount.Balance)
+
+	updatedRevenueAccount, err := testQueries.GetAccount(context.Background(), revenueAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, revenueAccount.Balance+expectedFee.Total, updatedRevenueAccount.Balance)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+	account1 = fundAccount(t, account1, 1000)
+
+	store := NewStore(testPool, WithTransferLimits(limit.Defaults{
+		account1.Currency: {PerTransfer: 50, Daily: 100},
+	}))
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(60, account1.Currency),
+	}
+	_, err := store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, limit.ErrExceeded)
+
+	arg.Amount = money.MustNew(50, account1.Currency)
+	for i := 0; i < 2; i++ {
+		_, err = store.TransferTx(context.Background(), arg)
+		require.NoError(t, err)
+	}
+
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, limit.ErrExceeded)
+
+	// an account override takes precedence over the currency default
+	_, err = testQueries.UpsertAccountLimit(context.Background(), UpsertAccountLimitParams{
+		AccountID: account1.ID,
+		Daily:     pgtype.Int8{Int64: 200, Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	allowance, err := store.GetTransferAllowance(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(50), allowance.Limits.PerTransfer)
+	require.Equal(t, int64(150), allowance.Usage.Daily)
+	require.NotNil(t, allowance.DailyRemaining)
+	require.Equal(t, int64(50), *allowance.DailyRemaining)
+	require.Nil(t, allowance.MonthlyRemaining)
+}
+
+func TestTransferTxInactiveAccount(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+
+	_, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account2.ID,
+		Status: AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(10, account1.Currency),
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// the transfer is rolled back
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func TestTransferTxSerializable(t *testing.T) {
+	var attempts atomic.Int64
+	store := NewStore(testPool,
+		WithTxIsolation(pgx.Serializable),
+		WithTxRetryPolicy(TxRetryPolicy{MaxAttempts: 20, BaseDelay: 5 * time.Millisecond, MaxDelay: 50 * time.Millisecond}),
+		WithTxObserver(func(stats TxStats) {
+			attempts.Add(int64(stats.Attempts))
+		}),
+	)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+	account1 = fundAccount(t, account1, 1000)
+
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        money.MustNew(amount, account1.Currency),
+			})
+			errs <- err
+		}()
+	}
+
+	// serialization failures are retried instead of failing the transfers
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+	require.GreaterOrEqual(t, attempts.Load(), int64(n))
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-int64(n)*amount, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance+int64(n)*amount, updatedAccount2.Balance)
+}
+
+// fundAccount sets the balance of account, so the transfers of a test do not depend on its random balance.
+func fundAccount(t *testing.T, account Account, balance int64) Account {
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: balance})
+	require.NoError(t, err)
+	return account
+}
+
+func TestHoldTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+
+	account1, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account1.ID, Balance: 100})
+	require.NoError(t, err)
+
+	placed, err := store.PlaceHoldTx(context.Background(), PlaceHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      70,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), placed.AvailableBalance)
+
+	// the hold reserves the funds without posting entries
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+
+	_, err = store.PlaceHoldTx(context.Background(), PlaceHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      40,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: placed.Hold.ID, Amount: 80})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	captured, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: placed.Hold.ID, Amount: 50})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusCaptured, captured.Hold.Status)
+	require.Equal(t, int64(50), captured.Hold.CapturedAmount)
+	require.Equal(t, int64(50), captured.Transfer.Transfer.Amount)
+	require.Equal(t, int64(50), captured.Transfer.FromAccount.Balance)
+	require.Equal(t, account2.Balance+50, captured.Transfer.ToAccount.Balance)
+
+	// the uncaptured part is available again
+	balance, err := testQueries.GetAccountBalance(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(50), balance.AvailableBalance)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: placed.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotPending)
+}
+
+func TestReleaseHoldTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+	hold := createRandomHold(t, account1, account2)
+
+	released, err := store.ReleaseHoldTx(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusReleased, released.Status)
+	require.Zero(t, released.CapturedAmount)
+
+	balance, err := testQueries.GetAccountBalance(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, balance.AvailableBalance)
+
+	_, err = store.ReleaseHoldTx(context.Background(), hold.ID)
+	require.ErrorIs(t, err, ErrHoldNotPending)
+
+	expired, err := testQueries.CreateHold(context.Background(), CreateHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      1,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: expired.ID})
+	require.ErrorIs(t, err, ErrHoldExpired)
+}
+
+func TestTransferTxCurrencyMismatch(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(10, "JPY"),
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestListAccountEventsAfter(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+	account1 = fundAccount(t, account1, 1000)
+
+	var results []TransferTxResult
+	for i := 0; i < 3; i++ {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        money.MustNew(10, account1.Currency),
+		})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+
+	events, err := store.ListAccountEventsAfter(context.Background(), ListAccountEventsAfterParams{
+		AccountID: account1.ID,
+		AfterSeq:  results[0].FromEntry.Seq,
+		MaxEvents: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	for i, ev := range events {
+		result := results[i+1]
+		require.Equal(t, result.FromEntry.ID, ev.ID)
+		require.Equal(t, result.FromEntry.Seq, ev.Seq)
+		require.Equal(t, result.FromEntry.Amount, ev.Amount)
+		require.Equal(t, result.FromAccount.Balance, ev.Balance)
+	}
+}
+
+func TestDispatchWebhookEventsTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountIn(t, account1.Currency)
+	account1 = fundAccount(t, account1, 1000)
+	subscription := createRandomWebhookSubscription(t, account2.Owner, webhook.EventTransferReceived)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(10, account1.Currency),
+	})
+	require.NoError(t, err)
+
+	// other tests leave pending events behind, so drain them all
+	var dispatched []WebhookEvent
+	for {
+		n, err := store.DispatchWebhookEventsTx(context.Background(), DispatchWebhookEventsTxParams{
+			Limit: 100,
+			Dispatch: func(event WebhookEvent, subscriptions []WebhookSubscription) error {
+				for _, s := range subscriptions {
+					if s.ID == subscription.ID {
+						dispatched = append(dispatched, event)
+					}
+				}
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+
+	require.Len(t, dispatched, 1)
+	require.Equal(t, webhook.EventTransferReceived, dispatched[0].EventType)
+
+	var data webhook.TransferData
+	err = json.Unmarshal(dispatched[0].Payload, &data)
+	require.NoError(t, err)
+	require.Equal(t, result.Transfer.ID, data.TransferID)
+	require.Equal(t, int64(10), data.Amount.Units)
+
+	event, err := store.GetWebhookEvent(context.Background(), dispatched[0].ID)
+	require.NoError(t, err)
+	require.True(t, event.DispatchedAt.Valid)
+}
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testPool)
+	existing := createRandomUser(t)
+
+	task := CreateOutboxTaskParams{
+		TaskType: "task:test_" + util.RandomString(6),
+		Payload:  []byte(`{}`),
+		Queue:    "default",
+		MaxRetry: 3,
+	}
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: existing.HashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		Tasks: []CreateOutboxTaskParams{task},
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, result.User.Username)
+
+	// a duplicate user rolls its tasks back
+	duplicate := arg
+	duplicate.Username = existing.Username
+	duplicate.Email = util.RandomEmail()
+	_, err = store.CreateUserTx(context.Background(), duplicate)
+	require.Error(t, err)
+
+	var dispatched []TaskOutbox
+	for {
+		n, err := store.DispatchOutboxTasks(context.Background(), DispatchOutboxTasksParams{
+			Limit: 100,
+			Dispatch: func(outboxTask TaskOutbox) error {
+				if outboxTask.TaskType == task.TaskType {
+					dispatched = append(dispatched, outboxTask)
+				}
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+
+	require.Len(t, dispatched, 1)
+	require.Equal(t, task.MaxRetry, dispatched[0].MaxRetry)
+
+	outboxTask, err := store.GetOutboxTask(context.Background(), dispatched[0].ID)
+	require.NoError(t, err)
+	require.True(t, outboxTask.DispatchedAt.Valid)
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/jackc/pgx/v5"
+)
+
+// TxRetryPolicy bounds how transactions failing with a serialization failure or a deadlock are run again.
+type TxRetryPolicy struct {
+	// MaxAttempts is the number of times a transaction runs at most, the first attempt included.
+	MaxAttempts int
+	// BaseDelay is the longest wait before the second attempt. It doubles with each attempt up to MaxDelay,
+	// and the actual wait is picked at random below it so conflicting transactions do not retry in lockstep.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultTxRetryPolicy = TxRetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    500 * time.Millisecond,
+}
+
+// TxStats describes a finished transaction, for metrics.
+type TxStats struct {
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+// TransferStats describes a finished TransferTx, for metrics.
+type TransferStats struct {
+	Amount   money.Amount
+	Duration time.Duration
+	Err      error
+}
+
+// WithTxIsolation sets the isolation level of the transactions that do not ask for their own.
+func WithTxIsolation(level pgx.TxIsoLevel) StoreOption {
+	return func(store *storeOptions) {
+		store.txOptions = pgx.TxOptions{IsoLevel: level}
+	}
+}
+
+// WithTxRetryPolicy replaces DefaultTxRetryPolicy.
+func WithTxRetryPolicy(policy TxRetryPolicy) StoreOption {
+	return func(store *storeOptions) {
+		store.txRetry = policy
+	}
+}
+
+// WithTxObserver makes the store call observe once every transaction is over.
+func WithTxObserver(observe func(TxStats)) StoreOption {
+	return func(store *storeOptions) {
+		store.observeTx = observe
+	}
+}
+
+// WithTransferObserver makes the store call observe once every TransferTx is over.
+func WithTransferObserver(observe func(TransferStats)) StoreOption {
+	return func(store *storeOptions) {
+		store.observeTransfer = observe
+	}
+}
+
+// execTx runs fn in a transaction with the default options of the store.
+func (store *transactions) execTx(ctx context.Context, fn func(q Querier) error) error {
+	return store.execTxWithOptions(ctx, store.txOptions, fn)
+}
+
+// execTxWithOptions runs fn in a transaction, and runs it again while it fails with a retryable error,
+// as long as the retry policy and the context deadline leave room for another attempt.
+// fn must be safe to run several times: everything it writes outside the transaction is kept.
+func (store *transactions) execTxWithOptions(ctx context.Context, opts pgx.TxOptions, fn func(q Querier) error) error {
+	start := time.Now()
+
+	attempts := 0
+	var err error
+	for {
+		attempts++
+		err = store.runTx(ctx, opts, fn)
+		if err == nil || !IsRetryableTxError(err) || attempts >= store.txRetry.MaxAttempts {
+			break
+		}
+		if !sleepBeforeRetry(ctx, store.txRetry.delay(attempts)) {
+			break
+		}
+	}
+
+	if store.observeTx != nil {
+		store.observeTx(TxStats{
+			Attempts: attempts,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+	return err
+}
+
+// delay returns the wait before the attempt following the given one.
+func (policy TxRetryPolicy) delay(attempt int) time.Duration {
+	delay := policy.MaxDelay
+	if attempt <= 20 {
+		delay = min(policy.BaseDelay<<(attempt-1), policy.MaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// sleepBeforeRetry waits for delay, and reports false without waiting
+// if the context would be done before the next attempt could start.
+func sleepBeforeRetry(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// IsRetryableTxError reports whether err aborted a transaction that may succeed if run again:
+// a serialization failure (SQLSTATE 40001) or a deadlock (40P01).
+func IsRetryableTxError(err error) bool {
+	switch ErrorCode(err) {
+	case SerializationFailure, DeadlockDetected:
+		return true
+	}
+	return false
+}
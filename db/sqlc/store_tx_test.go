@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/fee"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// newFlakyTransactions returns transactions whose runTx fails with the given errors before succeeding.
+func newFlakyTransactions(errs []error, opts ...StoreOption) (*transactions, *[]pgx.TxOptions) {
+	var calls []pgx.TxOptions
+	runTx := func(ctx context.Context, opts pgx.TxOptions, fn func(q Querier) error) error {
+		calls = append(calls, opts)
+		if len(calls) <= len(errs) {
+			return errs[len(calls)-1]
+		}
+		return fn(nil)
+	}
+	opts = append([]StoreOption{WithTxRetryPolicy(TxRetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})}, opts...)
+	store := newStoreOptions(opts).transactions
+	store.runTx = runTx
+	return &store, &calls
+}
+
+func TestExecTxRetry(t *testing.T) {
+	serializationFailure := &pgconn.PgError{Code: SerializationFailure}
+	deadlock := &pgconn.PgError{Code: DeadlockDetected}
+	uniqueViolation := &pgconn.PgError{Code: UniqueViolation}
+
+	testCases := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{
+			name:         "OK",
+			wantAttempts: 1,
+		},
+		{
+			name:         "RetriedSerializationFailure",
+			errs:         []error{serializationFailure},
+			wantAttempts: 2,
+		},
+		{
+			name:         "RetriedDeadlock",
+			errs:         []error{deadlock, serializationFailure},
+			wantAttempts: 3,
+		},
+		{
+			name:         "AttemptsExhausted",
+			errs:         []error{deadlock, deadlock, deadlock},
+			wantAttempts: 3,
+			wantErr:      deadlock,
+		},
+		{
+			name:         "NotRetryable",
+			errs:         []error{uniqueViolation},
+			wantAttempts: 1,
+			wantErr:      uniqueViolation,
+		},
+		{
+			name:         "Rollback",
+			errs:         []error{fmt.Errorf("tx error: %w, rb error: %v", serializationFailure, pgx.ErrTxClosed)},
+			wantAttempts: 2,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			var stats []TxStats
+			store, calls := newFlakyTransactions(tc.errs, WithTxObserver(func(s TxStats) {
+				stats = append(stats, s)
+			}))
+
+			err := store.execTx(context.Background(), func(q Querier) error { return nil })
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Len(t, *calls, tc.wantAttempts)
+
+			require.Len(t, stats, 1)
+			require.Equal(t, tc.wantAttempts, stats[0].Attempts)
+			require.Equal(t, err, stats[0].Err)
+		})
+	}
+}
+
+func TestExecTxRetryDeadline(t *testing.T) {
+	store, calls := newFlakyTransactions([]error{&pgconn.PgError{Code: SerializationFailure}}, WithTxRetryPolicy(TxRetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	err := store.execTx(ctx, func(q Querier) error { return nil })
+	require.True(t, IsRetryableTxError(err))
+	require.Len(t, *calls, 1)
+}
+
+func TestExecTxOptions(t *testing.T) {
+	store, calls := newFlakyTransactions(nil, WithTxIsolation(pgx.Serializable))
+
+	err := store.execTx(context.Background(), func(q Querier) error { return nil })
+	require.NoError(t, err)
+	err = store.execTxWithOptions(context.Background(), allowanceTxOptions, func(q Querier) error { return nil })
+	require.NoError(t, err)
+
+	require.Len(t, *calls, 2)
+	require.Equal(t, pgx.Serializable, (*calls)[0].IsoLevel)
+	require.Equal(t, allowanceTxOptions, (*calls)[1])
+}
+
+func TestMemStoreTransferObserver(t *testing.T) {
+	var observed []TransferStats
+	store := NewMemStore(WithTransferObserver(func(stats TransferStats) {
+		observed = append(observed, stats)
+	}))
+
+	account1 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+
+	amounts := []money.Amount{money.MustNew(60, util.USD), money.MustNew(60, util.EUR)}
+	for _, amount := range amounts {
+		store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+		})
+	}
+
+	require.Len(t, observed, 2)
+	require.Equal(t, amounts[0], observed[0].Amount)
+	require.NoError(t, observed[0].Err)
+	require.Equal(t, amounts[1], observed[1].Amount)
+	require.ErrorIs(t, observed[1].Err, ErrCurrencyMismatch)
+}
+
+func TestMemStoreTransferFeeRevenueCurrency(t *testing.T) {
+	schedule := &fee.Schedule{Default: fee.Rule{Flat: 2}}
+	store := NewMemStore(WithFeeSchedule(schedule))
+
+	revenueAccount := conformanceAccount(t, store, conformanceUser(t, store).Username, util.EUR, 0)
+	schedule.Default.RevenueAccountID = revenueAccount.ID
+	account1 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(10, util.USD),
+	})
+	require.ErrorIs(t, err, ErrRevenueCurrencyMismatch)
+
+	got, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), got.Balance)
+	got, err = store.GetAccount(context.Background(), revenueAccount.ID)
+	require.NoError(t, err)
+	require.Zero(t, got.Balance)
+}
+
+func TestMemStoreTransferFeeRevenueFrozen(t *testing.T) {
+	schedule := &fee.Schedule{Default: fee.Rule{Flat: 2}}
+	store := NewMemStore(WithFeeSchedule(schedule))
+
+	revenueAccount := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+	schedule.Default.RevenueAccountID = revenueAccount.ID
+	_, err := store.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     revenueAccount.ID,
+		Status: AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+	account1 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 0)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(10, util.USD),
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	got, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), got.Balance)
+}
+
+func TestMemStoreTransferToCurrencyMismatch(t *testing.T) {
+	store := NewMemStore()
+
+	account1 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.USD, 100)
+	account2 := conformanceAccount(t, store, conformanceUser(t, store).Username, util.EUR, 0)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.MustNew(10, util.USD),
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	got, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Zero(t, got.Balance)
+}
+
+func TestOutboxTaskFailure(t *testing.T) {
+	err := errors.New("cannot enqueue")
+
+	arg := outboxTaskFailure(TaskOutbox{ID: 1, Attempts: 1}, err)
+	require.Equal(t, int64(1), arg.ID)
+	require.Equal(t, "cannot enqueue", arg.LastError)
+	require.WithinDuration(t, time.Now().Add(outboxRetryDelay), arg.NextAttemptAt, time.Second)
+	require.False(t, arg.FailedAt.Valid)
+
+	// the last attempt sets the task aside
+	arg = outboxTaskFailure(TaskOutbox{ID: 1, Attempts: outboxMaxAttempts}, err)
+	require.True(t, arg.FailedAt.Valid)
+}
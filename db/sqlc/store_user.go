@@ -0,0 +1,43 @@
+package db
+
+import "context"
+
+// UpdateUserTx updates a user and records the change in the audit log.
+// A password change shows as a new password_changed_at, the password hash is never recorded.
+func (store *transactions) UpdateUserTx(ctx context.Context, arg UpdateUserParams) (User, error) {
+	var user User
+	err := store.execTx(ctx, func(q Querier) error {
+		before, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.UpdateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditUserUpdated, AuditSubject("user", user.Username), newAuditUser(before), newAuditUser(user))
+		return err
+	})
+	return user, err
+}
+
+// CreateSessionTx opens the session of a user who logged in, and records the login in the audit log.
+func (store *transactions) CreateSessionTx(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	var session Session
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		session, err = q.CreateSession(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditUserLoggedIn, AuditSubject("user", session.Username), nil, auditSession{
+			SessionID: session.ID.String(),
+			ExpiresAt: session.ExpiresAt,
+		})
+		return err
+	})
+	return session, err
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/webhook"
+)
+
+// DispatchWebhookEventsTxParams contains the input parameters of the webhook dispatch transaction
+type DispatchWebhookEventsTxParams struct {
+	Limit int32
+	// Dispatch is called for every pending event with the subscriptions listening to it.
+	// Returning an error rolls the transaction back and leaves the events pending.
+	Dispatch func(event WebhookEvent, subscriptions []WebhookSubscription) error
+}
+
+// DispatchWebhookEventsTx hands pending webhook events over to Dispatch and marks them dispatched.
+// Events locked by a concurrent dispatcher are skipped. It returns the number of dispatched events.
+func (store *transactions) DispatchWebhookEventsTx(ctx context.Context, arg DispatchWebhookEventsTxParams) (int, error) {
+	var dispatched int
+	err := store.execTx(ctx, func(q Querier) error {
+		dispatched = 0
+
+		events, err := q.ListPendingWebhookEvents(ctx, arg.Limit)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			subscriptions, err := q.ListWebhookSubscriptionsForEvent(ctx, ListWebhookSubscriptionsForEventParams{
+				Owner:     event.Owner,
+				EventType: event.EventType,
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(subscriptions) > 0 {
+				if err := arg.Dispatch(event, subscriptions); err != nil {
+					return err
+				}
+			}
+
+			if err := q.MarkWebhookEventDispatched(ctx, event.ID); err != nil {
+				return err
+			}
+			dispatched++
+		}
+		return nil
+	})
+	return dispatched, err
+}
+
+// recordTransferWebhookEvents records the webhook events of a transfer for the owners of both accounts.
+func recordTransferWebhookEvents(ctx context.Context, q Querier, result TransferTxResult, currency money.Currency) error {
+	data := webhook.TransferData{
+		TransferID:    result.Transfer.ID,
+		FromAccountID: result.Transfer.FromAccountID,
+		ToAccountID:   result.Transfer.ToAccountID,
+		Amount:        money.Amount{Units: result.Transfer.Amount, Currency: currency},
+		Fee:           money.Amount{Units: result.Fee.Total, Currency: currency},
+	}
+
+	err := recordWebhookEvent(ctx, q, result.FromAccount.Owner, webhook.EventTransferSent, data)
+	if err != nil {
+		return err
+	}
+	return recordWebhookEvent(ctx, q, result.ToAccount.Owner, webhook.EventTransferReceived, data)
+}
+
+func recordWebhookEvent(ctx context.Context, q Querier, owner string, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateWebhookEvent(ctx, CreateWebhookEventParams{
+		Owner:     owner,
+		EventType: eventType,
+		Payload:   payload,
+	})
+	return err
+}
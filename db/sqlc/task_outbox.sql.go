@@ -0,0 +1,171 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: task_outbox.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const claimOutboxTasks = `-- name: ClaimOutboxTasks :many
+UPDATE task_outbox
+SET attempts = attempts + 1,
+    next_attempt_at = $1
+WHERE id IN (
+  SELECT id FROM task_outbox
+  WHERE dispatched_at IS NULL
+    AND failed_at IS NULL
+    AND next_attempt_at <= now()
+  ORDER BY id
+  LIMIT $2
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, task_type, payload, queue, max_retry, dispatched_at, created_at, attempts, next_attempt_at, last_error, failed_at
+`
+
+type ClaimOutboxTasksParams struct {
+	ClaimedUntil time.Time `json:"claimed_until"`
+	MaxTasks     int32     `json:"max_tasks"`
+}
+
+// A claimed task is not claimed again before claimed_until, so the relay enqueues
+// the tasks outside of the transaction while the other relays skip them.
+func (q *Queries) ClaimOutboxTasks(ctx context.Context, arg ClaimOutboxTasksParams) ([]TaskOutbox, error) {
+	rows, err := q.db.Query(ctx, claimOutboxTasks, arg.ClaimedUntil, arg.MaxTasks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TaskOutbox{}
+	for rows.Next() {
+		var i TaskOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.TaskType,
+			&i.Payload,
+			&i.Queue,
+			&i.MaxRetry,
+			&i.DispatchedAt,
+			&i.CreatedAt,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.FailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOutboxTask = `-- name: CreateOutboxTask :one
+INSERT INTO task_outbox (
+  task_type,
+  payload,
+  queue,
+  max_retry
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING id, task_type, payload, queue, max_retry, dispatched_at, created_at, attempts, next_attempt_at, last_error, failed_at
+`
+
+type CreateOutboxTaskParams struct {
+	TaskType string          `json:"task_type"`
+	Payload  json.RawMessage `json:"payload"`
+	Queue    string          `json:"queue"`
+	MaxRetry int32           `json:"max_retry"`
+}
+
+func (q *Queries) CreateOutboxTask(ctx context.Context, arg CreateOutboxTaskParams) (TaskOutbox, error) {
+	row := q.db.QueryRow(ctx, createOutboxTask,
+		arg.TaskType,
+		arg.Payload,
+		arg.Queue,
+		arg.MaxRetry,
+	)
+	var i TaskOutbox
+	err := row.Scan(
+		&i.ID,
+		&i.TaskType,
+		&i.Payload,
+		&i.Queue,
+		&i.MaxRetry,
+		&i.DispatchedAt,
+		&i.CreatedAt,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.FailedAt,
+	)
+	return i, err
+}
+
+const getOutboxTask = `-- name: GetOutboxTask :one
+SELECT id, task_type, payload, queue, max_retry, dispatched_at, created_at, attempts, next_attempt_at, last_error, failed_at FROM task_outbox
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetOutboxTask(ctx context.Context, id int64) (TaskOutbox, error) {
+	row := q.db.QueryRow(ctx, getOutboxTask, id)
+	var i TaskOutbox
+	err := row.Scan(
+		&i.ID,
+		&i.TaskType,
+		&i.Payload,
+		&i.Queue,
+		&i.MaxRetry,
+		&i.DispatchedAt,
+		&i.CreatedAt,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.FailedAt,
+	)
+	return i, err
+}
+
+const markOutboxTaskDispatched = `-- name: MarkOutboxTaskDispatched :exec
+UPDATE task_outbox
+SET dispatched_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxTaskDispatched(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markOutboxTaskDispatched, id)
+	return err
+}
+
+const recordOutboxTaskFailure = `-- name: RecordOutboxTaskFailure :exec
+UPDATE task_outbox
+SET last_error = $1,
+    next_attempt_at = $2,
+    failed_at = $3
+WHERE id = $4
+`
+
+type RecordOutboxTaskFailureParams struct {
+	LastError     string             `json:"last_error"`
+	NextAttemptAt time.Time          `json:"next_attempt_at"`
+	FailedAt      pgtype.Timestamptz `json:"failed_at"`
+	ID            int64              `json:"id"`
+}
+
+func (q *Queries) RecordOutboxTaskFailure(ctx context.Context, arg RecordOutboxTaskFailureParams) error {
+	_, err := q.db.Exec(ctx, recordOutboxTaskFailure,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.FailedAt,
+		arg.ID,
+	)
+	return err
+}
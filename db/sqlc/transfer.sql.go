@@ -7,8 +7,45 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
+const countTransfersBetween = `-- name: CountTransfersBetween :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+AND to_account_id = $2
+`
+
+type CountTransfersBetweenParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+}
+
+func (q *Queries) CountTransfersBetween(ctx context.Context, arg CountTransfersBetweenParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countTransfersBetween, arg.FromAccountID, arg.ToAccountID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countTransfersSince = `-- name: CountTransfersSince :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+AND created_at >= $2
+`
+
+type CountTransfersSinceParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	Since         time.Time `json:"since"`
+}
+
+func (q *Queries) CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countTransfersSince, arg.FromAccountID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
@@ -26,7 +63,7 @@ type CreateTransferParams struct {
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -44,7 +81,7 @@ WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, getTransfer, id)
+	row := q.db.QueryRow(ctx, getTransfer, id)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -69,7 +106,7 @@ type ListTransfersParams struct {
 }
 
 func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
-	rows, err := q.db.QueryContext(ctx, listTransfers, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listTransfers, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -88,11 +125,26 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
+
+const sumTransfersSince = `-- name: SumTransfersSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM transfers
+WHERE from_account_id = $1
+AND created_at >= $2
+`
+
+type SumTransfersSinceParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	Since         time.Time `json:"since"`
+}
+
+func (q *Queries) SumTransfersSince(ctx context.Context, arg SumTransfersSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, sumTransfersSince, arg.FromAccountID, arg.Since)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
@@ -2,11 +2,11 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
 	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -66,7 +66,7 @@ func TestUpdateUserOnlyFullName(t *testing.T) {
 	newFullName := util.RandomOwner()
 	arg := UpdateUserParams{
 		Username: user.Username,
-		FullName: sql.NullString{String: newFullName, Valid: true},
+		FullName: pgtype.Text{String: newFullName, Valid: true},
 	}
 
 	updatedUser, err := testQueries.UpdateUser(context.Background(), arg)
@@ -0,0 +1,332 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: webhook.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+  subscription_id,
+  event_id,
+  attempt,
+  status_code,
+  error,
+  succeeded
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING id, subscription_id, event_id, attempt, status_code, error, succeeded, created_at
+`
+
+type CreateWebhookDeliveryParams struct {
+	SubscriptionID int64  `json:"subscription_id"`
+	EventID        int64  `json:"event_id"`
+	Attempt        int32  `json:"attempt"`
+	StatusCode     int32  `json:"status_code"`
+	Error          string `json:"error"`
+	Succeeded      bool   `json:"succeeded"`
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, createWebhookDelivery,
+		arg.SubscriptionID,
+		arg.EventID,
+		arg.Attempt,
+		arg.StatusCode,
+		arg.Error,
+		arg.Succeeded,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.EventID,
+		&i.Attempt,
+		&i.StatusCode,
+		&i.Error,
+		&i.Succeeded,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebhookEvent = `-- name: CreateWebhookEvent :one
+INSERT INTO webhook_events (
+  owner,
+  event_type,
+  payload
+) VALUES (
+  $1, $2, $3
+) RETURNING id, owner, event_type, payload, dispatched_at, created_at
+`
+
+type CreateWebhookEventParams struct {
+	Owner     string          `json:"owner"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (WebhookEvent, error) {
+	row := q.db.QueryRow(ctx, createWebhookEvent, arg.Owner, arg.EventType, arg.Payload)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.EventType,
+		&i.Payload,
+		&i.DispatchedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebhookSubscription = `-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+  owner,
+  url,
+  secret,
+  event_types
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING id, owner, url, secret, event_types, created_at
+`
+
+type CreateWebhookSubscriptionParams struct {
+	Owner      string   `json:"owner"`
+	Url        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (q *Queries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, createWebhookSubscription,
+		arg.Owner,
+		arg.Url,
+		arg.Secret,
+		arg.EventTypes,
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Url,
+		&i.Secret,
+		&i.EventTypes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteWebhookSubscription = `-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, deleteWebhookSubscription, id)
+	return err
+}
+
+const getWebhookEvent = `-- name: GetWebhookEvent :one
+SELECT id, owner, event_type, payload, dispatched_at, created_at FROM webhook_events
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetWebhookEvent(ctx context.Context, id int64) (WebhookEvent, error) {
+	row := q.db.QueryRow(ctx, getWebhookEvent, id)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.EventType,
+		&i.Payload,
+		&i.DispatchedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebhookSubscription = `-- name: GetWebhookSubscription :one
+SELECT id, owner, url, secret, event_types, created_at FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, getWebhookSubscription, id)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Url,
+		&i.Secret,
+		&i.EventTypes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listPendingWebhookEvents = `-- name: ListPendingWebhookEvents :many
+SELECT id, owner, event_type, payload, dispatched_at, created_at FROM webhook_events
+WHERE dispatched_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) ListPendingWebhookEvents(ctx context.Context, limit int32) ([]WebhookEvent, error) {
+	rows, err := q.db.Query(ctx, listPendingWebhookEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookEvent{}
+	for rows.Next() {
+		var i WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.EventType,
+			&i.Payload,
+			&i.DispatchedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT id, subscription_id, event_id, attempt, status_code, error, succeeded, created_at FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListWebhookDeliveriesParams struct {
+	SubscriptionID int64 `json:"subscription_id"`
+	Limit          int32 `json:"limit"`
+	Offset         int32 `json:"offset"`
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveries, arg.SubscriptionID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.EventID,
+			&i.Attempt,
+			&i.StatusCode,
+			&i.Error,
+			&i.Succeeded,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookSubscriptions = `-- name: ListWebhookSubscriptions :many
+SELECT id, owner, url, secret, event_types, created_at FROM webhook_subscriptions
+WHERE owner = $1
+ORDER BY id
+`
+
+func (q *Queries) ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscription, error) {
+	rows, err := q.db.Query(ctx, listWebhookSubscriptions, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookSubscription{}
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Url,
+			&i.Secret,
+			&i.EventTypes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookSubscriptionsForEvent = `-- name: ListWebhookSubscriptionsForEvent :many
+SELECT id, owner, url, secret, event_types, created_at FROM webhook_subscriptions
+WHERE owner = $1
+  AND $2::varchar = ANY(event_types)
+ORDER BY id
+`
+
+type ListWebhookSubscriptionsForEventParams struct {
+	Owner     string `json:"owner"`
+	EventType string `json:"event_type"`
+}
+
+func (q *Queries) ListWebhookSubscriptionsForEvent(ctx context.Context, arg ListWebhookSubscriptionsForEventParams) ([]WebhookSubscription, error) {
+	rows, err := q.db.Query(ctx, listWebhookSubscriptionsForEvent, arg.Owner, arg.EventType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookSubscription{}
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Url,
+			&i.Secret,
+			&i.EventTypes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markWebhookEventDispatched = `-- name: MarkWebhookEventDispatched :exec
+UPDATE webhook_events
+SET dispatched_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkWebhookEventDispatched(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markWebhookEventDispatched, id)
+	return err
+}
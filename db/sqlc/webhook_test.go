@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/MElghrbawy/simple_bank/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhookSubscription(t *testing.T, owner string, eventTypes ...string) WebhookSubscription {
+	arg := CreateWebhookSubscriptionParams{
+		Owner:      owner,
+		Url:        "https://example.com/" + util.RandomString(6),
+		Secret:     util.RandomString(32),
+		EventTypes: eventTypes,
+	}
+
+	subscription, err := testQueries.CreateWebhookSubscription(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, subscription.ID)
+
+	require.Equal(t, arg.Owner, subscription.Owner)
+	require.Equal(t, arg.Url, subscription.Url)
+	require.Equal(t, arg.Secret, subscription.Secret)
+	require.Equal(t, arg.EventTypes, subscription.EventTypes)
+
+	return subscription
+}
+
+func TestListWebhookSubscriptionsForEvent(t *testing.T) {
+	user := createRandomUser(t)
+	received := createRandomWebhookSubscription(t, user.Username, webhook.EventTransferReceived)
+	createRandomWebhookSubscription(t, user.Username, webhook.EventAccountStatusChanged)
+
+	subscriptions, err := testQueries.ListWebhookSubscriptionsForEvent(context.Background(), ListWebhookSubscriptionsForEventParams{
+		Owner:     user.Username,
+		EventType: webhook.EventTransferReceived,
+	})
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	require.Equal(t, received.ID, subscriptions[0].ID)
+}
+
+func TestDeleteWebhookSubscription(t *testing.T) {
+	user := createRandomUser(t)
+	subscription := createRandomWebhookSubscription(t, user.Username, webhook.EventTransferSent)
+
+	err := testQueries.DeleteWebhookSubscription(context.Background(), subscription.ID)
+	require.NoError(t, err)
+
+	subscriptions, err := testQueries.ListWebhookSubscriptions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, subscriptions)
+}
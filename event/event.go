@@ -0,0 +1,18 @@
+package event
+
+import "time"
+
+// AccountChannel is the Postgres notification channel account events are published on.
+const AccountChannel = "account_events"
+
+// AccountEvent reports a new entry on an account together with the account
+// balance right after the entry was applied. EventID is the seq of the entry,
+// its position on the account: the entries of an account commit in seq order,
+// so clients can resume a stream from the last event they received.
+type AccountEvent struct {
+	EventID   int64     `json:"event_id"`
+	AccountID int64     `json:"account_id"`
+	Amount    int64     `json:"amount"`
+	Balance   int64     `json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+}
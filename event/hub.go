@@ -0,0 +1,68 @@
+package event
+
+import "sync"
+
+// subscriberBuffer is the number of events a subscriber can fall behind
+// before the hub drops it.
+const subscriberBuffer = 64
+
+// Hub fans account events out to the subscribers of each account.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan AccountEvent]struct{}
+}
+
+// NewHub creates a new hub without subscribers.
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[int64]map[chan AccountEvent]struct{})}
+}
+
+// Subscribe returns a channel receiving the events of the account and a function
+// to stop the subscription. The channel is closed when the subscriber falls
+// too far behind, in which case it should resubscribe and resume from the
+// last event it received.
+func (hub *Hub) Subscribe(accountID int64) (<-chan AccountEvent, func()) {
+	ch := make(chan AccountEvent, subscriberBuffer)
+
+	hub.mu.Lock()
+	if hub.subscribers[accountID] == nil {
+		hub.subscribers[accountID] = make(map[chan AccountEvent]struct{})
+	}
+	hub.subscribers[accountID][ch] = struct{}{}
+	hub.mu.Unlock()
+
+	unsubscribe := func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		hub.remove(accountID, ch)
+	}
+	return ch, unsubscribe
+}
+
+// Publish delivers the event to every subscriber of its account without blocking.
+func (hub *Hub) Publish(event AccountEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for ch := range hub.subscribers[event.AccountID] {
+		select {
+		case ch <- event:
+		default:
+			hub.remove(event.AccountID, ch)
+		}
+	}
+}
+
+// remove closes the subscriber channel; hub.mu must be held.
+func (hub *Hub) remove(accountID int64, ch chan AccountEvent) {
+	subscribers := hub.subscribers[accountID]
+	if _, ok := subscribers[ch]; !ok {
+		return
+	}
+
+	delete(subscribers, ch)
+	close(ch)
+	if len(subscribers) == 0 {
+		delete(hub.subscribers, accountID)
+	}
+}
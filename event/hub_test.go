@@ -0,0 +1,66 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomEvent(accountID int64) AccountEvent {
+	return AccountEvent{
+		EventID:   util.RandomInt(1, 1000),
+		AccountID: accountID,
+		Amount:    util.RandomMoney(),
+		Balance:   util.RandomMoney(),
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+
+	events1, unsubscribe1 := hub.Subscribe(1)
+	defer unsubscribe1()
+	events2, unsubscribe2 := hub.Subscribe(2)
+	defer unsubscribe2()
+
+	event := randomEvent(1)
+	hub.Publish(event)
+
+	require.Equal(t, event, <-events1)
+	require.Empty(t, events2)
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub := NewHub()
+
+	events, unsubscribe := hub.Subscribe(1)
+	unsubscribe()
+	unsubscribe()
+
+	_, ok := <-events
+	require.False(t, ok)
+
+	hub.Publish(randomEvent(1))
+	require.Empty(t, hub.subscribers)
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+
+	events, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Publish(randomEvent(1))
+	}
+
+	for i := 0; i < subscriberBuffer; i++ {
+		_, ok := <-events
+		require.True(t, ok)
+	}
+	_, ok := <-events
+	require.False(t, ok)
+}
@@ -0,0 +1,77 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	reconnectInterval = 10 * time.Second
+	pingInterval      = 90 * time.Second
+)
+
+// Listen publishes the account events notified on AccountChannel until ctx is done.
+// It holds a connection of the pool out of it, and takes another one when the connection fails.
+// Events notified while no connection listens are lost; subscribers recover them
+// by resuming from their last event id.
+func (hub *Hub) Listen(ctx context.Context, connPool *pgxpool.Pool) error {
+	for {
+		err := hub.listen(ctx, connPool)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Error().Err(err).Msg("account event listener error")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectInterval):
+		}
+	}
+}
+
+// listen publishes the notified account events until the connection fails or ctx is done.
+func (hub *Hub) listen(ctx context.Context, connPool *pgxpool.Pool) error {
+	poolConn, err := connPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN belongs to the session, so the connection must not go back to the pool.
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{AccountChannel}.Sanitize())
+	if err != nil {
+		return err
+	}
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, pingInterval)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				// nothing was notified for a while, make sure the connection is still up
+				if err = conn.Ping(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		var event AccountEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Error().Err(err).Msg("cannot decode account event")
+			continue
+		}
+		hub.Publish(event)
+	}
+}
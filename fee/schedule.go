@@ -0,0 +1,173 @@
+package fee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// basisPoints is the denominator used for percentage fees (1 bp = 0.01%)
+const basisPoints = 10000
+
+var ErrNoRevenueAccount = errors.New("no revenue account configured for fee")
+
+// Tier charges a flat and a percentage fee for amounts up to UpTo (inclusive).
+// A tier with UpTo set to zero has no upper bound.
+type Tier struct {
+	UpTo        int64 `json:"up_to"`
+	Flat        int64 `json:"flat"`
+	BasisPoints int64 `json:"basis_points"`
+}
+
+// Rule describes the fees charged on transfers out of accounts in one currency.
+// When Tiers is not empty, the matching tier replaces Flat and BasisPoints.
+// The revenue account must hold the currency of the source account, so a Default rule with fees
+// only suits deployments with a single currency.
+type Rule struct {
+	Flat                     int64  `json:"flat"`
+	BasisPoints              int64  `json:"basis_points"`
+	Tiers                    []Tier `json:"tiers"`
+	CrossCurrencyBasisPoints int64  `json:"cross_currency_basis_points"`
+	RevenueAccountID         int64  `json:"revenue_account_id"`
+}
+
+// Schedule holds the fee rules keyed by the currency of the source account.
+// Currencies without a rule of their own fall back to Default.
+type Schedule struct {
+	Default    Rule            `json:"default"`
+	Currencies map[string]Rule `json:"currencies"`
+}
+
+// Breakdown is the fee charged for a single transfer.
+type Breakdown struct {
+	Flat             int64 `json:"flat"`
+	Percentage       int64 `json:"percentage"`
+	CrossCurrency    int64 `json:"cross_currency"`
+	Total            int64 `json:"total"`
+	RevenueAccountID int64 `json:"revenue_account_id"`
+}
+
+// LoadSchedule reads a fee schedule from a JSON file.
+func LoadSchedule(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fee schedule: %w", err)
+	}
+
+	schedule := &Schedule{}
+	if err := json.Unmarshal(data, schedule); err != nil {
+		return nil, fmt.Errorf("cannot parse fee schedule: %w", err)
+	}
+
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// Validate checks that every rule of the schedule is well formed.
+func (s *Schedule) Validate() error {
+	if err := s.Default.validate(); err != nil {
+		return fmt.Errorf("default rule: %w", err)
+	}
+
+	for currency, rule := range s.Currencies {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("%s rule: %w", currency, err)
+		}
+	}
+	return nil
+}
+
+// Rule returns the rule applied to transfers out of accounts in the currency.
+func (s *Schedule) Rule(currency string) Rule {
+	if rule, ok := s.Currencies[currency]; ok {
+		return rule
+	}
+	return s.Default
+}
+
+// Calculate returns the fee charged for transferring amount from an account in
+// fromCurrency to an account in toCurrency. A nil schedule charges nothing.
+func (s *Schedule) Calculate(amount int64, fromCurrency string, toCurrency string) Breakdown {
+	if s == nil {
+		return Breakdown{}
+	}
+
+	rule := s.Rule(fromCurrency)
+	flat, bps := rule.Flat, rule.BasisPoints
+	if tier, ok := rule.tier(amount); ok {
+		flat, bps = tier.Flat, tier.BasisPoints
+	}
+
+	breakdown := Breakdown{
+		Flat:             flat,
+		Percentage:       percentOf(amount, bps),
+		RevenueAccountID: rule.RevenueAccountID,
+	}
+
+	if fromCurrency != toCurrency {
+		breakdown.CrossCurrency = percentOf(amount, rule.CrossCurrencyBasisPoints)
+	}
+
+	breakdown.Total = breakdown.Flat + breakdown.Percentage + breakdown.CrossCurrency
+	return breakdown
+}
+
+// tier returns the first tier covering the amount, or the last tier when the
+// amount is above every bound.
+func (r Rule) tier(amount int64) (Tier, bool) {
+	if len(r.Tiers) == 0 {
+		return Tier{}, false
+	}
+
+	for _, tier := range r.Tiers {
+		if tier.UpTo == 0 || amount <= tier.UpTo {
+			return tier, true
+		}
+	}
+	return r.Tiers[len(r.Tiers)-1], true
+}
+
+func (r Rule) validate() error {
+	if r.Flat < 0 || r.BasisPoints < 0 || r.CrossCurrencyBasisPoints < 0 {
+		return errors.New("fees must not be negative")
+	}
+	if r.BasisPoints > basisPoints || r.CrossCurrencyBasisPoints > basisPoints {
+		return errors.New("percentage fees must not be above 100%")
+	}
+
+	chargesFee := r.Flat > 0 || r.BasisPoints > 0 || r.CrossCurrencyBasisPoints > 0
+
+	var lastUpTo int64
+	for i, tier := range r.Tiers {
+		if tier.Flat < 0 || tier.BasisPoints < 0 {
+			return fmt.Errorf("tier %d: fees must not be negative", i)
+		}
+		if tier.BasisPoints > basisPoints {
+			return fmt.Errorf("tier %d: percentage fees must not be above 100%%", i)
+		}
+		if tier.UpTo == 0 && i != len(r.Tiers)-1 {
+			return fmt.Errorf("tier %d: only the last tier can be unbounded", i)
+		}
+		if tier.UpTo != 0 && tier.UpTo <= lastUpTo {
+			return fmt.Errorf("tier %d: bounds must be increasing", i)
+		}
+		lastUpTo = tier.UpTo
+		chargesFee = chargesFee || tier.Flat > 0 || tier.BasisPoints > 0
+	}
+
+	if chargesFee && r.RevenueAccountID <= 0 {
+		return ErrNoRevenueAccount
+	}
+	return nil
+}
+
+// percentOf returns amount * bps / 10000 rounded half up, for a non-negative amount and bps up to 10000.
+// The amount is split by 10000 first, so the products stay within int64 even for the largest amounts.
+func percentOf(amount int64, bps int64) int64 {
+	quotient, remainder := amount/basisPoints, amount%basisPoints
+	return quotient*bps + (remainder*bps+basisPoints/2)/basisPoints
+}
@@ -0,0 +1,146 @@
+package fee
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculate(t *testing.T) {
+	schedule := &Schedule{
+		Default: Rule{
+			Flat:                     25,
+			BasisPoints:              100,
+			CrossCurrencyBasisPoints: 250,
+			RevenueAccountID:         1,
+		},
+		Currencies: map[string]Rule{
+			util.EUR: {
+				Tiers: []Tier{
+					{UpTo: 1000, Flat: 10},
+					{UpTo: 10000, BasisPoints: 50},
+					{BasisPoints: 20},
+				},
+				RevenueAccountID: 2,
+			},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		amount       int64
+		fromCurrency string
+		toCurrency   string
+		expected     Breakdown
+	}{
+		{
+			name:         "FlatAndPercentage",
+			amount:       1000,
+			fromCurrency: util.USD,
+			toCurrency:   util.USD,
+			expected:     Breakdown{Flat: 25, Percentage: 10, Total: 35, RevenueAccountID: 1},
+		},
+		{
+			name:         "CrossCurrency",
+			amount:       1000,
+			fromCurrency: util.USD,
+			toCurrency:   util.CAD,
+			expected:     Breakdown{Flat: 25, Percentage: 10, CrossCurrency: 25, Total: 60, RevenueAccountID: 1},
+		},
+		{
+			name:         "RoundHalfUp",
+			amount:       150,
+			fromCurrency: util.USD,
+			toCurrency:   util.USD,
+			expected:     Breakdown{Flat: 25, Percentage: 2, Total: 27, RevenueAccountID: 1},
+		},
+		{
+			name:         "FirstTier",
+			amount:       1000,
+			fromCurrency: util.EUR,
+			toCurrency:   util.EUR,
+			expected:     Breakdown{Flat: 10, Total: 10, RevenueAccountID: 2},
+		},
+		{
+			name:         "SecondTier",
+			amount:       5000,
+			fromCurrency: util.EUR,
+			toCurrency:   util.EUR,
+			expected:     Breakdown{Percentage: 25, Total: 25, RevenueAccountID: 2},
+		},
+		{
+			name:         "UnboundedTier",
+			amount:       100000,
+			fromCurrency: util.EUR,
+			toCurrency:   util.EUR,
+			expected:     Breakdown{Percentage: 200, Total: 200, RevenueAccountID: 2},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			breakdown := schedule.Calculate(tc.amount, tc.fromCurrency, tc.toCurrency)
+			require.Equal(t, tc.expected, breakdown)
+		})
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	require.Equal(t, int64(2), percentOf(150, 100))
+	require.Equal(t, int64(math.MaxInt64), percentOf(math.MaxInt64, basisPoints))
+	require.Equal(t, int64(922337203685478), percentOf(math.MaxInt64, 1))
+	require.Equal(t, int64(math.MaxInt64/2+1), percentOf(math.MaxInt64, basisPoints/2))
+	require.Zero(t, percentOf(math.MaxInt64, 0))
+}
+
+func TestCalculateNilSchedule(t *testing.T) {
+	var schedule *Schedule
+	require.Equal(t, Breakdown{}, schedule.Calculate(util.RandomMoney(), util.USD, util.EUR))
+}
+
+func TestValidate(t *testing.T) {
+	schedule := &Schedule{Default: Rule{Flat: 10}}
+	require.ErrorIs(t, schedule.Validate(), ErrNoRevenueAccount)
+
+	schedule = &Schedule{Default: Rule{Tiers: []Tier{{BasisPoints: 10}, {UpTo: 100}}, RevenueAccountID: 1}}
+	require.Error(t, schedule.Validate())
+
+	schedule = &Schedule{Default: Rule{Tiers: []Tier{{UpTo: 100}, {UpTo: 50}}, RevenueAccountID: 1}}
+	require.Error(t, schedule.Validate())
+
+	schedule = &Schedule{Default: Rule{BasisPoints: -1, RevenueAccountID: 1}}
+	require.Error(t, schedule.Validate())
+
+	schedule = &Schedule{Default: Rule{BasisPoints: basisPoints + 1, RevenueAccountID: 1}}
+	require.Error(t, schedule.Validate())
+
+	schedule = &Schedule{Default: Rule{Tiers: []Tier{{BasisPoints: basisPoints + 1}}, RevenueAccountID: 1}}
+	require.Error(t, schedule.Validate())
+
+	schedule = &Schedule{}
+	require.NoError(t, schedule.Validate())
+}
+
+func TestLoadSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fees.json")
+	err := os.WriteFile(path, []byte(`{
+		"default": {"flat": 5, "revenue_account_id": 7},
+		"currencies": {"USD": {"basis_points": 30, "revenue_account_id": 8}}
+	}`), 0o600)
+	require.NoError(t, err)
+
+	schedule, err := LoadSchedule(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), schedule.Default.Flat)
+	require.Equal(t, int64(8), schedule.Rule(util.USD).RevenueAccountID)
+	require.Equal(t, int64(7), schedule.Rule(util.EUR).RevenueAccountID)
+
+	_, err = LoadSchedule(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -3,8 +3,10 @@ package gapi
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/MElghrbawy/simple_bank/apperr"
 	"github.com/MElghrbawy/simple_bank/token"
 	"google.golang.org/grpc/metadata"
 )
@@ -25,7 +27,37 @@ func (server *Server) authorizeUser(c context.Context) (*token.Payload, error) {
 		return nil, fmt.Errorf("authorization token is not provided")
 	}
 
-	authHeader := values[0]
+	return server.verifyAuthorizationHeader(values[0])
+}
+
+// authorizeAdmin authorizes the call of an admin, one of the ADMIN_USERNAMES.
+func (server *Server) authorizeAdmin(c context.Context) (*token.Payload, error) {
+	payload, err := server.authorizeUser(c)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if !slices.Contains(server.config.AdminUsernames, payload.Username) {
+		return nil, apperr.GRPCError(apperr.New(apperr.PermissionDenied, "only admins can call this method"))
+	}
+	return payload, nil
+}
+
+// authorizeBanker authorizes the call of a banker, one of the BANKER_USERNAMES.
+func (server *Server) authorizeBanker(c context.Context) (*token.Payload, error) {
+	payload, err := server.authorizeUser(c)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if !slices.Contains(server.config.BankerUsernames, payload.Username) {
+		return nil, apperr.GRPCError(apperr.New(apperr.PermissionDenied, "only bankers can call this method"))
+	}
+	return payload, nil
+}
+
+// verifyAuthorizationHeader verifies the bearer token of an authorization header.
+func (server *Server) verifyAuthorizationHeader(authHeader string) (*token.Payload, error) {
 	fields := strings.Fields(authHeader)
 	if len(fields) < 2 {
 		return nil, fmt.Errorf("invalid authorization header format")
@@ -1,7 +1,12 @@
 package gapi
 
 import (
+	"encoding/hex"
+	"fmt"
+
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/event"
+	"github.com/MElghrbawy/simple_bank/money"
 	"github.com/MElghrbawy/simple_bank/pb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -15,3 +20,74 @@ func convertUser(user db.User) *pb.User {
 		CreatedAt:         timestamppb.New(user.CreatedAt),
 	}
 }
+
+func convertAuditEvent(event db.AuditEvent) *pb.AuditEvent {
+	return &pb.AuditEvent{
+		Id:        event.ID,
+		Action:    event.Action,
+		Actor:     event.Actor,
+		Subject:   event.Subject,
+		ClientIp:  event.ClientIp,
+		UserAgent: event.UserAgent,
+		Before:    string(event.Before),
+		After:     string(event.After),
+		PrevHash:  hex.EncodeToString(event.PrevHash),
+		Hash:      hex.EncodeToString(event.Hash),
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+}
+
+func convertMoney(amount money.Amount) *pb.Money {
+	return &pb.Money{
+		Amount:   amount.String(),
+		Currency: amount.Currency.Code,
+	}
+}
+
+func parseMoney(m *pb.Money) (money.Amount, error) {
+	if m == nil {
+		return money.Amount{}, fmt.Errorf("amount is required")
+	}
+	return money.Parse(m.GetAmount(), m.GetCurrency())
+}
+
+func convertTransfer(result db.TransferTxResult, currency money.Currency) *pb.Transfer {
+	return &pb.Transfer{
+		Id:            result.Transfer.ID,
+		FromAccountId: result.Transfer.FromAccountID,
+		ToAccountId:   result.Transfer.ToAccountID,
+		Amount:        convertMoney(money.Amount{Units: result.Transfer.Amount, Currency: currency}),
+		Fee:           convertMoney(money.Amount{Units: result.Fee.Total, Currency: currency}),
+		CreatedAt:     timestamppb.New(result.Transfer.CreatedAt),
+	}
+}
+
+func convertPendingTransfer(pending db.PendingTransfer, currency money.Currency) *pb.PendingTransfer {
+	rsp := &pb.PendingTransfer{
+		Id:            pending.ID,
+		FromAccountId: pending.FromAccountID,
+		ToAccountId:   pending.ToAccountID,
+		Amount:        convertMoney(money.Amount{Units: pending.Amount, Currency: currency}),
+		RequestedBy:   pending.RequestedBy,
+		Reason:        pending.Reason,
+		Status:        string(pending.Status),
+		DecidedBy:     pending.DecidedBy,
+		TransferId:    pending.TransferID.Int64,
+		CreatedAt:     timestamppb.New(pending.CreatedAt),
+		ExpiresAt:     timestamppb.New(pending.ExpiresAt),
+	}
+	if pending.DecidedAt.Valid {
+		rsp.DecidedAt = timestamppb.New(pending.DecidedAt.Time)
+	}
+	return rsp
+}
+
+func convertAccountEvent(ev event.AccountEvent, currency money.Currency) *pb.AccountEvent {
+	return &pb.AccountEvent{
+		EventId:   ev.EventID,
+		AccountId: ev.AccountID,
+		Amount:    convertMoney(money.Amount{Units: ev.Amount, Currency: currency}),
+		Balance:   convertMoney(money.Amount{Units: ev.Balance, Currency: currency}),
+		CreatedAt: timestamppb.New(ev.CreatedAt),
+	}
+}
@@ -1,25 +1,37 @@
 package gapi
 
 import (
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"encoding/json"
+	"net/http"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
 )
 
-func fieldViolation(fieldName string, err error) *errdetails.BadRequest_FieldViolation {
-	return &errdetails.BadRequest_FieldViolation{
+func fieldViolation(fieldName string, err error) apperr.FieldViolation {
+	return apperr.FieldViolation{
 		Field:       fieldName,
 		Description: err.Error(),
 	}
 }
 
-func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) error {
-	badRequest := &errdetails.BadRequest{FieldViolations: violations}
-	st, _ := status.New(codes.InvalidArgument, "invalid argument").WithDetails(badRequest)
-
-	return st.Err()
+func invalidArgumentError(violations []apperr.FieldViolation) error {
+	return apperr.GRPCError(apperr.Invalid(violations...))
 }
 
 func unauthenticatedError(err error) error {
-	return status.Errorf(codes.Unauthenticated, "unauthenticated : %s", err)
+	return apperr.GRPCError(apperr.Wrapf(err, apperr.Unauthenticated, "unauthenticated : %s", err))
+}
+
+// internalError reports err to the client with a message hiding its details.
+func internalError(err error, message string) error {
+	return apperr.GRPCError(apperr.Wrap(err, apperr.Internal, message))
+}
+
+// writeProblem writes the RFC 9457 problem details describing err,
+// for the HTTP handlers served next to the gateway.
+func writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := apperr.NewProblem(err, r.URL.Path)
+	w.Header().Set("Content-Type", apperr.ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
 }
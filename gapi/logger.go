@@ -29,10 +29,10 @@ func GrpcLogger(
 		statusCode = st.Code()
 	}
 
-	logger := log.Info()
+	logger := log.Ctx(ctx).Info()
 
 	if err != nil {
-		logger = log.Error().Err(err)
+		logger = log.Ctx(ctx).Error().Err(err)
 	}
 
 	logger.Str("protocol", "grpc").
@@ -61,6 +61,13 @@ func (rec *ResponseRecorder) Write(body []byte) (int, error) {
 	return rec.ResponseWriter.Write(body)
 }
 
+// Flush lets streaming handlers flush through the recorder.
+func (rec *ResponseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func HttpLogger(handler http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
@@ -69,10 +76,10 @@ func HttpLogger(handler http.Handler) http.Handler {
 		handler.ServeHTTP(rec, req)
 		duration := time.Since(startTime)
 
-		logger := log.Info()
+		logger := log.Ctx(req.Context()).Info()
 
 		if rec.statusCode >= 400 {
-			logger = log.Error().Bytes("response_body", rec.body)
+			logger = log.Ctx(req.Context()).Error().Bytes("response_body", rec.body)
 		}
 
 		logger.Str("protocol", "http").
@@ -3,43 +3,15 @@ package gapi
 import (
 	"context"
 
-	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/peer"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
 )
 
-const (
-	grpcGatewayUserAgentKey = "grpcgateway-user-agent"
-	xForwardFor             = " x-forwarded-for"
-
-	userAgentHeader = "user-agent"
-	clientIpHeader  = "x-forwarded-for"
-)
-
-type MetaData struct {
-	UserAgent string
-	ClientIp  string
-}
-
-func (server *Server) extractMetadata(c context.Context) *MetaData {
-	mtd := &MetaData{}
-	if md, ok := metadata.FromIncomingContext(c); ok {
-		if userAgents := md.Get(grpcGatewayUserAgentKey); len(userAgents) > 0 {
-			mtd.UserAgent = userAgents[0]
-		}
-
-		if userAgents := md.Get(userAgentHeader); len(userAgents) > 0 {
-			mtd.UserAgent = userAgents[0]
-		}
-
-		if clientIps := md.Get(xForwardFor); len(clientIps) > 0 {
-			mtd.ClientIp = clientIps[0]
-		}
-
-	}
-
-	if per, ok := peer.FromContext(c); ok {
-		mtd.ClientIp = per.Addr.String()
-	}
-
-	return mtd
+// withActor returns a context attributing the actions audited during the call to username.
+func (server *Server) withActor(c context.Context, username string) context.Context {
+	info := server.clientInfo.FromGRPC(c)
+	return db.WithActor(c, db.Actor{
+		Username:  username,
+		ClientIP:  info.ClientIP,
+		UserAgent: info.UserAgent,
+	})
 }
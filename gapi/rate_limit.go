@@ -0,0 +1,41 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/MElghrbawy/simple_bank/ratelimit"
+)
+
+// GatewayMethods maps the routes of the gateway to the RPCs they serve, as declared by the
+// http options of service_simplebank.proto, so the gateway applies the rate limits of the RPCs.
+var GatewayMethods = map[string]string{
+	"POST /v1/create_user":        "CreateUser",
+	"POST /v1/update_user":        "UpdateUser",
+	"POST /v1/login_user":         "LoginUser",
+	"POST /v1/create_transfer":    "CreateTransfer",
+	"GET /v1/accounts/{id}/watch": "WatchAccount",
+	"GET /v1/audit_events":        "ListAuditEvents",
+
+	"GET /v1/pending_transfers":               "ListPendingTransfers",
+	"POST /v1/pending_transfers/{id}/approve": "ApprovePendingTransfer",
+	"POST /v1/pending_transfers/{id}/reject":  "RejectPendingTransfer",
+}
+
+// GrpcRateLimitKey identifies the caller of a gRPC call for rate limiting:
+// the user of a valid access token, or else the client IP.
+func (server *Server) GrpcRateLimitKey(ctx context.Context) string {
+	if payload, err := server.authorizeUser(ctx); err == nil {
+		return ratelimit.UserKey(payload.Username)
+	}
+	return ratelimit.IPKey(server.clientInfo.FromGRPC(ctx).ClientIP)
+}
+
+// HttpRateLimitKey identifies the sender of a gateway request for rate limiting:
+// the user of a valid access token, or else the client IP.
+func (server *Server) HttpRateLimitKey(req *http.Request) string {
+	if payload, err := server.verifyAuthorizationHeader(req.Header.Get(authorizationHeader)); err == nil {
+		return ratelimit.UserKey(payload.Username)
+	}
+	return ratelimit.IPKey(server.clientInfo.FromHTTP(req).ClientIP)
+}
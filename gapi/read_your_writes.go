@@ -0,0 +1,43 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// readYourWritesHeader lets a client ask for its reads to be served by the primary database,
+// so they see the writes it just made even when the read replica lags behind.
+const readYourWritesHeader = "x-read-your-writes"
+
+func GrpcReadYourWrites(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp any, err error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(readYourWritesHeader); len(values) > 0 && readYourWrites(values[0]) {
+			ctx = db.WithPrimaryReads(ctx)
+		}
+	}
+	return handler(ctx, req)
+}
+
+func HttpReadYourWrites(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if readYourWrites(req.Header.Get(readYourWritesHeader)) {
+			req = req.WithContext(db.WithPrimaryReads(req.Context()))
+		}
+		handler.ServeHTTP(res, req)
+	})
+}
+
+func readYourWrites(value string) bool {
+	enabled, _ := strconv.ParseBool(value)
+	return enabled
+}
@@ -0,0 +1,46 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/MElghrbawy/simple_bank/requestid"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// GrpcRequestID takes the request ID from the x-request-id metadata, or generates one,
+// carries it in the context of the handler and returns it in the response header.
+// It must run before the interceptors that log.
+func GrpcRequestID(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp any, err error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestid.MetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	id = requestid.FromClient(id)
+
+	ctx = requestid.NewContext(ctx, id)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, id)); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("cannot send request id")
+	}
+	return handler(ctx, req)
+}
+
+// HttpRequestID takes the request ID from the X-Request-ID header, or generates one,
+// carries it in the request context and returns it in the response header.
+// It must wrap the handlers that log.
+func HttpRequestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		id := requestid.FromClient(req.Header.Get(requestid.Header))
+		res.Header().Set(requestid.Header, id)
+		handler.ServeHTTP(res, req.WithContext(requestid.NewContext(req.Context(), id)))
+	})
+}
@@ -0,0 +1,48 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/pb"
+)
+
+// ApprovePendingTransfer makes a transfer parked for review. Only bankers can approve one.
+func (server *Server) ApprovePendingTransfer(c context.Context, req *pb.ApprovePendingTransferRequest) (*pb.ApprovePendingTransferResponse, error) {
+	authPayload, err := server.authorizeBanker(c)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := validatePendingTransferID(req.GetId())
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations)
+	}
+
+	result, err := server.store.ApprovePendingTransferTx(server.withActor(c, authPayload.Username), db.DecidePendingTransferTxParams{
+		ID:        req.GetId(),
+		DecidedBy: authPayload.Username,
+	})
+	if err != nil {
+		return nil, apperr.GRPCError(err)
+	}
+
+	currency, err := server.accountCurrency(c, result.PendingTransfer.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &pb.ApprovePendingTransferResponse{
+		PendingTransfer: convertPendingTransfer(result.PendingTransfer, currency),
+		Transfer:        convertTransfer(result.Transfer, currency),
+	}
+	return rsp, nil
+}
+
+func validatePendingTransferID(id int64) (violations []apperr.FieldViolation) {
+	if id <= 0 {
+		violations = append(violations, apperr.FieldViolation{Field: "id", Description: "must be a positive pending transfer id"})
+	}
+	return violations
+}
@@ -0,0 +1,145 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/pb"
+	"github.com/MElghrbawy/simple_bank/risk"
+	"github.com/rs/zerolog/log"
+)
+
+func (server *Server) CreateTransfer(c context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	authPayload, err := server.authorizeUser(c)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	amount, violations := validateCreateTransferRequest(req)
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations)
+	}
+
+	fromAccount, err := server.validAccount(c, req.GetFromAccountId(), amount.Currency.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		return nil, apperr.GRPCError(apperr.New(apperr.PermissionDenied, "from account does not belong to the user"))
+	}
+
+	_, err = server.validAccount(c, req.GetToAccountId(), amount.Currency.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	c = server.withActor(c, authPayload.Username)
+	arg := db.TransferTxParams{
+		FromAccountID: req.GetFromAccountId(),
+		ToAccountID:   req.GetToAccountId(),
+		Amount:        amount,
+	}
+
+	assessment, err := server.risk.Evaluate(c, risk.Transfer{
+		Username:      authPayload.Username,
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ClientIP:      db.ActorFromContext(c).ClientIP,
+	})
+	if err != nil {
+		return nil, internalError(err, "failed to evaluate the transfer")
+	}
+
+	if assessment.Decision == risk.Deny {
+		server.recordDeclinedTransfer(c, arg, assessment)
+		return nil, apperr.GRPCError(risk.ErrDeclined)
+	}
+
+	if reasons := server.approvals.Reasons(arg.Amount, assessment); len(reasons) > 0 {
+		pending, err := server.store.CreatePendingTransferTx(c, db.CreatePendingTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount.Units,
+			RequestedBy:   authPayload.Username,
+			Reason:        strings.Join(reasons, "; "),
+			ExpiresAt:     time.Now().Add(server.config.PendingTransferTTL),
+		})
+		if err != nil {
+			return nil, apperr.GRPCError(err)
+		}
+
+		rsp := &pb.CreateTransferResponse{
+			PendingTransfer: convertPendingTransfer(pending, amount.Currency),
+		}
+		return rsp, nil
+	}
+
+	result, err := server.store.TransferTx(c, arg)
+	if err != nil {
+		return nil, apperr.GRPCError(err)
+	}
+
+	rsp := &pb.CreateTransferResponse{
+		Transfer: convertTransfer(result, amount.Currency),
+	}
+	return rsp, nil
+}
+
+// recordDeclinedTransfer keeps the reasons a transfer was declined in the audit log, as the client is not told.
+func (server *Server) recordDeclinedTransfer(c context.Context, arg db.TransferTxParams, assessment risk.Assessment) {
+	_, err := server.store.RecordAuditEventTx(c, db.RecordAuditEventTxParams{
+		Action:  db.AuditTransferDeclined,
+		Subject: db.AuditSubject("account", arg.FromAccountID),
+		After: map[string]any{
+			"transfer":   arg,
+			"assessment": assessment,
+		},
+	})
+	if err != nil {
+		log.Ctx(c).Error().Err(err).Msg("cannot record declined transfer")
+	}
+}
+
+// validAccount fetches an account and checks it is in the currency of the transfer.
+func (server *Server) validAccount(c context.Context, accountID int64, currency string) (db.Account, error) {
+	account, err := server.store.GetAccount(c, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err = apperr.Wrapf(err, apperr.NotFound, "account %d not found", accountID)
+		}
+		return account, apperr.GRPCError(err)
+	}
+
+	if account.Currency != currency {
+		return account, apperr.GRPCError(apperr.Newf(apperr.Validation, "account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency))
+	}
+
+	return account, nil
+}
+
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) (amount money.Amount, violations []apperr.FieldViolation) {
+	if req.GetFromAccountId() <= 0 {
+		violations = append(violations, fieldViolation("from_account_id", fmt.Errorf("must be a positive account id")))
+	}
+
+	if req.GetToAccountId() <= 0 {
+		violations = append(violations, fieldViolation("to_account_id", fmt.Errorf("must be a positive account id")))
+	}
+
+	amount, err := parseMoney(req.GetAmount())
+	if err != nil {
+		violations = append(violations, fieldViolation("amount", err))
+	} else if !amount.IsPositive() {
+		violations = append(violations, fieldViolation("amount", fmt.Errorf("must be positive")))
+	}
+
+	return amount, violations
+}
@@ -3,14 +3,12 @@ package gapi
 import (
 	"context"
 
+	"github.com/MElghrbawy/simple_bank/apperr"
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
 	"github.com/MElghrbawy/simple_bank/pb"
 	"github.com/MElghrbawy/simple_bank/util"
 	"github.com/MElghrbawy/simple_bank/val"
-	"github.com/lib/pq"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/MElghrbawy/simple_bank/worker"
 )
 
 func (server *Server) CreateUser(c context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
@@ -21,35 +19,39 @@ func (server *Server) CreateUser(c context.Context, req *pb.CreateUserRequest) (
 
 	hashedPassword, err := util.HashPassword(req.GetPassword())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to hash password")
+		return nil, internalError(err, "failed to hash password")
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.GetUsername(),
-		HashedPassword: hashedPassword,
-		FullName:       req.GetFullName(),
-		Email:          req.GetEmail(),
+	verifyEmailTask, err := worker.NewOutboxTaskSendVerifyEmail(c, &worker.PayloadSendVerifyEmail{
+		Username: req.GetUsername(),
+	})
+	if err != nil {
+		return nil, internalError(err, "failed to create verify email task")
+	}
+
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.GetUsername(),
+			HashedPassword: hashedPassword,
+			FullName:       req.GetFullName(),
+			Email:          req.GetEmail(),
+		},
+		Tasks: []db.CreateOutboxTaskParams{verifyEmailTask},
 	}
 
-	user, err := server.store.CreateUser(c, arg)
+	result, err := server.store.CreateUserTx(server.withActor(c, req.GetUsername()), arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				return nil, status.Error(codes.AlreadyExists, "username or email already exists")
-			}
-		}
-		return nil, status.Error(codes.Internal, "could not create user")
+		return nil, apperr.GRPCError(err)
 	}
 
 	rsp := &pb.CreateUserResponse{
-		User: convertUser(user),
+		User: convertUser(result.User),
 	}
 	return rsp, nil
 
 }
 
-func validateCreateUserRequest(req *pb.CreateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+func validateCreateUserRequest(req *pb.CreateUserRequest) (violations []apperr.FieldViolation) {
 	if err := val.ValidateUsername(req.GetUsername()); err != nil {
 		violations = append(violations, fieldViolation("username", err))
 	}
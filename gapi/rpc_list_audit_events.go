@@ -0,0 +1,63 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/pb"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 1000
+)
+
+// ListAuditEvents pages through the audit log, oldest event first. Only admins can read it.
+func (server *Server) ListAuditEvents(c context.Context, req *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResponse, error) {
+	_, err := server.authorizeAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := validateListAuditEventsRequest(req)
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations)
+	}
+
+	pageSize := req.GetPageSize()
+	if pageSize == 0 {
+		pageSize = defaultAuditPageSize
+	}
+
+	events, err := server.store.ListAuditEvents(c, db.ListAuditEventsParams{
+		AfterID: req.GetAfterId(),
+		Actor:   pgtype.Text{String: req.GetActor(), Valid: req.Actor != nil},
+		Subject: pgtype.Text{String: req.GetSubject(), Valid: req.Subject != nil},
+		Action:  pgtype.Text{String: req.GetAction(), Valid: req.Action != nil},
+		Limit:   pageSize,
+	})
+	if err != nil {
+		return nil, apperr.GRPCError(err)
+	}
+
+	rsp := &pb.ListAuditEventsResponse{
+		Events: make([]*pb.AuditEvent, len(events)),
+	}
+	for i, event := range events {
+		rsp.Events[i] = convertAuditEvent(event)
+	}
+	return rsp, nil
+}
+
+func validateListAuditEventsRequest(req *pb.ListAuditEventsRequest) (violations []apperr.FieldViolation) {
+	if req.GetAfterId() < 0 {
+		violations = append(violations, apperr.FieldViolation{Field: "after_id", Description: "must not be negative"})
+	}
+
+	if req.GetPageSize() < 0 || req.GetPageSize() > maxAuditPageSize {
+		violations = append(violations, apperr.FieldViolation{Field: "page_size", Description: "must be between 1 and 1000, or 0 for the default"})
+	}
+	return violations
+}
@@ -0,0 +1,94 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/pb"
+)
+
+const (
+	defaultPendingTransfersPageSize = 50
+	maxPendingTransfersPageSize     = 100
+)
+
+// ListPendingTransfers lists the transfers parked for review, oldest first. Only bankers can list them.
+func (server *Server) ListPendingTransfers(c context.Context, req *pb.ListPendingTransfersRequest) (*pb.ListPendingTransfersResponse, error) {
+	_, err := server.authorizeBanker(c)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := validateListPendingTransfersRequest(req)
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations)
+	}
+
+	status := db.PendingTransferStatus(req.GetStatus())
+	if status == "" {
+		status = db.PendingTransferStatusPending
+	}
+	pageSize := req.GetPageSize()
+	if pageSize == 0 {
+		pageSize = defaultPendingTransfersPageSize
+	}
+
+	pendingTransfers, err := server.store.ListPendingTransfers(c, db.ListPendingTransfersParams{
+		Status: status,
+		Limit:  pageSize,
+		Offset: req.GetPageOffset(),
+	})
+	if err != nil {
+		return nil, apperr.GRPCError(err)
+	}
+
+	rsp := &pb.ListPendingTransfersResponse{
+		PendingTransfers: make([]*pb.PendingTransfer, len(pendingTransfers)),
+	}
+	currencies := make(map[int64]money.Currency)
+	for i, pending := range pendingTransfers {
+		currency, ok := currencies[pending.FromAccountID]
+		if !ok {
+			currency, err = server.accountCurrency(c, pending.FromAccountID)
+			if err != nil {
+				return nil, err
+			}
+			currencies[pending.FromAccountID] = currency
+		}
+		rsp.PendingTransfers[i] = convertPendingTransfer(pending, currency)
+	}
+	return rsp, nil
+}
+
+// accountCurrency returns the currency of an account, which the amounts of its transfers are in.
+func (server *Server) accountCurrency(c context.Context, accountID int64) (money.Currency, error) {
+	account, err := server.store.GetAccount(c, accountID)
+	if err != nil {
+		return money.Currency{}, apperr.GRPCError(err)
+	}
+
+	currency, ok := money.LookupCurrency(account.Currency)
+	if !ok {
+		return money.Currency{}, apperr.GRPCError(apperr.New(apperr.Internal, "unsupported account currency"))
+	}
+	return currency, nil
+}
+
+func validateListPendingTransfersRequest(req *pb.ListPendingTransfersRequest) (violations []apperr.FieldViolation) {
+	switch db.PendingTransferStatus(req.GetStatus()) {
+	case "", db.PendingTransferStatusPending, db.PendingTransferStatusApproved, db.PendingTransferStatusRejected, db.PendingTransferStatusExpired:
+	default:
+		violations = append(violations, apperr.FieldViolation{Field: "status", Description: `must be "pending", "approved", "rejected" or "expired"`})
+	}
+
+	if req.GetPageSize() < 0 || req.GetPageSize() > maxPendingTransfersPageSize {
+		violations = append(violations, apperr.FieldViolation{Field: "page_size", Description: "must be between 1 and 100, or 0 for the default"})
+	}
+
+	if req.GetPageOffset() < 0 {
+		violations = append(violations, apperr.FieldViolation{Field: "page_offset", Description: "must not be negative"})
+	}
+	return violations
+}
@@ -2,61 +2,67 @@ package gapi
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 
+	"github.com/MElghrbawy/simple_bank/apperr"
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
 	"github.com/MElghrbawy/simple_bank/pb"
 	"github.com/MElghrbawy/simple_bank/util"
 	"github.com/MElghrbawy/simple_bank/val"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/rs/zerolog/log"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// errInvalidCredentials refuses an unknown user and a wrong password alike, so clients cannot tell which usernames exist.
+var errInvalidCredentials = apperr.New(apperr.Unauthenticated, "invalid username or password")
+
 func (server *Server) LoginUser(c context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
 	violations := validateLoginUserRequest(req)
 	if len(violations) > 0 {
 		return nil, invalidArgumentError(violations)
 	}
 
+	c = server.withActor(c, req.GetUsername())
 	user, err := server.store.GetUser(c, req.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Error(codes.NotFound, "user not found")
-
+		if errors.Is(err, db.ErrRecordNotFound) {
+			// check the password anyway, or the quicker response would give away that the user does not exist
+			util.CheckUnknownUserPassword(req.GetPassword())
+			server.recordFailedLogin(c, req.GetUsername(), "user not found")
+			err = errInvalidCredentials
 		}
-		return nil, status.Error(codes.Internal, "Internal server error")
+		return nil, apperr.GRPCError(err)
 	}
 
 	if err := util.CheckPasswordHash(req.Password, user.HashedPassword); err != nil {
-		return nil, status.Error(codes.Unauthenticated, "invalid username or password")
+		server.recordFailedLogin(c, req.GetUsername(), "wrong password")
+		return nil, apperr.GRPCError(errInvalidCredentials)
 	}
 
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create access token")
+		return nil, internalError(err, "failed to create access token")
 
 	}
 
 	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.RefreshTokenDuration)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create refresh token")
+		return nil, internalError(err, "failed to create refresh token")
 	}
 
-	md := server.extractMetadata(c)
-	session, err := server.store.CreateSession(c, db.CreateSessionParams{
+	info := server.clientInfo.FromGRPC(c)
+	session, err := server.store.CreateSessionTx(c, db.CreateSessionParams{
 		ID:           refreshPayload.ID,
 		Username:     user.Username,
 		RefreshToken: refreshToken,
-		UserAgent:    md.UserAgent,
-		ClientIp:     md.ClientIp,
+		UserAgent:    info.UserAgent,
+		ClientIp:     info.ClientIP,
 		IsBlocked:    false,
 		ExpiresAt:    refreshPayload.ExpiresAt,
 	})
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create session")
+		return nil, internalError(err, "failed to create session")
 	}
 
 	res := &pb.LoginUserResponse{
@@ -71,7 +77,19 @@ func (server *Server) LoginUser(c context.Context, req *pb.LoginUserRequest) (*p
 	return res, nil
 }
 
-func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+// recordFailedLogin records a failed login in the audit log. The client is refused whether the log is written or not.
+func (server *Server) recordFailedLogin(c context.Context, username string, reason string) {
+	_, err := server.store.RecordAuditEventTx(c, db.RecordAuditEventTxParams{
+		Action:  db.AuditUserLoginFailed,
+		Subject: db.AuditSubject("user", username),
+		After:   map[string]string{"reason": reason},
+	})
+	if err != nil {
+		log.Ctx(c).Error().Err(err).Msg("cannot record failed login")
+	}
+}
+
+func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []apperr.FieldViolation) {
 	if err := val.ValidateUsername(req.GetUsername()); err != nil {
 		violations = append(violations, fieldViolation("username", err))
 	}
@@ -0,0 +1,40 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/pb"
+)
+
+// RejectPendingTransfer refuses a transfer parked for review. Only bankers can reject one.
+func (server *Server) RejectPendingTransfer(c context.Context, req *pb.RejectPendingTransferRequest) (*pb.RejectPendingTransferResponse, error) {
+	authPayload, err := server.authorizeBanker(c)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := validatePendingTransferID(req.GetId())
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations)
+	}
+
+	pending, err := server.store.RejectPendingTransferTx(server.withActor(c, authPayload.Username), db.DecidePendingTransferTxParams{
+		ID:        req.GetId(),
+		DecidedBy: authPayload.Username,
+	})
+	if err != nil {
+		return nil, apperr.GRPCError(err)
+	}
+
+	currency, err := server.accountCurrency(c, pending.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &pb.RejectPendingTransferResponse{
+		PendingTransfer: convertPendingTransfer(pending, currency),
+	}
+	return rsp, nil
+}
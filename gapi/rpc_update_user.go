@@ -2,16 +2,15 @@ package gapi
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/MElghrbawy/simple_bank/apperr"
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
 	"github.com/MElghrbawy/simple_bank/pb"
 	"github.com/MElghrbawy/simple_bank/util"
 	"github.com/MElghrbawy/simple_bank/val"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func (server *Server) UpdateUser(c context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
@@ -26,17 +25,17 @@ func (server *Server) UpdateUser(c context.Context, req *pb.UpdateUserRequest) (
 	}
 
 	if authPayload.Username != req.GetUsername() {
-		return nil, status.Error(codes.PermissionDenied, "cannot update other user")
+		return nil, apperr.GRPCError(apperr.New(apperr.PermissionDenied, "cannot update other user"))
 	}
 
 	arg := db.UpdateUserParams{
 		Username: req.GetUsername(),
-		FullName: sql.NullString{
+		FullName: pgtype.Text{
 			String: req.GetFullName(),
 			Valid:  req.FullName != nil,
 		},
 
-		Email: sql.NullString{
+		Email: pgtype.Text{
 			String: req.GetEmail(),
 			Valid:  req.Email != nil,
 		},
@@ -45,25 +44,25 @@ func (server *Server) UpdateUser(c context.Context, req *pb.UpdateUserRequest) (
 	if req.Password != nil {
 		hashedPassword, err := util.HashPassword(req.GetPassword())
 		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to hash password")
+			return nil, internalError(err, "failed to hash password")
 		}
-		arg.HashedPassword = sql.NullString{
+		arg.HashedPassword = pgtype.Text{
 			String: hashedPassword,
 			Valid:  true,
 		}
 
-		arg.PasswordChangedAt = sql.NullTime{
+		arg.PasswordChangedAt = pgtype.Timestamptz{
 			Time:  time.Now(),
 			Valid: true,
 		}
 	}
 
-	user, err := server.store.UpdateUser(c, arg)
+	user, err := server.store.UpdateUserTx(server.withActor(c, authPayload.Username), arg)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Error(codes.NotFound, "user not found")
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err = apperr.Wrap(err, apperr.NotFound, "user not found")
 		}
-		return nil, status.Errorf(codes.Internal, "could not update user %s", err)
+		return nil, apperr.GRPCError(err)
 	}
 
 	rsp := &pb.UpdateUserResponse{
@@ -73,7 +72,7 @@ func (server *Server) UpdateUser(c context.Context, req *pb.UpdateUserRequest) (
 
 }
 
-func validateUpdateUserRequest(req *pb.UpdateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+func validateUpdateUserRequest(req *pb.UpdateUserRequest) (violations []apperr.FieldViolation) {
 	if err := val.ValidateUsername(req.GetUsername()); err != nil {
 		violations = append(violations, fieldViolation("username", err))
 	}
@@ -0,0 +1,156 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/event"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/pb"
+)
+
+func (server *Server) WatchAccount(req *pb.WatchAccountRequest, stream pb.SimpleBank_WatchAccountServer) error {
+	authPayload, err := server.authorizeUser(stream.Context())
+	if err != nil {
+		return unauthenticatedError(err)
+	}
+
+	watch, err := server.openAccountWatch(stream.Context(), authPayload.Username, req)
+	if err != nil {
+		return apperr.GRPCError(err)
+	}
+	defer watch.close()
+
+	return watch.run(stream.Context(), stream.Send)
+}
+
+// accountWatch is a subscription to the events of an account.
+type accountWatch struct {
+	store       db.Store
+	account     db.Account
+	currency    money.Currency
+	events      <-chan event.AccountEvent
+	close       func()
+	lastEventID int64
+}
+
+// openAccountWatch checks the account is owned by the user and subscribes to its events.
+// Its errors are apperr errors, for both the gRPC and the SSE handlers to report.
+func (server *Server) openAccountWatch(c context.Context, username string, req *pb.WatchAccountRequest) (*accountWatch, error) {
+	violations := validateWatchAccountRequest(req)
+	if len(violations) > 0 {
+		return nil, apperr.Invalid(violations...)
+	}
+
+	account, err := server.store.GetAccount(c, req.GetAccountId())
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, apperr.Wrapf(err, apperr.NotFound, "account %d not found", req.GetAccountId())
+		}
+		return nil, err
+	}
+
+	if account.Owner != username {
+		return nil, apperr.New(apperr.PermissionDenied, "account does not belong to the user")
+	}
+
+	currency, ok := money.LookupCurrency(account.Currency)
+	if !ok {
+		return nil, apperr.New(apperr.Internal, "unsupported account currency")
+	}
+
+	events, unsubscribe := server.events.Subscribe(account.ID)
+	watch := &accountWatch{
+		store:       server.store,
+		account:     account,
+		currency:    currency,
+		events:      events,
+		close:       unsubscribe,
+		lastEventID: req.GetLastEventId(),
+	}
+	return watch, nil
+}
+
+// replayPageSize bounds the entries read by each query when replaying the events a client missed.
+const replayPageSize = 100
+
+// run sends the account events until ctx is done. When the watch was opened with
+// a last event id, the entries that follow it on the account are replayed before the live events.
+// The subscription is opened before the replay, so no event is missed in between.
+func (watch *accountWatch) run(c context.Context, send func(*pb.AccountEvent) error) error {
+	if watch.lastEventID > 0 {
+		if err := watch.replay(c, send); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-c.Done():
+			return nil
+		case ev, ok := <-watch.events:
+			if !ok {
+				return apperr.GRPCError(apperr.New(apperr.Unavailable, "event stream fell behind, reconnect with the last event id"))
+			}
+			if ev.EventID <= watch.lastEventID {
+				continue
+			}
+			if err := watch.send(ev, send); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// replay sends the entries that follow the last event id, a page at a time.
+func (watch *accountWatch) replay(c context.Context, send func(*pb.AccountEvent) error) error {
+	for {
+		missed, err := watch.store.ListAccountEventsAfter(c, db.ListAccountEventsAfterParams{
+			AccountID: watch.account.ID,
+			AfterSeq:  watch.lastEventID,
+			MaxEvents: replayPageSize,
+		})
+		if err != nil {
+			return internalError(err, "could not list account events")
+		}
+
+		for _, row := range missed {
+			ev := event.AccountEvent{
+				EventID:   row.Seq,
+				AccountID: row.AccountID,
+				Amount:    row.Amount,
+				Balance:   row.Balance,
+				CreatedAt: row.CreatedAt,
+			}
+			if err := watch.send(ev, send); err != nil {
+				return err
+			}
+		}
+		if len(missed) < replayPageSize {
+			return nil
+		}
+	}
+}
+
+func (watch *accountWatch) send(ev event.AccountEvent, send func(*pb.AccountEvent) error) error {
+	if err := send(convertAccountEvent(ev, watch.currency)); err != nil {
+		return err
+	}
+	watch.lastEventID = ev.EventID
+	return nil
+}
+
+func validateWatchAccountRequest(req *pb.WatchAccountRequest) (violations []apperr.FieldViolation) {
+	if req.GetAccountId() <= 0 {
+		violations = append(violations, fieldViolation("account_id", fmt.Errorf("must be a positive account id")))
+	}
+
+	if req.GetLastEventId() < 0 {
+		violations = append(violations, fieldViolation("last_event_id", fmt.Errorf("must not be negative")))
+	}
+
+	return violations
+}
@@ -1,10 +1,12 @@
 package gapi
 
 import (
-	"fmt"
-
+	"github.com/MElghrbawy/simple_bank/approval"
+	"github.com/MElghrbawy/simple_bank/clientinfo"
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/event"
 	"github.com/MElghrbawy/simple_bank/pb"
+	"github.com/MElghrbawy/simple_bank/risk"
 	"github.com/MElghrbawy/simple_bank/token"
 	"github.com/MElghrbawy/simple_bank/util"
 )
@@ -15,15 +17,30 @@ type Server struct {
 	config     util.Config
 	store      db.Store
 	tokenMaker token.Maker
+	events     *event.Hub
+	clientInfo *clientinfo.Extractor
+	risk       risk.Evaluator
+	approvals  approval.Thresholds
 }
 
 // NewServer creates a new gRPC server.
-func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+func NewServer(config util.Config, store db.Store, tokenMaker token.Maker, events *event.Hub) (*Server, error) {
+	clientInfo, err := clientinfo.NewExtractor(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator, err := risk.NewEvaluator(config.RiskRulesPath, store)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create token maker %w", err)
+		return nil, err
 	}
-	server := &Server{config: config, store: store, tokenMaker: tokenMaker}
+
+	approvals, err := approval.NewThresholds(config.ApprovalThresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{config: config, store: store, tokenMaker: tokenMaker, events: events, clientInfo: clientInfo, risk: evaluator, approvals: approvals}
 
 	return server, nil
 }
@@ -0,0 +1,84 @@
+package gapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/pb"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// lastEventIDHeader is sent by SSE clients when they reconnect.
+const lastEventIDHeader = "Last-Event-ID"
+
+// WatchAccountSSE streams the events of an account as server-sent events.
+// The gateway cannot serve streaming RPCs in process, so it is registered on
+// the HTTP mux as GET /v1/accounts/{id}/watch.
+func (server *Server) WatchAccountSSE(w http.ResponseWriter, r *http.Request) {
+	authPayload, err := server.verifyAuthorizationHeader(r.Header.Get(authorizationHeader))
+	if err != nil {
+		writeProblem(w, r, apperr.Wrap(err, apperr.Unauthenticated, err.Error()))
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperr.Invalid(apperr.FieldViolation{Field: "id", Description: "must be an account id"}))
+		return
+	}
+
+	lastEventID := r.Header.Get(lastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	req := &pb.WatchAccountRequest{AccountId: accountID}
+	if lastEventID != "" {
+		req.LastEventId, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			writeProblem(w, r, apperr.Invalid(apperr.FieldViolation{Field: "last_event_id", Description: "must be an event id"}))
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeProblem(w, r, apperr.New(apperr.Internal, "streaming is not supported"))
+		return
+	}
+
+	watch, err := server.openAccountWatch(r.Context(), authPayload.Username, req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	defer watch.close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	marshaler := protojson.MarshalOptions{UseProtoNames: true}
+	err = watch.run(r.Context(), func(ev *pb.AccountEvent) error {
+		data, err := marshaler.Marshal(ev)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "id: %d\nevent: account_event\ndata: %s\n\n", ev.GetEventId(), data)
+		if err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil {
+		// the client reconnects with Last-Event-ID to resume the stream
+		log.Ctx(r.Context()).Error().Err(err).Int64("account_id", accountID).Msg("account event stream ended")
+	}
+}
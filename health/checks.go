@@ -0,0 +1,57 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// Pinger is a dependency reachable through a ping, such as a pgxpool.Pool.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck checks the dependency answers a ping.
+func PingCheck(pinger Pinger) CheckFunc {
+	return pinger.Ping
+}
+
+// RedisCheck checks Redis answers a ping.
+func RedisCheck(client redis.UniversalClient) CheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// RowQuerier runs a query returning a single row, such as a pgxpool.Pool.
+type RowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// MigrationCheck checks the database schema is migrated to at least the version the server
+// was built for, and that no migration failed halfway. Newer versions are accepted, so the
+// instances of the previous release stay ready while a new release migrates the database.
+func MigrationCheck(db RowQuerier, want uint) CheckFunc {
+	return func(ctx context.Context) error {
+		var version int64
+		var dirty bool
+		err := db.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("database is not migrated")
+		}
+		if err != nil {
+			return err
+		}
+
+		if dirty {
+			return fmt.Errorf("migration %d failed, the database is dirty", version)
+		}
+		if version < int64(want) {
+			return fmt.Errorf("database is at migration %d, expected %d", version, want)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,157 @@
+// Package health reports whether the server is alive and ready to serve, for the probes of
+// the orchestrator: over HTTP, and through the standard grpc.health.v1 service.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// The statuses of the reports.
+const (
+	StatusOK           = "ok"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+)
+
+// CheckFunc reports why a dependency of the server cannot be used, or nil when it can.
+type CheckFunc func(ctx context.Context) error
+
+// Report describes the outcome of the readiness checks.
+type Report struct {
+	Status string `json:"status"`
+	// Checks holds "ok" or the error of each check by name.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Checker runs the readiness checks of the server, and stops reporting ready once shutdown begins
+// so the orchestrator stops routing requests before the listeners close.
+type Checker struct {
+	timeout      time.Duration
+	names        []string
+	checks       map[string]CheckFunc
+	shuttingDown atomic.Bool
+	grpcHealth   *grpchealth.Server
+}
+
+// NewChecker creates a checker giving each check up to timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout:    timeout,
+		checks:     make(map[string]CheckFunc),
+		grpcHealth: grpchealth.NewServer(),
+	}
+}
+
+// Add makes readiness depend on check.
+func (c *Checker) Add(name string, check CheckFunc) {
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Check runs the checks concurrently, and reports ready if they all pass and shutdown has not begun.
+func (c *Checker) Check(ctx context.Context) (Report, bool) {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	errs := make([]error, len(c.names))
+	var wg sync.WaitGroup
+	for i, name := range c.names {
+		wg.Add(1)
+		go func(i int, check CheckFunc) {
+			defer wg.Done()
+			errs[i] = check(ctx)
+		}(i, c.checks[name])
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(c.names))}
+	for i, name := range c.names {
+		report.Checks[name] = StatusOK
+		if errs[i] != nil {
+			report.Status = StatusNotReady
+			report.Checks[name] = errs[i].Error()
+		}
+	}
+	return report, report.Status == StatusOK
+}
+
+// Shutdown makes the server report not ready from now on.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+	c.grpcHealth.Shutdown()
+}
+
+// GRPCServer is the grpc.health.v1 service, serving the status of the last Run check.
+func (c *Checker) GRPCServer() grpc_health_v1.HealthServer {
+	return c.grpcHealth
+}
+
+// Run runs the checks every interval until ctx is done, updating the status served by GRPCServer.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	wasReady := true
+	for {
+		report, ready := c.Check(ctx)
+		if ready != wasReady {
+			log.Warn().Str("status", report.Status).Interface("checks", report.Checks).Msg("readiness changed")
+			wasReady = ready
+		}
+
+		status := grpc_health_v1.HealthCheckResponse_SERVING
+		if !ready {
+			status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		}
+		// the empty service name stands for the server as a whole
+		c.grpcHealth.SetServingStatus("", status)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LivenessHandler reports the process is alive, whatever the state of its dependencies,
+// so the orchestrator only restarts it when it stops responding.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler runs the checks, answering 503 Service Unavailable when the server is not ready.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report, ready := c.Check(r.Context())
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,177 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	failing := errors.New("connection refused")
+
+	testCases := []struct {
+		name       string
+		checks     map[string]CheckFunc
+		shutdown   bool
+		wantCode   int
+		wantReport Report
+	}{
+		{
+			name: "Ready",
+			checks: map[string]CheckFunc{
+				"database": func(ctx context.Context) error { return nil },
+				"redis":    func(ctx context.Context) error { return nil },
+			},
+			wantCode:   http.StatusOK,
+			wantReport: Report{Status: StatusOK, Checks: map[string]string{"database": StatusOK, "redis": StatusOK}},
+		},
+		{
+			name: "CheckFails",
+			checks: map[string]CheckFunc{
+				"database": func(ctx context.Context) error { return nil },
+				"redis":    func(ctx context.Context) error { return failing },
+			},
+			wantCode:   http.StatusServiceUnavailable,
+			wantReport: Report{Status: StatusNotReady, Checks: map[string]string{"database": StatusOK, "redis": failing.Error()}},
+		},
+		{
+			name: "CheckTimesOut",
+			checks: map[string]CheckFunc{
+				"database": func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			wantCode:   http.StatusServiceUnavailable,
+			wantReport: Report{Status: StatusNotReady, Checks: map[string]string{"database": context.DeadlineExceeded.Error()}},
+		},
+		{
+			name: "ShuttingDown",
+			checks: map[string]CheckFunc{
+				"database": func(ctx context.Context) error { return nil },
+			},
+			shutdown:   true,
+			wantCode:   http.StatusServiceUnavailable,
+			wantReport: Report{Status: StatusShuttingDown},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			checker := NewChecker(50 * time.Millisecond)
+			for name, check := range tc.checks {
+				checker.Add(name, check)
+			}
+			if tc.shutdown {
+				checker.Shutdown()
+			}
+
+			recorder := httptest.NewRecorder()
+			checker.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			require.Equal(t, tc.wantCode, recorder.Code)
+
+			var report Report
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&report))
+			require.Equal(t, tc.wantReport, report)
+
+			// liveness does not depend on the checks
+			recorder = httptest.NewRecorder()
+			checker.LivenessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
+
+func TestGRPCHealth(t *testing.T) {
+	var down bool
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error {
+		if down {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	status := func() grpc_health_v1.HealthCheckResponse_ServingStatus {
+		res, err := checker.GRPCServer().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		return res.Status
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	checker.Run(ctx, time.Minute)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, status())
+
+	down = true
+	checker.Run(ctx, time.Minute)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status())
+
+	down = false
+	checker.Shutdown()
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status())
+	// the status set by the checks is ignored once shutdown begins
+	checker.Run(ctx, time.Minute)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status())
+}
+
+type fakeRow struct {
+	version int64
+	dirty   bool
+	err     error
+}
+
+func (row fakeRow) Scan(dest ...any) error {
+	if row.err != nil {
+		return row.err
+	}
+	*dest[0].(*int64) = row.version
+	*dest[1].(*bool) = row.dirty
+	return nil
+}
+
+type fakeQuerier struct {
+	row fakeRow
+}
+
+func (q fakeQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return q.row
+}
+
+func TestMigrationCheck(t *testing.T) {
+	testCases := []struct {
+		name    string
+		row     fakeRow
+		wantErr bool
+	}{
+		{name: "Current", row: fakeRow{version: 5}},
+		{name: "Newer", row: fakeRow{version: 6}},
+		{name: "Older", row: fakeRow{version: 4}, wantErr: true},
+		{name: "Dirty", row: fakeRow{version: 5, dirty: true}, wantErr: true},
+		{name: "NotMigrated", row: fakeRow{err: pgx.ErrNoRows}, wantErr: true},
+		{name: "QueryFails", row: fakeRow{err: errors.New("connection refused")}, wantErr: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := MigrationCheck(fakeQuerier{row: tc.row}, 5)(context.Background())
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
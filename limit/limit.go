@@ -0,0 +1,123 @@
+package limit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var ErrExceeded = errors.New("transfer limit exceeded")
+
+// Limits caps the amount that can be sent out of an account.
+// A zero value means the limit is not enforced.
+type Limits struct {
+	PerTransfer int64 `json:"per_transfer"`
+	Daily       int64 `json:"daily"`
+	Monthly     int64 `json:"monthly"`
+}
+
+// Defaults holds the limits applied to accounts without their own, keyed by currency.
+type Defaults map[string]Limits
+
+// Usage is the amount already sent out of an account in the current periods.
+type Usage struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// Allowance reports the limits of an account and how much of them is left.
+// A nil remaining value means the limit is not enforced.
+type Allowance struct {
+	AccountID        int64  `json:"account_id"`
+	Limits           Limits `json:"limits"`
+	Usage            Usage  `json:"usage"`
+	DailyRemaining   *int64 `json:"daily_remaining"`
+	MonthlyRemaining *int64 `json:"monthly_remaining"`
+}
+
+// ExceededError describes which limit a transfer would break.
+type ExceededError struct {
+	Limit  string
+	Max    int64
+	Used   int64
+	Amount int64
+}
+
+func (e *ExceededError) Error() string {
+	if e.Limit == "per_transfer" {
+		return fmt.Sprintf("%s: amount %d is above the per transfer limit of %d", ErrExceeded, e.Amount, e.Max)
+	}
+	return fmt.Sprintf("%s: amount %d is above the remaining %s allowance of %d", ErrExceeded, e.Amount, e.Limit, e.Max-e.Used)
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrExceeded
+}
+
+// LoadDefaults reads the default limits per currency from a JSON file.
+func LoadDefaults(path string) (Defaults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read transfer limits: %w", err)
+	}
+
+	var defaults Defaults
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return nil, fmt.Errorf("cannot parse transfer limits: %w", err)
+	}
+
+	for currency, limits := range defaults {
+		if limits.PerTransfer < 0 || limits.Daily < 0 || limits.Monthly < 0 {
+			return nil, fmt.Errorf("%s limits must not be negative", currency)
+		}
+	}
+
+	return defaults, nil
+}
+
+// Check returns an ExceededError if sending amount would break one of the limits.
+func (l Limits) Check(amount int64, usage Usage) error {
+	if l.PerTransfer > 0 && amount > l.PerTransfer {
+		return &ExceededError{Limit: "per_transfer", Max: l.PerTransfer, Amount: amount}
+	}
+	if l.Daily > 0 && usage.Daily+amount > l.Daily {
+		return &ExceededError{Limit: "daily", Max: l.Daily, Used: usage.Daily, Amount: amount}
+	}
+	if l.Monthly > 0 && usage.Monthly+amount > l.Monthly {
+		return &ExceededError{Limit: "monthly", Max: l.Monthly, Used: usage.Monthly, Amount: amount}
+	}
+	return nil
+}
+
+// Allowance returns the limits together with what is left of them.
+func (l Limits) Allowance(accountID int64, usage Usage) Allowance {
+	return Allowance{
+		AccountID:        accountID,
+		Limits:           l,
+		Usage:            usage,
+		DailyRemaining:   remaining(l.Daily, usage.Daily),
+		MonthlyRemaining: remaining(l.Monthly, usage.Monthly),
+	}
+}
+
+func remaining(ceiling int64, used int64) *int64 {
+	if ceiling <= 0 {
+		return nil
+	}
+	left := max(ceiling-used, 0)
+	return &left
+}
+
+// DayStart returns the beginning of the UTC day containing t.
+func DayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// MonthStart returns the beginning of the UTC month containing t.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
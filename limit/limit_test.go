@@ -0,0 +1,99 @@
+package limit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	limits := Limits{PerTransfer: 100, Daily: 300, Monthly: 1000}
+
+	testCases := []struct {
+		name    string
+		amount  int64
+		usage   Usage
+		limit   string
+		allowed bool
+	}{
+		{
+			name:    "OK",
+			amount:  100,
+			usage:   Usage{Daily: 200, Monthly: 900},
+			allowed: true,
+		},
+		{
+			name:   "PerTransfer",
+			amount: 101,
+			limit:  "per_transfer",
+		},
+		{
+			name:   "Daily",
+			amount: 50,
+			usage:  Usage{Daily: 260, Monthly: 260},
+			limit:  "daily",
+		},
+		{
+			name:   "Monthly",
+			amount: 50,
+			usage:  Usage{Daily: 0, Monthly: 960},
+			limit:  "monthly",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := limits.Check(tc.amount, tc.usage)
+			if tc.allowed {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrExceeded)
+			exceeded, ok := err.(*ExceededError)
+			require.True(t, ok)
+			require.Equal(t, tc.limit, exceeded.Limit)
+		})
+	}
+}
+
+func TestCheckUnlimited(t *testing.T) {
+	require.NoError(t, Limits{}.Check(1_000_000, Usage{Daily: 1_000_000, Monthly: 1_000_000}))
+}
+
+func TestAllowance(t *testing.T) {
+	allowance := Limits{Daily: 300}.Allowance(1, Usage{Daily: 350, Monthly: 350})
+
+	require.Equal(t, int64(1), allowance.AccountID)
+	require.NotNil(t, allowance.DailyRemaining)
+	require.Zero(t, *allowance.DailyRemaining)
+	require.Nil(t, allowance.MonthlyRemaining)
+}
+
+func TestPeriodStart(t *testing.T) {
+	now := time.Date(2024, time.February, 15, 13, 45, 0, 0, time.UTC)
+
+	require.Equal(t, time.Date(2024, time.February, 15, 0, 0, 0, 0, time.UTC), DayStart(now))
+	require.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), MonthStart(now))
+}
+
+func TestLoadDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	err := os.WriteFile(path, []byte(`{"USD": {"per_transfer": 100, "daily": 500}}`), 0o600)
+	require.NoError(t, err)
+
+	defaults, err := LoadDefaults(path)
+	require.NoError(t, err)
+	require.Equal(t, Limits{PerTransfer: 100, Daily: 500}, defaults["USD"])
+
+	err = os.WriteFile(path, []byte(`{"USD": {"daily": -1}}`), 0o600)
+	require.NoError(t, err)
+
+	_, err = LoadDefaults(path)
+	require.Error(t, err)
+}
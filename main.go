@@ -2,29 +2,63 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/MElghrbawy/simple_bank/db/migration"
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
 	_ "github.com/MElghrbawy/simple_bank/doc/statik"
+	"github.com/MElghrbawy/simple_bank/event"
+	"github.com/MElghrbawy/simple_bank/fee"
 	"github.com/MElghrbawy/simple_bank/gapi"
+	"github.com/MElghrbawy/simple_bank/health"
+	"github.com/MElghrbawy/simple_bank/limit"
+	"github.com/MElghrbawy/simple_bank/metrics"
 	"github.com/MElghrbawy/simple_bank/pb"
+	"github.com/MElghrbawy/simple_bank/ratelimit"
+	"github.com/MElghrbawy/simple_bank/token"
+	"github.com/MElghrbawy/simple_bank/tracing"
 	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/MElghrbawy/simple_bank/worker"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	_ "github.com/lib/pq"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rakyll/statik/fs"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
+// memoryDriver is the DB_DRIVER value running the server on the in-memory store.
+const memoryDriver = "memory"
+
+// The TASK_QUEUE values selecting where background tasks are queued.
+const (
+	redisTaskQueue  = "redis"
+	memoryTaskQueue = "memory"
+)
+
+// The RATE_LIMIT_BACKEND values selecting where the rate limits are tracked.
+const (
+	redisRateLimitBackend  = "redis"
+	memoryRateLimitBackend = "memory"
+)
+
 func main() {
 
 	config, err := util.LoadConfig(".")
@@ -35,20 +69,228 @@ func main() {
 	if config.Environment == "development" {
 		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 	}
+	// log.Ctx falls back to the global logger for contexts without a request logger
+	zerolog.DefaultContextLogger = &log.Logger
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:     config.TracingExporter,
+		OTLPEndpoint: config.TracingOTLPEndpoint,
+		SampleRatio:  config.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot set up tracing")
+	}
+	defer shutdownTracing(context.Background())
+
+	appMetrics := metrics.New()
+	checker := health.NewChecker(config.HealthCheckTimeout)
+
+	if config.AuditKey == "" {
+		log.Fatal().Msg("AUDIT_KEY must be set to chain the audit log")
+	}
+	storeOpts := []db.StoreOption{
+		db.WithTxObserver(appMetrics.ObserveTx),
+		db.WithTransferObserver(appMetrics.ObserveTransfer),
+		db.WithAuditKey([]byte(config.AuditKey)),
+	}
+	if config.FeeSchedulePath != "" {
+		feeSchedule, err := fee.LoadSchedule(config.FeeSchedulePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot load fee schedule")
+		}
+		storeOpts = append(storeOpts, db.WithFeeSchedule(feeSchedule))
+	}
+	if config.TransferLimitsPath != "" {
+		limitDefaults, err := limit.LoadDefaults(config.TransferLimitsPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot load transfer limits")
+		}
+		storeOpts = append(storeOpts, db.WithTransferLimits(limitDefaults))
+	}
+
+	events := event.NewHub()
+	background := newBackgroundTasks()
+	var pools []*pgxpool.Pool
+
+	var store db.Store
+	if config.DBDriver == memoryDriver {
+		log.Warn().Msg("using the in-memory store, all data is lost on exit")
+		store = db.NewMemStore(storeOpts...)
+	} else {
+		connPool, err := newConnPool(context.Background(), config, config.DBSource)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot connect to db")
+		}
+		pools = append(pools, connPool)
+		if err := appMetrics.RegisterPool("primary", connPool); err != nil {
+			log.Fatal().Err(err).Msg("cannot register db pool metrics")
+		}
+		checker.Add("database", health.PingCheck(connPool))
+		if config.DBReplicaSource != "" {
+			replicaPool, err := newConnPool(context.Background(), config, config.DBReplicaSource)
+			if err != nil {
+				log.Fatal().Err(err).Msg("cannot connect to db replica")
+			}
+			pools = append(pools, replicaPool)
+			if err := appMetrics.RegisterPool("replica", replicaPool); err != nil {
+				log.Fatal().Err(err).Msg("cannot register db replica pool metrics")
+			}
+			checker.Add("database_replica", health.PingCheck(replicaPool))
+			storeOpts = append(storeOpts, db.WithReplica(replicaPool))
+		}
+		store = db.NewStore(connPool, storeOpts...)
+
+		runDBMigrations(config.MIGRATION_URL, config.DBSource)
+		migrationVersion, err := migration.LatestVersion()
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot read the embedded migrations")
+		}
+		checker.Add("migrations", health.MigrationCheck(connPool, migrationVersion))
+
+		background.Go(func(ctx context.Context) {
+			if err := events.Listen(ctx, connPool); err != nil {
+				log.Fatal().Err(err).Msg("cannot listen for account events")
+			}
+		})
+	}
+
+	var taskProcessor worker.TaskProcessor
+
+	switch config.TaskQueue {
+	case redisTaskQueue:
+		redisOpt := asynq.RedisClientOpt{Addr: config.RedisAddress}
+		if err := appMetrics.RegisterQueues(asynq.NewInspector(redisOpt)); err != nil {
+			log.Fatal().Err(err).Msg("cannot register task queue metrics")
+		}
+		checker.Add("redis", health.RedisCheck(redisOpt.MakeRedisClient().(redis.UniversalClient)))
+		taskProcessor = worker.NewRedisTaskProcessor(&redisOpt, store, appMetrics.TaskMiddleware)
+		runTaskQueue(config, background, store, worker.NewRedisTaskDistributor(redisOpt), taskProcessor)
+	case memoryTaskQueue:
+		log.Warn().Msg("using the in-memory task queue, pending tasks are lost on exit")
+		taskQueue := worker.NewMemoryTaskQueue(store, worker.WithTaskMiddleware(appMetrics.TaskMiddleware))
+		taskProcessor = taskQueue
+		runTaskQueue(config, background, store, taskQueue, taskQueue)
+	case "":
+		log.Warn().Msg("no task queue configured, background tasks and webhooks are disabled")
+	default:
+		log.Fatal().Str("task_queue", config.TaskQueue).Msg("unknown task queue")
+	}
+
+	keyring, err := token.LoadKeyring(token.KeySource{
+		Keys:    config.TokenSymmetricKey,
+		File:    config.TokenKeyFile,
+		Dir:     config.TokenKeyDir,
+		Current: config.TokenKeyVersion,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot load token keys")
+	}
+	tokenMaker, err := token.NewPasetoMakerFromKeyring(keyring)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create token maker")
+	}
+
+	// the gRPC and gateway listeners share the server, so tokens issued by one are accepted by the other
+	server, err := gapi.NewServer(config, store, tokenMaker, events)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create server")
+	}
+
+	limiter := newRateLimiter(config)
+
+	go checker.Run(ctx, config.HealthCheckInterval)
+
+	grpcServer := runGrpcServer(config, server, checker, limiter, appMetrics)
+	gatewayServer := runGatewayServer(ctx, config, server, checker, limiter, appMetrics)
+
+	<-ctx.Done()
+	// a second signal kills the process without waiting for the graceful shutdown
+	stop()
+	shutdownServers(config, checker, grpcServer, gatewayServer)
+	stopBackground(background, taskProcessor, pools)
+}
+
+// backgroundTasks runs the work of the server besides serving requests, until it is stopped.
+type backgroundTasks struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newBackgroundTasks() *backgroundTasks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundTasks{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in the background, fn must return once ctx is done.
+func (b *backgroundTasks) Go(fn func(ctx context.Context)) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		fn(b.ctx)
+	}()
+}
 
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+// Stop cancels the background tasks and waits for them to return.
+func (b *backgroundTasks) Stop() {
+	b.cancel()
+	b.wg.Wait()
+}
+
+// newConnPool creates a connection pool to dbSource, tuned by the DB_* settings left to
+// the pgxpool defaults when zero.
+func newConnPool(ctx context.Context, config util.Config, dbSource string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(dbSource)
 	if err != nil {
-		log.Fatal().Err(err).Msg("cannot connect to db")
+		return nil, err
 	}
-	store := db.NewStore(conn)
 
-	runDBMigrations(config.MIGRATION_URL, config.DBSource)
+	if config.DBMaxConns > 0 {
+		poolConfig.MaxConns = config.DBMaxConns
+	}
+	if config.DBMinConns > 0 {
+		poolConfig.MinConns = config.DBMinConns
+	}
+	if config.DBMaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.DBMaxConnLifetime
+	}
+	if config.DBMaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.DBMaxConnIdleTime
+	}
+	if config.DBHealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.DBHealthCheckPeriod
+	}
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
 
-	go runGatewayServer(config, store)
-	runGrpcServer(config, store)
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
 
+// newRateLimiter creates the limiter of the APIs. Without RATE_LIMITS_PATH, the default rules apply.
+func newRateLimiter(config util.Config) *ratelimit.Limiter {
+	rules := ratelimit.DefaultRules()
+	if config.RateLimitsPath != "" {
+		var err error
+		rules, err = ratelimit.LoadRules(config.RateLimitsPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot load rate limits")
+		}
+	}
+
+	switch config.RateLimitBackend {
+	case redisRateLimitBackend:
+		return ratelimit.NewLimiter(rules, ratelimit.NewRedisBackend(redis.NewClient(&redis.Options{Addr: config.RedisAddress})))
+	case memoryRateLimitBackend, "":
+		return ratelimit.NewLimiter(rules, ratelimit.NewMemoryBackend())
+	default:
+		log.Fatal().Str("rate_limit_backend", config.RateLimitBackend).Msg("unknown rate limit backend")
+		return nil
+	}
 }
 
+// runDBMigrations migrates the database up.
 func runDBMigrations(migrationURL string, dbSource string) {
 	migration, err := migrate.New(migrationURL, dbSource)
 	if err != nil {
@@ -60,19 +302,54 @@ func runDBMigrations(migrationURL string, dbSource string) {
 		log.Fatal().Err(err).Msg("cannot migrate db:")
 	}
 
-	log.Info().Msg("migration completed")
+	version, _, err := migration.Version()
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot read migration version")
+	}
+
+	log.Info().Uint("version", version).Msg("migration completed")
+}
+
+func runTaskQueue(config util.Config, background *backgroundTasks, store db.Store, taskDistributor worker.TaskDistributor, taskProcessor worker.TaskProcessor) {
+	go runTaskProcessor(taskProcessor)
+	background.Go(func(ctx context.Context) {
+		worker.RunOutboxRelay(ctx, store, taskDistributor, config.OutboxRelayInterval)
+	})
+	background.Go(func(ctx context.Context) {
+		worker.RunWebhookRelay(ctx, store, taskDistributor, config.OutboxRelayInterval)
+	})
+	background.Go(func(ctx context.Context) {
+		worker.RunPendingTransferExpiry(ctx, store, config.PendingSweepInterval)
+	})
+	background.Go(func(ctx context.Context) {
+		worker.RunAuditChainer(ctx, store, config.AuditChainInterval)
+	})
 }
 
-func runGrpcServer(config util.Config, store db.Store) {
-	server, err := gapi.NewServer(config, store)
+func runTaskProcessor(taskProcessor worker.TaskProcessor) {
+	log.Info().Msg("start task processor")
+	err := taskProcessor.Start()
 	if err != nil {
-		log.Fatal().Err(err).Msg("cannot create server")
+		log.Fatal().Err(err).Msg("failed to start task processor")
 	}
+}
 
-	grpcLogger := grpc.UnaryInterceptor(gapi.GrpcLogger)
-	grpcServer := grpc.NewServer(grpcLogger)
+// runGrpcServer starts serving gRPC in the background, and returns the server to shut down.
+func runGrpcServer(config util.Config, server *gapi.Server, checker *health.Checker, limiter *ratelimit.Limiter, appMetrics *metrics.Metrics) *grpc.Server {
+	grpcInterceptors := grpc.ChainUnaryInterceptor(
+		gapi.GrpcRequestID,
+		appMetrics.UnaryInterceptor,
+		gapi.GrpcLogger,
+		limiter.UnaryInterceptor(server.GrpcRateLimitKey),
+		gapi.GrpcReadYourWrites,
+	)
+	grpcStreamInterceptors := grpc.ChainStreamInterceptor(
+		limiter.StreamInterceptor(server.GrpcRateLimitKey),
+	)
+	grpcServer := grpc.NewServer(grpcInterceptors, grpcStreamInterceptors, grpc.StatsHandler(otelgrpc.NewServerHandler()))
 
 	pb.RegisterSimpleBankServer(grpcServer, server)
+	grpc_health_v1.RegisterHealthServer(grpcServer, checker.GRPCServer())
 	reflection.Register(grpcServer)
 	listener, err := net.Listen("tcp", config.GRPCServerAddress)
 
@@ -82,19 +359,16 @@ func runGrpcServer(config util.Config, store db.Store) {
 
 	log.Info().Msgf("start gRPC server on %s", listener.Addr().String())
 
-	err = grpcServer.Serve(listener)
-
-	if err != nil {
-		log.Fatal().Err(err).Msg("cannot start gRPC server")
-	}
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatal().Err(err).Msg("cannot start gRPC server")
+		}
+	}()
+	return grpcServer
 }
 
-func runGatewayServer(config util.Config, store db.Store) {
-	server, err := gapi.NewServer(config, store)
-	if err != nil {
-		log.Fatal().Err(err).Msg("cannot create server")
-	}
-
+// runGatewayServer starts serving the HTTP gateway in the background, and returns the server to shut down.
+func runGatewayServer(ctx context.Context, config util.Config, server *gapi.Server, checker *health.Checker, limiter *ratelimit.Limiter, appMetrics *metrics.Metrics) *http.Server {
 	jsonOption := runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
 		MarshalOptions: protojson.MarshalOptions{
 			UseProtoNames: true,
@@ -104,18 +378,19 @@ func runGatewayServer(config util.Config, store db.Store) {
 		},
 	})
 
-	grpcMux := runtime.NewServeMux(jsonOption)
-	ctx, cancel := context.WithCancel(context.Background())
-
-	err = pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
-	defer cancel()
+	grpcMux := runtime.NewServeMux(jsonOption, runtime.WithMetadata(metrics.GatewayRoute), runtime.WithMetadata(tracing.GatewayRoute))
 
+	err := pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot register gateway server")
 	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/", grpcMux)
+	mux.Handle("GET /v1/accounts/{id}/watch", metrics.Route("/v1/accounts/{id}/watch", otelhttp.WithRouteTag("/v1/accounts/{id}/watch", http.HandlerFunc(server.WatchAccountSSE))))
+	mux.Handle("GET /metrics", metrics.Route("/metrics", appMetrics.Handler()))
+	mux.Handle("GET /healthz", metrics.Route("/healthz", checker.LivenessHandler()))
+	mux.Handle("GET /readyz", metrics.Route("/readyz", checker.ReadinessHandler()))
 
 	// fs := http.FileServer(http.Dir("doc/swagger"))
 	// mux.Handle("/swagger/", http.StripPrefix("/swagger", fs))
@@ -126,7 +401,7 @@ func runGatewayServer(config util.Config, store db.Store) {
 	}
 
 	swaggerHandler := http.StripPrefix("/swagger/", http.FileServer(statikFs))
-	mux.Handle("/swagger/", swaggerHandler)
+	mux.Handle("/swagger/", metrics.Route("/swagger/", swaggerHandler))
 
 	listener, err := net.Listen("tcp", config.HTTPServerAddress)
 	if err != nil {
@@ -134,12 +409,57 @@ func runGatewayServer(config util.Config, store db.Store) {
 	}
 
 	log.Info().Msgf("start HTTP server on %s", listener.Addr().String())
-	handler := gapi.HttpLogger(mux)
+	rateLimit := limiter.HTTPMiddleware(gapi.GatewayMethods, server.HttpRateLimitKey)
+	handler := otelhttp.NewHandler(appMetrics.HTTPMiddleware(gapi.HttpRequestID(gapi.HttpLogger(rateLimit(gapi.HttpReadYourWrites(mux))))), "gateway")
+	httpServer := &http.Server{Handler: handler}
+
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Err(err).Msg("cannot start HTTP server:")
+		}
+	}()
+	return httpServer
+}
 
-	err = http.Serve(listener, handler)
+// shutdownServers reports the server not ready, keeps serving for SHUTDOWN_DRAIN_DELAY so the
+// orchestrator stops routing new requests to it, then waits up to SHUTDOWN_TIMEOUT for the
+// requests in flight before closing the remaining connections.
+func shutdownServers(config util.Config, checker *health.Checker, grpcServer *grpc.Server, httpServer *http.Server) {
+	log.Info().Msg("shutting down")
+	checker.Shutdown()
+	time.Sleep(config.ShutdownDrainDelay)
 
-	if err != nil {
-		log.Fatal().Err(err).Msg("cannot start gRPC server:")
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("cannot shut down HTTP server gracefully")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Error().Msg("cannot shut down gRPC server gracefully")
+		grpcServer.Stop()
 	}
 
+	log.Info().Msg("servers stopped")
+}
+
+// stopBackground stops the relays and the event listener, lets the task processor finish the
+// tasks it is running, then closes the database pools they all use.
+func stopBackground(background *backgroundTasks, taskProcessor worker.TaskProcessor, pools []*pgxpool.Pool) {
+	background.Stop()
+	if taskProcessor != nil {
+		taskProcessor.Shutdown()
+	}
+	for _, pool := range pools {
+		pool.Close()
+	}
+	log.Info().Msg("background tasks stopped")
 }
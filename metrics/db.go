@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"math"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// outcomeOK labels what ended without an error. Errors are labelled with their apperr.Kind.
+const outcomeOK = "ok"
+
+func outcome(err error) string {
+	if err == nil {
+		return outcomeOK
+	}
+	return string(apperr.KindOf(err))
+}
+
+// ObserveTx records a finished transaction. It is meant for db.WithTxObserver.
+func (m *Metrics) ObserveTx(stats db.TxStats) {
+	outcome := outcome(stats.Err)
+	m.txs.WithLabelValues(outcome).Inc()
+	m.txDuration.WithLabelValues(outcome).Observe(stats.Duration.Seconds())
+	m.txAttempts.Observe(float64(stats.Attempts))
+}
+
+// ObserveTransfer records a finished transfer. It is meant for db.WithTransferObserver.
+func (m *Metrics) ObserveTransfer(stats db.TransferStats) {
+	currency := stats.Amount.Currency.Code
+	outcome := outcome(stats.Err)
+	m.transfers.WithLabelValues(currency, outcome).Inc()
+	m.transferDuration.WithLabelValues(currency, outcome).Observe(stats.Duration.Seconds())
+	if stats.Err == nil {
+		amount := float64(stats.Amount.Units) / math.Pow10(stats.Amount.Currency.Exponent)
+		m.transferAmount.WithLabelValues(currency).Observe(amount)
+	}
+}
+
+// RegisterPool exposes the statistics of a connection pool, labelled with name.
+func (m *Metrics) RegisterPool(name string, pool *pgxpool.Pool) error {
+	return m.registry.Register(newPoolCollector(name, pool))
+}
+
+// poolCollector reads the statistics of a connection pool when the metrics are scraped.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	totalConns      *prometheus.Desc
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquires        *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+func newPoolCollector(name string, pool *pgxpool.Pool) *poolCollector {
+	desc := func(metric string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "db_pool", metric),
+			help, nil, prometheus.Labels{"pool": name},
+		)
+	}
+
+	return &poolCollector{
+		pool:            pool,
+		totalConns:      desc("connections", "Number of open connections of the pool."),
+		acquiredConns:   desc("acquired_connections", "Number of connections of the pool in use."),
+		idleConns:       desc("idle_connections", "Number of idle connections of the pool."),
+		maxConns:        desc("max_connections", "Maximum number of connections of the pool."),
+		acquires:        desc("acquires_total", "Number of connections acquired from the pool."),
+		acquireDuration: desc("acquire_duration_seconds_total", "Time spent acquiring connections from the pool."),
+		emptyAcquires:   desc("empty_acquires_total", "Number of acquires that waited for a connection as none was idle."),
+		canceledAcquire: desc("canceled_acquires_total", "Number of acquires canceled by their context."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.totalConns
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.maxConns
+	ch <- c.acquires
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquires
+	ch <- c.canceledAcquire
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryInterceptor counts the gRPC requests and their errors, and measures how long they take.
+func (m *Metrics) UnaryInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	startTime := time.Now()
+
+	result, err := handler(ctx, req)
+
+	m.grpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(startTime).Seconds())
+	m.grpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	return result, err
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+)
+
+// unmatchedRoute labels the requests no route was set for, to keep raw paths out of the labels.
+const unmatchedRoute = "unmatched"
+
+type routeKey struct{}
+
+// route is filled in by the handler of a request with the pattern it matched.
+type route struct {
+	pattern string
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (rec *statusRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// HTTPMiddleware counts the HTTP requests and their errors, and measures how long they take.
+// Requests are labelled with the route set by Route or GatewayRoute.
+func (m *Metrics) HTTPMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		startTime := time.Now()
+		matched := &route{pattern: unmatchedRoute}
+		rec := &statusRecorder{ResponseWriter: res, statusCode: http.StatusOK}
+
+		handler.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), routeKey{}, matched)))
+
+		m.httpDuration.WithLabelValues(req.Method, matched.pattern).Observe(time.Since(startTime).Seconds())
+		m.httpRequests.WithLabelValues(req.Method, matched.pattern, strconv.Itoa(rec.statusCode)).Inc()
+	})
+}
+
+// Route labels the requests served by handler with pattern.
+func Route(pattern string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		setRoute(req.Context(), pattern)
+		handler.ServeHTTP(res, req)
+	})
+}
+
+// GatewayRoute labels the requests served by the gateway with the path pattern of their RPC.
+// It is meant for runtime.WithMetadata, which calls it once the pattern is known, and adds no metadata.
+func GatewayRoute(ctx context.Context, req *http.Request) metadata.MD {
+	if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+		setRoute(req.Context(), pattern)
+	}
+	return nil
+}
+
+func setRoute(ctx context.Context, pattern string) {
+	if matched, ok := ctx.Value(routeKey{}).(*route); ok {
+		matched.pattern = pattern
+	}
+}
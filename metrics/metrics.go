@@ -0,0 +1,134 @@
+// Package metrics exposes Prometheus metrics of the servers, the store and the worker.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the name of every metric.
+const namespace = "simplebank"
+
+// Metrics holds the collectors of the application, registered on a registry of their own.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	grpcRequests     *prometheus.CounterVec
+	grpcDuration     *prometheus.HistogramVec
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	txs              *prometheus.CounterVec
+	txDuration       *prometheus.HistogramVec
+	txAttempts       prometheus.Histogram
+	transfers        *prometheus.CounterVec
+	transferDuration *prometheus.HistogramVec
+	transferAmount   *prometheus.HistogramVec
+	tasks            *prometheus.CounterVec
+	taskDuration     *prometheus.HistogramVec
+}
+
+// New creates the metrics, along with the Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Number of gRPC requests handled, by method and status code.",
+		}, []string{"method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to handle gRPC requests, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests handled, by method, route and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		txs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "transactions_total",
+			Help:      "Number of database transactions, by outcome.",
+		}, []string{"outcome"}),
+		txDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "transaction_duration_seconds",
+			Help:      "Time taken by database transactions, retries included, by outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"outcome"}),
+		txAttempts: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "transaction_attempts",
+			Help:      "Number of times database transactions ran before they were over.",
+			Buckets:   []float64{1, 2, 3, 5, 10},
+		}),
+		transfers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "transfer",
+			Name:      "total",
+			Help:      "Number of transfers, by currency and outcome.",
+		}, []string{"currency", "outcome"}),
+		transferDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "transfer",
+			Name:      "duration_seconds",
+			Help:      "Time taken by transfers, by currency and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"currency", "outcome"}),
+		transferAmount: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "transfer",
+			Name:      "amount",
+			Help:      "Amount of the successful transfers in major units of their currency, by currency.",
+			Buckets:   prometheus.ExponentialBuckets(1, 10, 8),
+		}, []string{"currency"}),
+		tasks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "worker",
+			Name:      "tasks_processed_total",
+			Help:      "Number of times background tasks were processed, by type and outcome.",
+		}, []string{"type", "outcome"}),
+		taskDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "worker",
+			Name:      "task_duration_seconds",
+			Help:      "Time taken to process background tasks, by type.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"type"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.grpcRequests, m.grpcDuration,
+		m.httpRequests, m.httpDuration,
+		m.txs, m.txDuration, m.txAttempts,
+		m.transfers, m.transferDuration, m.transferAmount,
+		m.tasks, m.taskDuration,
+	)
+	return m
+}
+
+// Handler serves the metrics to Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	m := New()
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/GetAccount"}
+
+	_, err := m.UnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+	_, err = m.UnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "account not found")
+	})
+	require.Error(t, err)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "OK")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "NotFound")))
+	require.Equal(t, 1, testutil.CollectAndCount(m.grpcDuration))
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	m := New()
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /v1/accounts/{id}/watch", Route("/v1/accounts/{id}/watch", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})))
+	gatewayMux := runtime.NewServeMux(runtime.WithMetadata(GatewayRoute))
+	mux.HandleFunc("GET /v1/accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, err := runtime.AnnotateIncomingContext(r.Context(), gatewayMux, r, "/pb.SimpleBank/GetAccount", runtime.WithHTTPPathPattern("/v1/accounts/{id}"))
+		require.NoError(t, err)
+	})
+	handler := m.HTTPMiddleware(mux)
+
+	for _, path := range []string{"/v1/accounts/1/watch", "/v1/accounts/2/watch", "/v1/accounts/1", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/v1/accounts/{id}/watch", "403")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/v1/accounts/{id}", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+}
+
+func TestObserveTransfer(t *testing.T) {
+	m := New()
+
+	m.ObserveTransfer(db.TransferStats{Amount: money.MustNew(1234, util.USD), Duration: time.Millisecond})
+	m.ObserveTransfer(db.TransferStats{Amount: money.MustNew(10, util.USD), Err: db.ErrAccountFrozen})
+	m.ObserveTransfer(db.TransferStats{Amount: money.MustNew(10, util.EUR), Err: errors.New("connection reset")})
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.transfers.WithLabelValues(util.USD, outcomeOK)))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.transfers.WithLabelValues(util.USD, string(apperr.FailedPrecondition))))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.transfers.WithLabelValues(util.EUR, string(apperr.Internal))))
+	// only the successful transfer is in the amount histogram
+	require.Equal(t, 1, testutil.CollectAndCount(m.transferAmount))
+}
+
+func TestTaskMiddleware(t *testing.T) {
+	m := New()
+
+	errs := []error{nil, errors.New("smtp unavailable"), asynq.SkipRetry}
+	for _, err := range errs {
+		handler := m.TaskMiddleware(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			return err
+		}))
+		require.Equal(t, err, handler.ProcessTask(context.Background(), asynq.NewTask("task:send_verify_email", nil)))
+	}
+
+	for _, outcome := range []string{taskSucceeded, taskFailed, taskSkipped} {
+		require.Equal(t, 1.0, testutil.ToFloat64(m.tasks.WithLabelValues("task:send_verify_email", outcome)))
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.ObserveTx(db.TxStats{Attempts: 2, Duration: time.Millisecond})
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `simplebank_db_transactions_total{outcome="ok"} 1`)
+	require.Contains(t, recorder.Body.String(), "go_goroutines")
+}
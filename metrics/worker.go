@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The outcomes of processing a task.
+const (
+	taskSucceeded = "succeeded"
+	taskFailed    = "failed"
+	// taskSkipped means the task failed and asked not to be retried.
+	taskSkipped = "skipped"
+)
+
+// TaskMiddleware counts the processed tasks by outcome, and measures how long they take.
+func (m *Metrics) TaskMiddleware(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		startTime := time.Now()
+
+		err := handler.ProcessTask(ctx, task)
+
+		outcome := taskSucceeded
+		switch {
+		case errors.Is(err, asynq.SkipRetry):
+			outcome = taskSkipped
+		case err != nil:
+			outcome = taskFailed
+		}
+		m.taskDuration.WithLabelValues(task.Type()).Observe(time.Since(startTime).Seconds())
+		m.tasks.WithLabelValues(task.Type(), outcome).Inc()
+		return err
+	})
+}
+
+// RegisterQueues exposes the depth of the asynq queues, read from Redis through inspector.
+func (m *Metrics) RegisterQueues(inspector *asynq.Inspector) error {
+	return m.registry.Register(newQueueCollector(inspector))
+}
+
+// queueCollector reads the state of the asynq queues when the metrics are scraped.
+type queueCollector struct {
+	inspector *asynq.Inspector
+
+	tasks   *prometheus.Desc
+	latency *prometheus.Desc
+}
+
+func newQueueCollector(inspector *asynq.Inspector) *queueCollector {
+	return &queueCollector{
+		inspector: inspector,
+		tasks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "worker", "queue_tasks"),
+			"Number of tasks in the queues, by queue and state.",
+			[]string{"queue", "state"}, nil,
+		),
+		latency: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "worker", "queue_latency_seconds"),
+			"Time the oldest pending task of the queues has been waiting, by queue.",
+			[]string{"queue"}, nil,
+		),
+	}
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.tasks
+	ch <- c.latency
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	queues, err := c.inspector.Queues()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.tasks, err)
+		return
+	}
+
+	for _, queue := range queues {
+		info, err := c.inspector.GetQueueInfo(queue)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(c.tasks, err)
+			continue
+		}
+
+		for state, count := range map[string]int{
+			"pending":   info.Pending,
+			"active":    info.Active,
+			"scheduled": info.Scheduled,
+			"retry":     info.Retry,
+			"archived":  info.Archived,
+		} {
+			ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.GaugeValue, float64(count), queue, state)
+		}
+		ch <- prometheus.MustNewConstMetric(c.latency, prometheus.GaugeValue, info.Latency.Seconds(), queue)
+	}
+}
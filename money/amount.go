@@ -0,0 +1,142 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidAmount       = errors.New("invalid amount")
+)
+
+// Amount is an exact amount of money, stored as an integer number of minor units of its currency.
+type Amount struct {
+	Units    int64
+	Currency Currency
+}
+
+// New returns the amount made of units minor units of the currency.
+func New(units int64, code string) (Amount, error) {
+	currency, ok := LookupCurrency(code)
+	if !ok {
+		return Amount{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+	return Amount{Units: units, Currency: currency}, nil
+}
+
+// MustNew is like New but panics if the currency is not supported.
+func MustNew(units int64, code string) Amount {
+	amount, err := New(units, code)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// Parse reads a decimal amount such as "12.34" in the given currency.
+// It refuses more decimal places than the minor unit of the currency allows instead of rounding.
+func Parse(value string, code string) (Amount, error) {
+	currency, ok := LookupCurrency(code)
+	if !ok {
+		return Amount{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+
+	s := strings.TrimSpace(value)
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if whole == "" || !isDigits(whole) || (hasPoint && (fraction == "" || !isDigits(fraction))) {
+		return Amount{}, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidAmount, value)
+	}
+
+	if len(fraction) > currency.Exponent {
+		return Amount{}, fmt.Errorf("%w: %s allows at most %d decimal places", ErrInvalidAmount, currency.Code, currency.Exponent)
+	}
+	fraction += strings.Repeat("0", currency.Exponent-len(fraction))
+
+	units, err := strconv.ParseInt(sign+whole+fraction, 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, value)
+	}
+
+	return Amount{Units: units, Currency: currency}, nil
+}
+
+// String formats the amount as a decimal number in major units, e.g. "12.34".
+func (a Amount) String() string {
+	sign := ""
+	units := strconv.FormatInt(a.Units, 10)
+	if a.Units < 0 {
+		sign, units = "-", units[1:]
+	}
+
+	exponent := a.Currency.Exponent
+	if exponent == 0 {
+		return sign + units
+	}
+
+	if len(units) <= exponent {
+		units = strings.Repeat("0", exponent-len(units)+1) + units
+	}
+	point := len(units) - exponent
+	return sign + units[:point] + "." + units[point:]
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (a Amount) IsPositive() bool {
+	return a.Units > 0
+}
+
+type amountJSON struct {
+	Value    json.RawMessage `json:"value"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as {"value": "12.34", "currency": "USD"},
+// keeping the value a string so clients never parse it as a float.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	value, err := json.Marshal(a.String())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(amountJSON{Value: value, Currency: a.Currency.Code})
+}
+
+// UnmarshalJSON decodes an amount whose value is either a decimal string or a JSON number.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var raw amountJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	value := string(raw.Value)
+	if strings.HasPrefix(value, `"`) {
+		if err := json.Unmarshal(raw.Value, &value); err != nil {
+			return err
+		}
+	}
+
+	amount, err := Parse(value, raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		currency string
+		units    int64
+		err      error
+	}{
+		{name: "Cents", value: "12.34", currency: "USD", units: 1234},
+		{name: "OneDecimal", value: "12.3", currency: "EUR", units: 1230},
+		{name: "Whole", value: "12", currency: "USD", units: 1200},
+		{name: "Negative", value: "-0.05", currency: "CAD", units: -5},
+		{name: "ZeroExponent", value: "1500", currency: "JPY", units: 1500},
+		{name: "ThreeDecimals", value: "1.005", currency: "KWD", units: 1005},
+		{name: "TooPrecise", value: "12.345", currency: "USD", err: ErrInvalidAmount},
+		{name: "FractionOfYen", value: "1.5", currency: "JPY", err: ErrInvalidAmount},
+		{name: "NotANumber", value: "12,34", currency: "USD", err: ErrInvalidAmount},
+		{name: "Exponent", value: "1e3", currency: "USD", err: ErrInvalidAmount},
+		{name: "TrailingPoint", value: "12.", currency: "USD", err: ErrInvalidAmount},
+		{name: "Empty", value: "", currency: "USD", err: ErrInvalidAmount},
+		{name: "Overflow", value: "92233720368547758.08", currency: "USD", err: ErrInvalidAmount},
+		{name: "UnknownCurrency", value: "1", currency: "XYZ", err: ErrUnsupportedCurrency},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			amount, err := Parse(tc.value, tc.currency)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.units, amount.Units)
+			require.Equal(t, tc.currency, amount.Currency.Code)
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	require.Equal(t, "12.34", MustNew(1234, "USD").String())
+	require.Equal(t, "0.05", MustNew(5, "USD").String())
+	require.Equal(t, "-0.05", MustNew(-5, "USD").String())
+	require.Equal(t, "1500", MustNew(1500, "JPY").String())
+	require.Equal(t, "1.005", MustNew(1005, "KWD").String())
+	require.Equal(t, "0.000", MustNew(0, "KWD").String())
+}
+
+func TestJSON(t *testing.T) {
+	amount := MustNew(1234, "USD")
+
+	data, err := json.Marshal(amount)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"value": "12.34", "currency": "USD"}`, string(data))
+
+	var got Amount
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, amount, got)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"value": 12.34, "currency": "USD"}`), &got))
+	require.Equal(t, amount, got)
+
+	require.Error(t, json.Unmarshal([]byte(`{"value": 12.345, "currency": "USD"}`), &got))
+}
+
+func TestLookupCurrency(t *testing.T) {
+	currency, ok := LookupCurrency("JPY")
+	require.True(t, ok)
+	require.Equal(t, 0, currency.Exponent)
+
+	require.True(t, IsSupportedCurrency("USD"))
+	require.False(t, IsSupportedCurrency("usd"))
+	require.False(t, IsSupportedCurrency("XYZ"))
+}
@@ -0,0 +1,53 @@
+package money
+
+// Currency is an ISO 4217 currency with the number of digits after the decimal separator of its minor unit.
+type Currency struct {
+	Code     string `json:"code"`
+	Exponent int    `json:"exponent"`
+}
+
+// currencies holds the active ISO 4217 currencies keyed by code.
+var currencies = map[string]Currency{}
+
+func init() {
+	for exponent, codes := range map[int][]string{
+		0: {
+			"BIF", "CLP", "DJF", "GNF", "ISK", "JPY", "KMF", "KRW", "PYG",
+			"RWF", "UGX", "UYI", "VND", "VUV", "XAF", "XOF", "XPF",
+		},
+		2: {
+			"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN",
+			"BAM", "BBD", "BDT", "BGN", "BMD", "BND", "BOB", "BRL", "BSD", "BTN",
+			"BWP", "BYN", "BZD", "CAD", "CDF", "CHF", "CNY", "COP", "CRC", "CUP",
+			"CVE", "CZK", "DKK", "DOP", "DZD", "EGP", "ERN", "ETB", "EUR", "FJD",
+			"FKP", "GBP", "GEL", "GHS", "GIP", "GMD", "GTQ", "GYD", "HKD", "HNL",
+			"HTG", "HUF", "IDR", "ILS", "INR", "IRR", "JMD", "KES", "KGS", "KHR",
+			"KPW", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD", "LSL", "MAD", "MDL",
+			"MGA", "MKD", "MMK", "MNT", "MOP", "MRU", "MUR", "MVR", "MWK", "MXN",
+			"MYR", "MZN", "NAD", "NGN", "NIO", "NOK", "NPR", "NZD", "PAB", "PEN",
+			"PGK", "PHP", "PKR", "PLN", "QAR", "RON", "RSD", "RUB", "SAR", "SBD",
+			"SCR", "SDG", "SEK", "SGD", "SHP", "SLE", "SOS", "SRD", "SSP", "STN",
+			"SVC", "SYP", "SZL", "THB", "TJS", "TMT", "TOP", "TRY", "TTD", "TWD",
+			"TZS", "UAH", "USD", "UYU", "UZS", "VES", "WST", "XCD", "YER", "ZAR",
+			"ZMW", "ZWL",
+		},
+		3: {"BHD", "IQD", "JOD", "KWD", "LYD", "OMR", "TND"},
+		4: {"CLF", "UYW"},
+	} {
+		for _, code := range codes {
+			currencies[code] = Currency{Code: code, Exponent: exponent}
+		}
+	}
+}
+
+// LookupCurrency returns the ISO 4217 currency with the given code.
+func LookupCurrency(code string) (Currency, bool) {
+	currency, ok := currencies[code]
+	return currency, ok
+}
+
+// IsSupportedCurrency checks if the code is an active ISO 4217 currency.
+func IsSupportedCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
@@ -0,0 +1,230 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v4.25.3
+// source: rpc_approve_pending_transfer.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ApprovePendingTransferRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *ApprovePendingTransferRequest) Reset() {
+	*x = ApprovePendingTransferRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_approve_pending_transfer_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ApprovePendingTransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovePendingTransferRequest) ProtoMessage() {}
+
+func (x *ApprovePendingTransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_approve_pending_transfer_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovePendingTransferRequest.ProtoReflect.Descriptor instead.
+func (*ApprovePendingTransferRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_approve_pending_transfer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ApprovePendingTransferRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ApprovePendingTransferResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PendingTransfer *PendingTransfer `protobuf:"bytes,1,opt,name=pending_transfer,json=pendingTransfer,proto3" json:"pending_transfer,omitempty"`
+	Transfer        *Transfer        `protobuf:"bytes,2,opt,name=transfer,proto3" json:"transfer,omitempty"`
+}
+
+func (x *ApprovePendingTransferResponse) Reset() {
+	*x = ApprovePendingTransferResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_approve_pending_transfer_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ApprovePendingTransferResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovePendingTransferResponse) ProtoMessage() {}
+
+func (x *ApprovePendingTransferResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_approve_pending_transfer_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovePendingTransferResponse.ProtoReflect.Descriptor instead.
+func (*ApprovePendingTransferResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_approve_pending_transfer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ApprovePendingTransferResponse) GetPendingTransfer() *PendingTransfer {
+	if x != nil {
+		return x.PendingTransfer
+	}
+	return nil
+}
+
+func (x *ApprovePendingTransferResponse) GetTransfer() *Transfer {
+	if x != nil {
+		return x.Transfer
+	}
+	return nil
+}
+
+var File_rpc_approve_pending_transfer_proto protoreflect.FileDescriptor
+
+var file_rpc_approve_pending_transfer_proto_rawDesc = []byte{
+	0x0a, 0x22, 0x72, 0x70, 0x63, 0x5f, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x5f, 0x70, 0x65,
+	0x6e, 0x64, 0x69, 0x6e, 0x67, 0x5f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x1a, 0x16, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e,
+	0x67, 0x5f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x1a, 0x0e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x22, 0x2f, 0x0a, 0x1d, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x50, 0x65, 0x6e, 0x64, 0x69,
+	0x6e, 0x67, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69,
+	0x64, 0x22, 0x8a, 0x01, 0x0a, 0x1e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x50, 0x65, 0x6e,
+	0x64, 0x69, 0x6e, 0x67, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3e, 0x0a, 0x10, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x5f,
+	0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13,
+	0x2e, 0x70, 0x62, 0x2e, 0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x66, 0x65, 0x72, 0x52, 0x0f, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x54, 0x72, 0x61, 0x6e,
+	0x73, 0x66, 0x65, 0x72, 0x12, 0x28, 0x0a, 0x08, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x70, 0x62, 0x2e, 0x54, 0x72, 0x61, 0x6e,
+	0x73, 0x66, 0x65, 0x72, 0x52, 0x08, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x42, 0x26,
+	0x5a, 0x24, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x4d, 0x45, 0x6c,
+	0x67, 0x68, 0x72, 0x62, 0x61, 0x77, 0x79, 0x2f, 0x73, 0x69, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x62,
+	0x61, 0x6e, 0x6b, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_rpc_approve_pending_transfer_proto_rawDescOnce sync.Once
+	file_rpc_approve_pending_transfer_proto_rawDescData = file_rpc_approve_pending_transfer_proto_rawDesc
+)
+
+func file_rpc_approve_pending_transfer_proto_rawDescGZIP() []byte {
+	file_rpc_approve_pending_transfer_proto_rawDescOnce.Do(func() {
+		file_rpc_approve_pending_transfer_proto_rawDescData = protoimpl.X.CompressGZIP(file_rpc_approve_pending_transfer_proto_rawDescData)
+	})
+	return file_rpc_approve_pending_transfer_proto_rawDescData
+}
+
+var file_rpc_approve_pending_transfer_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_rpc_approve_pending_transfer_proto_goTypes = []interface{}{
+	(*ApprovePendingTransferRequest)(nil),  // 0: pb.ApprovePendingTransferRequest
+	(*ApprovePendingTransferResponse)(nil), // 1: pb.ApprovePendingTransferResponse
+	(*PendingTransfer)(nil),                // 2: pb.PendingTransfer
+	(*Transfer)(nil),                       // 3: pb.Transfer
+}
+var file_rpc_approve_pending_transfer_proto_depIdxs = []int32{
+	2, // 0: pb.ApprovePendingTransferResponse.pending_transfer:type_name -> pb.PendingTransfer
+	3, // 1: pb.ApprovePendingTransferResponse.transfer:type_name -> pb.Transfer
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_rpc_approve_pending_transfer_proto_init() }
+func file_rpc_approve_pending_transfer_proto_init() {
+	if File_rpc_approve_pending_transfer_proto != nil {
+		return
+	}
+	file_pending_transfer_proto_init()
+	file_transfer_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_rpc_approve_pending_transfer_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ApprovePendingTransferRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_approve_pending_transfer_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ApprovePendingTransferResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_rpc_approve_pending_transfer_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_rpc_approve_pending_transfer_proto_goTypes,
+		DependencyIndexes: file_rpc_approve_pending_transfer_proto_depIdxs,
+		MessageInfos:      file_rpc_approve_pending_transfer_proto_msgTypes,
+	}.Build()
+	File_rpc_approve_pending_transfer_proto = out.File
+	file_rpc_approve_pending_transfer_proto_rawDesc = nil
+	file_rpc_approve_pending_transfer_proto_goTypes = nil
+	file_rpc_approve_pending_transfer_proto_depIdxs = nil
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"path"
+	"strconv"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// retryAfterMetadataKey returns how many seconds a rate limited client has to wait, as the Retry-After header does.
+const retryAfterMetadataKey = "retry-after"
+
+// UnaryInterceptor rejects the calls of the clients over their limit with ResourceExhausted.
+// client identifies the caller of a call. The calls are let through when the backend fails,
+// so an outage of Redis does not take the API down.
+func (l *Limiter) UnaryInterceptor(client func(ctx context.Context) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.allowCall(ctx, info.FullMethod, client(ctx), func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		}); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rejects the streams of the clients over their limit with ResourceExhausted,
+// as UnaryInterceptor does for the calls. Opening a stream counts as one call.
+func (l *Limiter) StreamInterceptor(client func(ctx context.Context) string) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		if err := l.allowCall(ctx, info.FullMethod, client(ctx), stream.SetHeader); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// allowCall returns the gRPC error rejecting the call when client is over its limit,
+// after sending how long it has to wait with setHeader.
+func (l *Limiter) allowCall(ctx context.Context, fullMethod string, client string, setHeader func(metadata.MD) error) error {
+	result, err := l.Allow(ctx, path.Base(fullMethod), client)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("cannot check rate limit")
+		return nil
+	}
+	if !result.Allowed {
+		retryAfter := strconv.Itoa(result.RetryAfterSeconds())
+		if err := setHeader(metadata.Pairs(retryAfterMetadataKey, retryAfter)); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("cannot send retry-after")
+		}
+		return apperr.GRPCError(limitedError(result))
+	}
+	return nil
+}
+
+func limitedError(result Result) error {
+	return apperr.Newf(apperr.RateLimited, "too many requests, retry in %d seconds", result.RetryAfterSeconds())
+}
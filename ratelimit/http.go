@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/rs/zerolog/log"
+)
+
+// HTTPMiddleware rejects the requests of the clients over their limit with 429 Too Many Requests.
+// routes maps the route patterns, e.g. "POST /v1/login_user", to the RPC names the rules are
+// keyed by; the requests to other routes are not limited. client identifies the sender of a request.
+// As UnaryInterceptor, it lets the requests through when the backend fails.
+func (l *Limiter) HTTPMiddleware(routes map[string]string, client func(req *http.Request) string) func(http.Handler) http.Handler {
+	// the mux only matches the requests against the patterns
+	patterns := http.NewServeMux()
+	for pattern := range routes {
+		patterns.Handle(pattern, http.NotFoundHandler())
+	}
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			_, pattern := patterns.Handler(req)
+			method, ok := routes[pattern]
+			if !ok {
+				handler.ServeHTTP(res, req)
+				return
+			}
+
+			result, err := l.Allow(req.Context(), method, client(req))
+			if err != nil {
+				log.Ctx(req.Context()).Warn().Err(err).Msg("cannot check rate limit")
+				handler.ServeHTTP(res, req)
+				return
+			}
+			if !result.Allowed {
+				problem := apperr.NewProblem(limitedError(result), req.URL.Path)
+				res.Header().Set("Retry-After", strconv.Itoa(result.RetryAfterSeconds()))
+				res.Header().Set("Content-Type", apperr.ProblemContentType)
+				res.WriteHeader(problem.Status)
+				json.NewEncoder(res).Encode(problem)
+				return
+			}
+			handler.ServeHTTP(res, req)
+		})
+	}
+}
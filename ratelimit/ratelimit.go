@@ -0,0 +1,142 @@
+// Package ratelimit protects the APIs against abusive clients with token buckets,
+// one per client and method, held in memory or in Redis when several instances share the limits.
+package ratelimit
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// Limit lets a client call a method Rate times per second on average, and up to Burst times at once.
+// A zero Rate means the method is not limited.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Unlimited reports whether the limit is not enforced.
+func (l Limit) Unlimited() bool {
+	return l.Rate == 0
+}
+
+// Rules holds the limit of each method, keyed by RPC name, e.g. "LoginUser",
+// and the default limit of the other methods.
+type Rules struct {
+	Default Limit            `json:"default"`
+	Methods map[string]Limit `json:"methods"`
+}
+
+// For returns the limit of method.
+func (r Rules) For(method string) Limit {
+	if limit, ok := r.Methods[method]; ok {
+		return limit
+	}
+	return r.Default
+}
+
+// defaultRules are the rules applied when no rules file is configured: every method is limited,
+// and logging in, signing up and transferring money more tightly.
+//
+//go:embed default_rules.json
+var defaultRules []byte
+
+// DefaultRules returns the rules shipped with the server.
+func DefaultRules() Rules {
+	rules, err := parseRules(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default rate limits: %v", err))
+	}
+	return rules
+}
+
+// LoadRules reads the rate limits from a JSON file such as
+//
+//	{"default": {"rate": 10, "burst": 20}, "methods": {"LoginUser": {"rate": 0.2, "burst": 5}}}
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, fmt.Errorf("cannot read rate limits: %w", err)
+	}
+	return parseRules(data)
+}
+
+func parseRules(data []byte) (Rules, error) {
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return Rules{}, fmt.Errorf("cannot parse rate limits: %w", err)
+	}
+
+	if err := rules.Default.validate(); err != nil {
+		return Rules{}, fmt.Errorf("default %w", err)
+	}
+	for method, limit := range rules.Methods {
+		if err := limit.validate(); err != nil {
+			return Rules{}, fmt.Errorf("%s %w", method, err)
+		}
+	}
+
+	return rules, nil
+}
+
+func (l Limit) validate() error {
+	if l.Rate < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+	if !l.Unlimited() && l.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1")
+	}
+	return nil
+}
+
+// Result is the outcome of a call to Allow.
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long the client has to wait before its next call is allowed.
+	RetryAfter time.Duration
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, for the Retry-After header.
+func (r Result) RetryAfterSeconds() int {
+	return int(math.Ceil(r.RetryAfter.Seconds()))
+}
+
+// Backend holds the token buckets.
+type Backend interface {
+	// Allow takes a token from the bucket of key, which refills at the rate of limit.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter applies the rules to the calls of the clients.
+type Limiter struct {
+	rules   Rules
+	backend Backend
+}
+
+// NewLimiter creates a limiter keeping its buckets in backend.
+func NewLimiter(rules Rules, backend Backend) *Limiter {
+	return &Limiter{rules: rules, backend: backend}
+}
+
+// Allow reports whether client may call method now. Each client has a bucket per method.
+func (l *Limiter) Allow(ctx context.Context, method string, client string) (Result, error) {
+	limit := l.rules.For(method)
+	if limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+	return l.backend.Allow(ctx, method+":"+client, limit)
+}
+
+// UserKey identifies an authenticated client.
+func UserKey(username string) string {
+	return "user:" + username
+}
+
+// IPKey identifies an anonymous client.
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
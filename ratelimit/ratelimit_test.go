@@ -0,0 +1,232 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestLimiter(rules Rules) (*Limiter, *time.Time) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+	return NewLimiter(rules, backend), &now
+}
+
+func TestLoadRules(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "OK",
+			content: `{"default": {"rate": 10, "burst": 20}, "methods": {"LoginUser": {"rate": 0.2, "burst": 5}}}`,
+		},
+		{
+			name:    "NegativeRate",
+			content: `{"methods": {"LoginUser": {"rate": -1, "burst": 5}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "NoBurst",
+			content: `{"default": {"rate": 10}}`,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidJSON",
+			content: `{"default":`,
+			wantErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rate_limits.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			rules, err := LoadRules(path)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, Limit{Rate: 0.2, Burst: 5}, rules.For("LoginUser"))
+			require.Equal(t, Limit{Rate: 10, Burst: 20}, rules.For("CreateUser"))
+		})
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	limiter, now := newTestLimiter(Rules{
+		Methods: map[string]Limit{"LoginUser": {Rate: 0.5, Burst: 2}},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(ctx, "LoginUser", IPKey("10.0.0.1"))
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := limiter.Allow(ctx, "LoginUser", IPKey("10.0.0.1"))
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 2*time.Second, result.RetryAfter)
+
+	// the buckets are per client and per method
+	result, err = limiter.Allow(ctx, "LoginUser", IPKey("10.0.0.2"))
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	result, err = limiter.Allow(ctx, "CreateUser", IPKey("10.0.0.1"))
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	*now = now.Add(2 * time.Second)
+	result, err = limiter.Allow(ctx, "LoginUser", IPKey("10.0.0.1"))
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+}
+
+func TestMemoryBackendSweep(t *testing.T) {
+	limiter, now := newTestLimiter(Rules{Default: Limit{Rate: 1, Burst: 1}})
+	backend := limiter.backend.(*MemoryBackend)
+
+	_, err := limiter.Allow(context.Background(), "CreateUser", UserKey("alice"))
+	require.NoError(t, err)
+	require.Len(t, backend.buckets, 1)
+
+	*now = now.Add(sweepInterval)
+	_, err = limiter.Allow(context.Background(), "CreateUser", UserKey("bob"))
+	require.NoError(t, err)
+	require.Len(t, backend.buckets, 1)
+	require.Contains(t, backend.buckets, "CreateUser:"+UserKey("bob"))
+}
+
+type failingBackend struct{}
+
+func (failingBackend) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	limiter, _ := newTestLimiter(Rules{Methods: map[string]Limit{"LoginUser": {Rate: 1, Burst: 1}}})
+	interceptor := limiter.UnaryInterceptor(func(ctx context.Context) string { return IPKey("10.0.0.1") })
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/LoginUser"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	res, err := interceptor(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", res)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// the other methods are not limited
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/CreateUser"}, handler)
+	require.NoError(t, err)
+
+	// the calls are let through when the backend fails
+	limiter = NewLimiter(Rules{Default: Limit{Rate: 1, Burst: 1}}, failingBackend{})
+	interceptor = limiter.UnaryInterceptor(func(ctx context.Context) string { return IPKey("10.0.0.1") })
+	_, err = interceptor(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+}
+
+// fakeServerStream is a stream whose headers are recorded.
+type fakeServerStream struct {
+	grpc.ServerStream
+	header metadata.MD
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	limiter, _ := newTestLimiter(Rules{Methods: map[string]Limit{"WatchAccount": {Rate: 1, Burst: 1}}})
+	interceptor := limiter.StreamInterceptor(func(ctx context.Context) string { return UserKey("alice") })
+	info := &grpc.StreamServerInfo{FullMethod: "/pb.SimpleBank/WatchAccount", IsServerStream: true}
+	handled := 0
+	handler := func(srv any, stream grpc.ServerStream) error {
+		handled++
+		return nil
+	}
+
+	stream := &fakeServerStream{}
+	require.NoError(t, interceptor(nil, stream, info, handler))
+	require.Equal(t, 1, handled)
+
+	err := interceptor(nil, stream, info, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, 1, handled)
+	require.Equal(t, []string{"1"}, stream.header.Get(retryAfterMetadataKey))
+}
+
+func TestDefaultRules(t *testing.T) {
+	rules := DefaultRules()
+	require.False(t, rules.Default.Unlimited())
+
+	for _, method := range []string{"LoginUser", "CreateTransfer"} {
+		limit := rules.For(method)
+		require.Less(t, limit.Rate, rules.Default.Rate, method)
+		require.Less(t, limit.Burst, rules.Default.Burst, method)
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	limiter, _ := newTestLimiter(Rules{Default: Limit{Rate: 0.1, Burst: 1}})
+	routes := map[string]string{
+		"POST /v1/login_user":         "LoginUser",
+		"GET /v1/accounts/{id}/watch": "WatchAccount",
+	}
+	middleware := limiter.HTTPMiddleware(routes, func(req *http.Request) string {
+		return UserKey(req.Header.Get("X-User"))
+	})
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, path, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/login_user", "alice").Code)
+	recorder := serve(http.MethodPost, "/v1/login_user", "alice")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "10", recorder.Header().Get("Retry-After"))
+	require.Contains(t, recorder.Body.String(), "/problems/rate_limited")
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/login_user", "bob").Code)
+
+	// the path parameters of a route share its bucket
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/accounts/1/watch", "alice").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, "/v1/accounts/2/watch", "alice").Code)
+
+	// the other routes are not limited
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/metrics", "alice").Code)
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/login_user", "alice").Code)
+	}
+}
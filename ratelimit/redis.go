@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces the buckets in Redis.
+const redisKeyPrefix = "ratelimit:"
+
+// allowScript takes a token from the bucket of KEYS[1], refilling at ARGV[1] tokens per second
+// up to ARGV[2]. It reads the time of the Redis server, so the instances agree on it, and lets
+// the bucket expire once it is full again.
+var allowScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil or updated == nil then
+	tokens = burst
+	updated = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = (1 - tokens) / rate
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(wait)}
+`)
+
+// RedisBackend holds the buckets in Redis, so the instances of the server share the limits.
+type RedisBackend struct {
+	client redis.UniversalClient
+}
+
+// NewRedisBackend creates a backend storing the buckets with client.
+func NewRedisBackend(client redis.UniversalClient) *RedisBackend {
+	return &RedisBackend{client: client}
+}
+
+// Allow implements Backend.
+func (r *RedisBackend) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := allowScript.Run(ctx, r.client, []string{redisKeyPrefix + key}, limit.Rate, limit.Burst).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	waitText, _ := values[1].(string)
+	wait, err := strconv.ParseFloat(waitText, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    allowed == 1,
+		RetryAfter: time.Duration(wait * float64(time.Second)),
+	}, nil
+}
@@ -0,0 +1,65 @@
+// Package requestid identifies the requests, so the log lines of a request, and of the
+// background tasks it creates, can be correlated.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Header is the HTTP header a request ID is accepted from and returned in.
+	Header = "X-Request-ID"
+	// MetadataKey is the gRPC metadata key a request ID is accepted from and returned in.
+	MetadataKey = "x-request-id"
+	// LogField is the field of the log events the request ID is attached to.
+	LogField = "request_id"
+)
+
+// maxLength bounds the request IDs accepted from clients.
+const maxLength = 128
+
+type contextKey struct{}
+
+// New generates a request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether id, given by a client, can be used as a request ID:
+// it is not empty, not too long, and printable ASCII without spaces, so it can be logged and echoed as is.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// FromClient returns id if it is a valid request ID, or a new one.
+func FromClient(id string) string {
+	if Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// NewContext returns a copy of ctx carrying id, along with a logger attaching id to its events,
+// which log.Ctx returns.
+func NewContext(ctx context.Context, id string) context.Context {
+	logger := zerolog.Ctx(ctx).With().Str(LogField, id).Logger()
+	ctx = context.WithValue(ctx, contextKey{}, id)
+	return logger.WithContext(ctx)
+}
+
+// FromContext returns the request ID carried by ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,41 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValid(t *testing.T) {
+	require.True(t, Valid("0b1c7a4e-5d1f-4c43-9c5e-7d2a1f0c9e11"))
+	require.True(t, Valid("checkout-42"))
+	require.False(t, Valid(""))
+	require.False(t, Valid("two words"))
+	require.False(t, Valid("line\nbreak"))
+	require.False(t, Valid("café"))
+	require.False(t, Valid(strings.Repeat("a", maxLength+1)))
+
+	require.Equal(t, "checkout-42", FromClient("checkout-42"))
+	require.True(t, Valid(FromClient("two words")))
+}
+
+func TestNewContext(t *testing.T) {
+	var out bytes.Buffer
+	logger := zerolog.New(&out)
+	ctx := logger.WithContext(context.Background())
+
+	_, ok := FromContext(ctx)
+	require.False(t, ok)
+
+	ctx = NewContext(ctx, "checkout-42")
+	id, ok := FromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, "checkout-42", id)
+
+	zerolog.Ctx(ctx).Info().Msg("transfer created")
+	require.JSONEq(t, `{"level":"info","request_id":"checkout-42","message":"transfer created"}`, out.String())
+}
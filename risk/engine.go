@@ -0,0 +1,81 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Signals is what the rules know of the history of a transfer.
+type Signals struct {
+	// PayeeTransfers counts the transfers already made from the source account to the destination account.
+	PayeeTransfers int64
+	// RecentTransfers counts the transfers made from the source account within the velocity window.
+	RecentTransfers int64
+	// SessionCreatedAt is when the user last logged in, zero if never.
+	SessionCreatedAt time.Time
+	// EarlierLogins counts the logins of the user before their last login.
+	EarlierLogins int64
+	// KnownClientIP reports whether the user logged in from the client IP before their last login.
+	KnownClientIP bool
+}
+
+// History looks up the Signals of the transfers.
+type History interface {
+	// GetTransferRiskSignals returns the signals of transfer, counting the recent transfers since the given time.
+	GetTransferRiskSignals(ctx context.Context, transfer Transfer, since time.Time) (Signals, error)
+}
+
+// Engine is the built-in Evaluator, scoring the transfers with Rules.
+type Engine struct {
+	rules   Rules
+	history History
+	now     func() time.Time
+}
+
+// NewEngine creates an engine applying rules to the transfers, with their signals looked up in history.
+func NewEngine(rules Rules, history History) *Engine {
+	return &Engine{rules: rules, history: history, now: time.Now}
+}
+
+// Evaluate implements Evaluator.
+func (e *Engine) Evaluate(ctx context.Context, transfer Transfer) (Assessment, error) {
+	now := e.now()
+	signals, err := e.history.GetTransferRiskSignals(ctx, transfer, now.Add(-e.rules.Velocity.Window.Duration))
+	if err != nil {
+		return Assessment{}, fmt.Errorf("cannot look up the risk signals: %w", err)
+	}
+
+	assessment := Assessment{Decision: Allow}
+	match := func(score int, reason string) {
+		assessment.Score += score
+		assessment.Reasons = append(assessment.Reasons, reason)
+	}
+
+	if rule := e.rules.NewPayee; rule.Score > 0 && signals.PayeeTransfers == 0 {
+		if large, ok := rule.Amounts[transfer.Amount.Currency.Code]; ok && transfer.Amount.Units >= large {
+			match(rule.Score, "large amount to a new payee")
+		}
+	}
+
+	if rule := e.rules.Velocity; rule.Score > 0 && signals.RecentTransfers >= rule.MaxTransfers {
+		match(rule.Score, fmt.Sprintf("%d transfers within %s", signals.RecentTransfers+1, rule.Window.Duration))
+	}
+
+	if rule := e.rules.NewSession; rule.Score > 0 && !signals.SessionCreatedAt.IsZero() && now.Sub(signals.SessionCreatedAt) < rule.MaxAge.Duration {
+		match(rule.Score, "session started moments ago")
+	}
+
+	// without earlier logins, there is no usual IP to compare the client IP with
+	if rule := e.rules.UnusualIP; rule.Score > 0 && transfer.ClientIP != "" && signals.EarlierLogins > 0 && !signals.KnownClientIP {
+		match(rule.Score, "unusual client IP")
+	}
+
+	switch {
+	case e.rules.DenyScore > 0 && assessment.Score >= e.rules.DenyScore:
+		assessment.Decision = Deny
+	case e.rules.ReviewScore > 0 && assessment.Score >= e.rules.ReviewScore:
+		assessment.Decision = Review
+	}
+	return assessment, nil
+}
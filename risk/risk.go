@@ -0,0 +1,184 @@
+// Package risk scores the transfers before they are made, so the suspicious ones are
+// parked for a banker to review, or declined.
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/apperr"
+	"github.com/MElghrbawy/simple_bank/money"
+)
+
+// ErrDeclined refuses a transfer denied by the risk checks. It does not tell the client why.
+var ErrDeclined = apperr.New(apperr.PermissionDenied, "the transfer was declined by the risk checks")
+
+// Decision is the outcome of the evaluation of a transfer.
+type Decision string
+
+// The decisions of an Evaluator.
+const (
+	// Allow lets the transfer go through.
+	Allow Decision = "allow"
+	// Review parks the transfer until a banker approves or rejects it.
+	Review Decision = "review"
+	// Deny refuses the transfer.
+	Deny Decision = "deny"
+)
+
+// Transfer is a transfer about to be made.
+type Transfer struct {
+	Username      string
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        money.Amount
+	ClientIP      string
+}
+
+// Assessment is the outcome of the evaluation of a transfer, with the reasons it is not allowed outright.
+type Assessment struct {
+	Decision Decision `json:"decision"`
+	Score    int      `json:"score"`
+	Reasons  []string `json:"reasons,omitempty"`
+}
+
+// Evaluator decides whether a transfer can be made.
+type Evaluator interface {
+	Evaluate(ctx context.Context, transfer Transfer) (Assessment, error)
+}
+
+// AllowAll is the Evaluator allowing every transfer.
+type AllowAll struct{}
+
+// Evaluate implements Evaluator.
+func (AllowAll) Evaluate(ctx context.Context, transfer Transfer) (Assessment, error) {
+	return Assessment{Decision: Allow}, nil
+}
+
+// NewEvaluator creates the rules engine with the rules of rulesPath, looking up the history of the
+// transfers in history. Without rulesPath, every transfer is allowed.
+func NewEvaluator(rulesPath string, history History) (Evaluator, error) {
+	if rulesPath == "" {
+		return AllowAll{}, nil
+	}
+
+	rules, err := LoadRules(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(rules, history), nil
+}
+
+// Duration is a time.Duration written as a string in JSON, e.g. "1h30m".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// Rules scores the transfers: each rule a transfer matches adds its score, and the total decides.
+// A rule with a zero score is not applied.
+type Rules struct {
+	// ReviewScore is the score from which a transfer is parked for review, 0 to never park one.
+	ReviewScore int `json:"review_score"`
+	// DenyScore is the score from which a transfer is denied, 0 to never deny one.
+	DenyScore  int            `json:"deny_score"`
+	NewPayee   NewPayeeRule   `json:"new_payee"`
+	Velocity   VelocityRule   `json:"velocity"`
+	NewSession NewSessionRule `json:"new_session"`
+	UnusualIP  UnusualIPRule  `json:"unusual_ip"`
+}
+
+// NewPayeeRule matches the large transfers to an account the source account never paid before.
+type NewPayeeRule struct {
+	// Amounts is the amount from which a transfer is large, in minor units, keyed by currency.
+	Amounts map[string]int64 `json:"amounts"`
+	Score   int              `json:"score"`
+}
+
+// VelocityRule matches the transfers following MaxTransfers others from the same account within Window.
+type VelocityRule struct {
+	Window       Duration `json:"window"`
+	MaxTransfers int64    `json:"max_transfers"`
+	Score        int      `json:"score"`
+}
+
+// NewSessionRule matches the transfers made less than MaxAge after the user logged in.
+type NewSessionRule struct {
+	MaxAge Duration `json:"max_age"`
+	Score  int      `json:"score"`
+}
+
+// UnusualIPRule matches the transfers made from an IP the user never logged in from before.
+// Users without a login before their last one have no usual IP, so the rule skips them.
+type UnusualIPRule struct {
+	Score int `json:"score"`
+}
+
+// LoadRules reads the risk rules from a JSON file such as
+//
+//	{
+//	  "review_score": 50, "deny_score": 100,
+//	  "new_payee": {"amounts": {"USD": 100000}, "score": 40},
+//	  "velocity": {"window": "1h", "max_transfers": 10, "score": 50},
+//	  "new_session": {"max_age": "10m", "score": 20},
+//	  "unusual_ip": {"score": 30}
+//	}
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, fmt.Errorf("cannot read risk rules: %w", err)
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return Rules{}, fmt.Errorf("cannot parse risk rules: %w", err)
+	}
+
+	if err := rules.validate(); err != nil {
+		return Rules{}, fmt.Errorf("invalid risk rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r Rules) validate() error {
+	scores := []int{r.ReviewScore, r.DenyScore, r.NewPayee.Score, r.Velocity.Score, r.NewSession.Score, r.UnusualIP.Score}
+	for _, score := range scores {
+		if score < 0 {
+			return errors.New("scores must not be negative")
+		}
+	}
+	if r.ReviewScore > 0 && r.DenyScore > 0 && r.DenyScore < r.ReviewScore {
+		return errors.New("deny score must not be below the review score")
+	}
+
+	for currency, amount := range r.NewPayee.Amounts {
+		if amount < 0 {
+			return fmt.Errorf("%s new payee amount must not be negative", currency)
+		}
+	}
+	if r.Velocity.Score > 0 && (r.Velocity.Window.Duration <= 0 || r.Velocity.MaxTransfers < 0) {
+		return errors.New("velocity needs a positive window and a max transfers not negative")
+	}
+	if r.NewSession.Score > 0 && r.NewSession.MaxAge.Duration <= 0 {
+		return errors.New("new session needs a positive max age")
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/money"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHistory returns the same signals for every transfer.
+type fakeHistory struct {
+	signals Signals
+	err     error
+	since   time.Time
+}
+
+func (h *fakeHistory) GetTransferRiskSignals(ctx context.Context, transfer Transfer, since time.Time) (Signals, error) {
+	h.since = since
+	return h.signals, h.err
+}
+
+var testRules = Rules{
+	ReviewScore: 50,
+	DenyScore:   100,
+	NewPayee:    NewPayeeRule{Amounts: map[string]int64{"USD": 100_000}, Score: 40},
+	Velocity:    VelocityRule{Window: Duration{time.Hour}, MaxTransfers: 10, Score: 60},
+	NewSession:  NewSessionRule{MaxAge: Duration{10 * time.Minute}, Score: 20},
+	UnusualIP:   UnusualIPRule{Score: 30},
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2024, time.February, 15, 13, 45, 0, 0, time.UTC)
+	usual := Signals{PayeeTransfers: 3, RecentTransfers: 1, SessionCreatedAt: now.Add(-time.Hour), EarlierLogins: 4, KnownClientIP: true}
+
+	testCases := []struct {
+		name     string
+		amount   int64
+		signals  func(signals *Signals)
+		decision Decision
+		reasons  []string
+	}{
+		{
+			name:     "Usual",
+			amount:   500_000,
+			decision: Allow,
+		},
+		{
+			name:   "LargeAmountToNewPayee",
+			amount: 100_000,
+			signals: func(signals *Signals) {
+				signals.PayeeTransfers = 0
+			},
+			decision: Allow,
+			reasons:  []string{"large amount to a new payee"},
+		},
+		{
+			name:   "SmallAmountToNewPayee",
+			amount: 99_999,
+			signals: func(signals *Signals) {
+				signals.PayeeTransfers = 0
+			},
+			decision: Allow,
+		},
+		{
+			name:   "NewPayeeFromNewSession",
+			amount: 100_000,
+			signals: func(signals *Signals) {
+				signals.PayeeTransfers = 0
+				signals.SessionCreatedAt = now.Add(-time.Minute)
+			},
+			decision: Review,
+			reasons:  []string{"large amount to a new payee", "session started moments ago"},
+		},
+		{
+			name:   "Velocity",
+			amount: 100,
+			signals: func(signals *Signals) {
+				signals.RecentTransfers = 10
+			},
+			decision: Review,
+			reasons:  []string{"11 transfers within 1h0m0s"},
+		},
+		{
+			name:   "Everything",
+			amount: 100_000,
+			signals: func(signals *Signals) {
+				*signals = Signals{RecentTransfers: 10, SessionCreatedAt: now, EarlierLogins: 1}
+			},
+			decision: Deny,
+			reasons:  []string{"large amount to a new payee", "11 transfers within 1h0m0s", "session started moments ago", "unusual client IP"},
+		},
+		{
+			name:   "UnusualIP",
+			amount: 100,
+			signals: func(signals *Signals) {
+				signals.KnownClientIP = false
+			},
+			decision: Allow,
+			reasons:  []string{"unusual client IP"},
+		},
+		{
+			name:   "FirstLogin",
+			amount: 100,
+			signals: func(signals *Signals) {
+				signals.EarlierLogins = 0
+				signals.KnownClientIP = false
+			},
+			decision: Allow,
+		},
+		{
+			name:   "NeverLoggedIn",
+			amount: 100,
+			signals: func(signals *Signals) {
+				signals.SessionCreatedAt = time.Time{}
+			},
+			decision: Allow,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			history := &fakeHistory{signals: usual}
+			if tc.signals != nil {
+				tc.signals(&history.signals)
+			}
+			engine := NewEngine(testRules, history)
+			engine.now = func() time.Time { return now }
+
+			assessment, err := engine.Evaluate(context.Background(), Transfer{
+				Username:      "alice",
+				FromAccountID: 1,
+				ToAccountID:   2,
+				Amount:        money.MustNew(tc.amount, "USD"),
+				ClientIP:      "203.0.113.5",
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.decision, assessment.Decision)
+			require.Equal(t, tc.reasons, assessment.Reasons)
+			require.Equal(t, now.Add(-time.Hour), history.since)
+		})
+	}
+}
+
+func TestEvaluateHistoryError(t *testing.T) {
+	engine := NewEngine(testRules, &fakeHistory{err: errors.New("connection refused")})
+
+	_, err := engine.Evaluate(context.Background(), Transfer{Amount: money.MustNew(100, "USD")})
+	require.ErrorContains(t, err, "connection refused")
+}
+
+func TestAllowAll(t *testing.T) {
+	evaluator, err := NewEvaluator("", nil)
+	require.NoError(t, err)
+
+	assessment, err := evaluator.Evaluate(context.Background(), Transfer{Amount: money.MustNew(1_000_000_000, "USD")})
+	require.NoError(t, err)
+	require.Equal(t, Allow, assessment.Decision)
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "risk.json")
+	err := os.WriteFile(path, []byte(`{
+		"review_score": 50, "deny_score": 100,
+		"new_payee": {"amounts": {"USD": 100000}, "score": 40},
+		"velocity": {"window": "1h", "max_transfers": 10, "score": 60},
+		"new_session": {"max_age": "10m", "score": 20},
+		"unusual_ip": {"score": 30}
+	}`), 0o600)
+	require.NoError(t, err)
+
+	rules, err := LoadRules(path)
+	require.NoError(t, err)
+	require.Equal(t, testRules, rules)
+
+	invalid := []string{
+		`{"review_score": 100, "deny_score": 50}`,
+		`{"unusual_ip": {"score": -1}}`,
+		`{"velocity": {"window": "0s", "max_transfers": 10, "score": 60}}`,
+		`{"new_session": {"max_age": "ten minutes", "score": 20}}`,
+		`{"new_payee": {"amounts": {"USD": -1}, "score": 40}}`,
+	}
+	for _, rules := range invalid {
+		err = os.WriteFile(path, []byte(rules), 0o600)
+		require.NoError(t, err)
+
+		_, err = LoadRules(path)
+		require.Error(t, err, rules)
+	}
+}
@@ -0,0 +1,167 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// defaultKeyVersion is the version of a key given without one.
+const defaultKeyVersion = "1"
+
+// Keyring holds the versioned symmetric keys of a PasetoMaker. Tokens are encrypted
+// with the current key and accepted when encrypted with any key of the ring, so a key
+// can be rotated without logging users out: add the new key, make it current, and
+// drop the old one once the tokens it encrypted have expired.
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring creates a keyring of keys by version, encrypting new tokens with the current one.
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no token key configured")
+	}
+	for version, key := range keys {
+		if err := validateKeyVersion(version); err != nil {
+			return nil, err
+		}
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, fmt.Errorf("invalid size of key %q of %d, expected %d", version, len(key), chacha20poly1305.KeySize)
+		}
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current key %q is not configured", current)
+	}
+
+	return &Keyring{current: current, keys: keys}, nil
+}
+
+// KeySource tells where to load the keys of a keyring from. Keys of all the sources are merged.
+type KeySource struct {
+	// Keys is a key, or a comma separated list of version:key pairs, typically from the environment.
+	Keys string
+	// File is a file with a version:key pair per line.
+	File string
+	// Dir is a directory with a file per key named after its version, e.g. mounted secrets.
+	Dir string
+	// Current is the version of the key new tokens are encrypted with.
+	// It defaults to the latest version, comparing numbers numerically.
+	Current string
+}
+
+// LoadKeyring loads the keys of source into a keyring.
+func LoadKeyring(source KeySource) (*Keyring, error) {
+	keys := make(map[string][]byte)
+
+	if source.Keys != "" {
+		if err := addKeyList(keys, strings.Split(source.Keys, ",")); err != nil {
+			return nil, err
+		}
+	}
+
+	if source.File != "" {
+		data, err := os.ReadFile(source.File)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read token key file: %w", err)
+		}
+		var lines []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		if err := addKeyList(keys, lines); err != nil {
+			return nil, err
+		}
+	}
+
+	if source.Dir != "" {
+		entries, err := os.ReadDir(source.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read token key directory: %w", err)
+		}
+		for _, entry := range entries {
+			// skip subdirectories and the dot files of Kubernetes secret volumes
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(source.Dir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("cannot read token key: %w", err)
+			}
+			if err := addKey(keys, entry.Name(), strings.TrimRight(string(data), "\r\n")); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	current := source.Current
+	if current == "" {
+		for version := range keys {
+			if current == "" || compareKeyVersions(version, current) > 0 {
+				current = version
+			}
+		}
+	}
+
+	return NewKeyring(current, keys)
+}
+
+// addKeyList adds keys given as a bare key or as version:key pairs.
+func addKeyList(keys map[string][]byte, list []string) error {
+	if len(list) == 1 && len(list[0]) == chacha20poly1305.KeySize {
+		return addKey(keys, defaultKeyVersion, list[0])
+	}
+
+	for _, pair := range list {
+		version, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return errors.New("token keys must be a single key or version:key pairs")
+		}
+		if err := addKey(keys, version, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addKey(keys map[string][]byte, version string, key string) error {
+	if existing, ok := keys[version]; ok && string(existing) != key {
+		return fmt.Errorf("conflicting keys for version %q", version)
+	}
+	keys[version] = []byte(key)
+	return nil
+}
+
+func validateKeyVersion(version string) error {
+	if version == "" {
+		return errors.New("token key version must not be empty")
+	}
+	if strings.ContainsAny(version, `":,\`) || strings.TrimSpace(version) != version {
+		return fmt.Errorf("invalid token key version %q", version)
+	}
+	return nil
+}
+
+// compareKeyVersions orders versions numerically when both are numbers, and as strings otherwise.
+func compareKeyVersions(a, b string) int {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
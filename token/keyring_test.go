@@ -0,0 +1,103 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKeyring(t *testing.T) {
+	key1 := util.RandomString(32)
+	key2 := util.RandomString(32)
+	key10 := util.RandomString(32)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2"), []byte(key2+"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "10"), []byte(key10), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0o700))
+
+	file := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(file, []byte("# token keys\n1:"+key1+"\n\n2:"+key2+"\n"), 0o600))
+
+	testCases := []struct {
+		name    string
+		source  KeySource
+		current string
+		keys    map[string][]byte
+		err     string
+	}{
+		{
+			name:    "BareKey",
+			source:  KeySource{Keys: key1},
+			current: "1",
+			keys:    map[string][]byte{"1": []byte(key1)},
+		},
+		{
+			name:    "KeyList",
+			source:  KeySource{Keys: "a:" + key1 + ", b:" + key2},
+			current: "b",
+			keys:    map[string][]byte{"a": []byte(key1), "b": []byte(key2)},
+		},
+		{
+			name:    "File",
+			source:  KeySource{File: file},
+			current: "2",
+			keys:    map[string][]byte{"1": []byte(key1), "2": []byte(key2)},
+		},
+		{
+			name:    "Dir",
+			source:  KeySource{Dir: dir},
+			current: "10",
+			keys:    map[string][]byte{"2": []byte(key2), "10": []byte(key10)},
+		},
+		{
+			name:    "Merged",
+			source:  KeySource{File: file, Dir: dir, Current: "2"},
+			current: "2",
+			keys:    map[string][]byte{"1": []byte(key1), "2": []byte(key2), "10": []byte(key10)},
+		},
+		{
+			name:   "NoKey",
+			source: KeySource{},
+			err:    "no token key configured",
+		},
+		{
+			name:   "InvalidSize",
+			source: KeySource{Keys: "1:short"},
+			err:    `invalid size of key "1" of 5, expected 32`,
+		},
+		{
+			name:   "UnknownCurrent",
+			source: KeySource{Keys: key1, Current: "2"},
+			err:    `current key "2" is not configured`,
+		},
+		{
+			name:   "Conflict",
+			source: KeySource{Keys: "2:" + key1, Dir: dir},
+			err:    `conflicting keys for version "2"`,
+		},
+		{
+			name:   "MissingVersion",
+			source: KeySource{Keys: key1 + "," + key2},
+			err:    "token keys must be a single key or version:key pairs",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			keyring, err := LoadKeyring(tc.source)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.current, keyring.current)
+			require.Equal(t, tc.keys, keyring.keys)
+		})
+	}
+}
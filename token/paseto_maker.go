@@ -7,23 +7,41 @@ import (
 
 	"aidanwoods.dev/go-paseto"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/chacha20poly1305"
 )
 
 // PasetoMaker is a PASETO token maker
 type PasetoMaker struct {
-	symmetricKey paseto.V4SymmetricKey
+	current string
+	keys    map[string]paseto.V4SymmetricKey
 }
 
+// keyFooter is the footer of the tokens, naming the version of the key they are encrypted with.
+type keyFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// NewPasetoMaker creates a maker encrypting tokens with a single symmetric key.
 func NewPasetoMaker(symmetricKey string) (*PasetoMaker, error) {
-	if len(symmetricKey) != chacha20poly1305.KeySize {
-		return nil, fmt.Errorf("invalid key size of %d, expected %d", len(symmetricKey), chacha20poly1305.KeySize)
+	keyring, err := NewKeyring(defaultKeyVersion, map[string][]byte{defaultKeyVersion: []byte(symmetricKey)})
+	if err != nil {
+		return nil, err
 	}
+	return NewPasetoMakerFromKeyring(keyring)
+}
 
-	key := paseto.NewV4SymmetricKey()
+// NewPasetoMakerFromKeyring creates a maker encrypting tokens with the current key of keyring,
+// and accepting tokens encrypted with any of its keys.
+func NewPasetoMakerFromKeyring(keyring *Keyring) (*PasetoMaker, error) {
 	maker := &PasetoMaker{
-
-		symmetricKey: key,
+		current: keyring.current,
+		keys:    make(map[string]paseto.V4SymmetricKey, len(keyring.keys)),
+	}
+	for version, material := range keyring.keys {
+		key, err := paseto.V4SymmetricKeyFromBytes(material)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", version, err)
+		}
+		maker.keys[version] = key
 	}
 
 	return maker, nil
@@ -45,8 +63,13 @@ func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (
 	token.SetString("id", payload.ID.String())
 	token.SetString("username", payload.Username)
 
-	return token.V4Encrypt(maker.symmetricKey, nil), payload, nil
+	footer, err := json.Marshal(keyFooter{KeyID: maker.current})
+	if err != nil {
+		return "", nil, err
+	}
+	token.SetFooter(footer)
 
+	return token.V4Encrypt(maker.keys[maker.current], nil), payload, nil
 }
 
 // VerifyToken checks if the token is valid or not
@@ -54,7 +77,11 @@ func (maker *PasetoMaker) VerifyToken(tokenString string) (*Payload, error) {
 	payload := &Payload{}
 
 	parser := paseto.NewParser()
-	token, err := parser.ParseV4Local(maker.symmetricKey, tokenString, nil)
+	key, ok := maker.tokenKey(parser, tokenString)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	token, err := parser.ParseV4Local(key, tokenString, nil)
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
@@ -83,3 +110,22 @@ func (maker *PasetoMaker) VerifyToken(tokenString string) (*Payload, error) {
 
 	return payload, nil
 }
+
+// tokenKey finds the key a token was encrypted with from its footer,
+// assuming the current key for tokens without one.
+func (maker *PasetoMaker) tokenKey(parser paseto.Parser, tokenString string) (paseto.V4SymmetricKey, bool) {
+	data, err := parser.UnsafeParseFooter(paseto.V4Local, tokenString)
+	if err != nil {
+		return paseto.V4SymmetricKey{}, false
+	}
+
+	footer := keyFooter{KeyID: maker.current}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &footer); err != nil {
+			return paseto.V4SymmetricKey{}, false
+		}
+	}
+
+	key, ok := maker.keys[footer.KeyID]
+	return key, ok
+}
@@ -46,3 +46,62 @@ func TestExpiredPasetoToken(t *testing.T) {
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestPasetoMakerSharedKey(t *testing.T) {
+	key := util.RandomString(32)
+
+	issuer, err := NewPasetoMaker(key)
+	require.NoError(t, err)
+	verifier, err := NewPasetoMaker(key)
+	require.NoError(t, err)
+
+	token, _, err := issuer.CreateToken(util.RandomOwner(), time.Minute)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+
+	other, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	_, err = other.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+func TestPasetoMakerKeyRotation(t *testing.T) {
+	oldKey := []byte(util.RandomString(32))
+	newKey := []byte(util.RandomString(32))
+
+	oldKeyring, err := NewKeyring("1", map[string][]byte{"1": oldKey})
+	require.NoError(t, err)
+	oldMaker, err := NewPasetoMakerFromKeyring(oldKeyring)
+	require.NoError(t, err)
+
+	oldToken, _, err := oldMaker.CreateToken(util.RandomOwner(), time.Minute)
+	require.NoError(t, err)
+
+	keyring, err := NewKeyring("2", map[string][]byte{"1": oldKey, "2": newKey})
+	require.NoError(t, err)
+	maker, err := NewPasetoMakerFromKeyring(keyring)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	newToken, _, err := maker.CreateToken(util.RandomOwner(), time.Minute)
+	require.NoError(t, err)
+
+	_, err = oldMaker.VerifyToken(newToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	newKeyring, err := NewKeyring("2", map[string][]byte{"2": newKey})
+	require.NoError(t, err)
+	newMaker, err := NewPasetoMakerFromKeyring(newKeyring)
+	require.NoError(t, err)
+
+	_, err = newMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	_, err = newMaker.VerifyToken(oldToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
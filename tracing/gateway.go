@@ -0,0 +1,23 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// GatewayRoute names the span of a request served by the gateway after the path pattern of its RPC,
+// as the pattern is unknown when the HTTP middleware starts the span.
+// It is meant for runtime.WithMetadata, which calls it once the pattern is known, and adds no metadata.
+func GatewayRoute(ctx context.Context, req *http.Request) metadata.MD {
+	if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+		span := trace.SpanFromContext(ctx)
+		span.SetName(req.Method + " " + pattern)
+		span.SetAttributes(semconv.HTTPRoute(pattern))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestQueryName(t *testing.T) {
+	testCases := []struct {
+		sql  string
+		name string
+	}{
+		{sql: "-- name: GetAccount :one\nSELECT id FROM accounts WHERE id = $1", name: "GetAccount"},
+		{sql: "  -- name: ListAccounts :many\nSELECT 1", name: "ListAccounts"},
+		{sql: "begin", name: "begin"},
+		{sql: "SELECT\n1", name: "select"},
+		{sql: "", name: "query"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.name, queryName(tc.sql))
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+
+	tracer := QueryTracer{}
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "-- name: GetAccount :one\nSELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "-- name: CreateAccount :one\nINSERT"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("duplicate key")})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "GetAccount", spans[0].Name())
+	require.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, "CreateAccount", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+}
@@ -0,0 +1,89 @@
+// Package tracing sets up OpenTelemetry tracing, so a request can be followed from the
+// gateway into the gRPC handlers, the database and the background tasks it creates.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// The exporters spans can be sent to.
+const (
+	// ExporterNone disables tracing.
+	ExporterNone = "none"
+	// ExporterStdout writes the spans to stdout, for development.
+	ExporterStdout = "stdout"
+	// ExporterOTLP sends the spans to an OTLP collector over gRPC.
+	ExporterOTLP = "otlp"
+)
+
+// ServiceName is the name the spans are reported under.
+const ServiceName = "simplebank"
+
+// instrumentationName names the tracer of the spans started by this repository.
+const instrumentationName = "github.com/MElghrbawy/simple_bank"
+
+// Options selects where the spans go.
+type Options struct {
+	// Exporter is one of the Exporter constants. Empty means ExporterNone.
+	Exporter string
+	// OTLPEndpoint is the host:port of the collector for ExporterOTLP, e.g. localhost:4317.
+	OTLPEndpoint string
+	// SampleRatio is the fraction of the traces recorded, from 0 to 1.
+	// Traces started by a caller keep the decision of the caller.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// The returned function flushes the pending spans and stops the exporter.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracegrpc.New(ctx,
+			otlptracegrpc.WithEndpoint(opts.OTLPEndpoint),
+			otlptracegrpc.WithInsecure(),
+		)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
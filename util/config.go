@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
@@ -13,12 +14,54 @@ type Config struct {
 	Environment          string        `mapstructure:"ENVIRONMENT"`
 	DBDriver             string        `mapstructure:"DB_DRIVER"`
 	DBSource             string        `mapstructure:"DB_SOURCE"`
+	DBReplicaSource      string        `mapstructure:"DB_REPLICA_SOURCE"`
+	DBMaxConns           int32         `mapstructure:"DB_MAX_CONNS"`
+	DBMinConns           int32         `mapstructure:"DB_MIN_CONNS"`
+	DBMaxConnLifetime    time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME"`
+	DBMaxConnIdleTime    time.Duration `mapstructure:"DB_MAX_CONN_IDLE_TIME"`
+	DBHealthCheckPeriod  time.Duration `mapstructure:"DB_HEALTH_CHECK_PERIOD"`
 	MIGRATION_URL        string        `mapstructure:"MIGRATION_URL"`
 	HTTPServerAddress    string        `mapstructure:"HTTP_SERVER_ADDRESS"`
 	GRPCServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
 	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenKeyFile         string        `mapstructure:"TOKEN_KEY_FILE"`
+	TokenKeyDir          string        `mapstructure:"TOKEN_KEY_DIR"`
+	TokenKeyVersion      string        `mapstructure:"TOKEN_KEY_VERSION"`
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	AdminUsernames       []string      `mapstructure:"ADMIN_USERNAMES"`
+	BankerUsernames      []string      `mapstructure:"BANKER_USERNAMES"`
+	TrustedProxies       []string      `mapstructure:"TRUSTED_PROXIES"`
+	AuditKey             string        `mapstructure:"AUDIT_KEY"`
+	AuditChainInterval   time.Duration `mapstructure:"AUDIT_CHAIN_INTERVAL"`
+	FeeSchedulePath      string        `mapstructure:"FEE_SCHEDULE_PATH"`
+	TransferLimitsPath   string        `mapstructure:"TRANSFER_LIMITS_PATH"`
+	RiskRulesPath        string        `mapstructure:"RISK_RULES_PATH"`
+	ApprovalThresholds   string        `mapstructure:"APPROVAL_THRESHOLDS_PATH"`
+	PendingTransferTTL   time.Duration `mapstructure:"PENDING_TRANSFER_TTL"`
+	PendingSweepInterval time.Duration `mapstructure:"PENDING_SWEEP_INTERVAL"`
+	TaskQueue            string        `mapstructure:"TASK_QUEUE"`
+	RedisAddress         string        `mapstructure:"REDIS_ADDRESS"`
+	OutboxRelayInterval  time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL"`
+	RateLimitsPath       string        `mapstructure:"RATE_LIMITS_PATH"`
+	RateLimitBackend     string        `mapstructure:"RATE_LIMIT_BACKEND"`
+	TracingExporter      string        `mapstructure:"TRACING_EXPORTER"`
+	TracingOTLPEndpoint  string        `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	TracingSampleRatio   float64       `mapstructure:"TRACING_SAMPLE_RATIO"`
+	HealthCheckInterval  time.Duration `mapstructure:"HEALTH_CHECK_INTERVAL"`
+	HealthCheckTimeout   time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+	ShutdownDrainDelay   time.Duration `mapstructure:"SHUTDOWN_DRAIN_DELAY"`
+	ShutdownTimeout      time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+}
+
+// intervalDefaults are the intervals used when they are not configured.
+// They drive tickers and timeouts, which cannot work with a zero duration.
+var intervalDefaults = map[string]time.Duration{
+	"AUDIT_CHAIN_INTERVAL":   time.Second,
+	"PENDING_SWEEP_INTERVAL": time.Minute,
+	"OUTBOX_RELAY_INTERVAL":  5 * time.Second,
+	"HEALTH_CHECK_INTERVAL":  10 * time.Second,
+	"HEALTH_CHECK_TIMEOUT":   2 * time.Second,
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -27,6 +70,9 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.SetConfigType("env")
 
 	viper.AutomaticEnv()
+	for key, interval := range intervalDefaults {
+		viper.SetDefault(key, interval)
+	}
 
 	err = viper.ReadInConfig()
 	if err != nil {
@@ -34,5 +80,30 @@ func LoadConfig(path string) (config Config, err error) {
 	}
 
 	err = viper.Unmarshal(&config)
+	if err != nil {
+		return
+	}
+
+	err = config.validate()
 	return
 }
+
+// validate checks the intervals with a default are positive.
+func (config Config) validate() error {
+	intervals := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"AUDIT_CHAIN_INTERVAL", config.AuditChainInterval},
+		{"PENDING_SWEEP_INTERVAL", config.PendingSweepInterval},
+		{"OUTBOX_RELAY_INTERVAL", config.OutboxRelayInterval},
+		{"HEALTH_CHECK_INTERVAL", config.HealthCheckInterval},
+		{"HEALTH_CHECK_TIMEOUT", config.HealthCheckTimeout},
+	}
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", interval.key, interval.value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestConfig(t *testing.T, env string) (Config, error) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.env"), []byte(env), 0o600))
+	return LoadConfig(dir)
+}
+
+func TestLoadConfigIntervalDefaults(t *testing.T) {
+	config, err := loadTestConfig(t, "ENVIRONMENT=test\n")
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Second, config.HealthCheckInterval)
+	require.Equal(t, 2*time.Second, config.HealthCheckTimeout)
+	require.Equal(t, 5*time.Second, config.OutboxRelayInterval)
+	require.Equal(t, time.Minute, config.PendingSweepInterval)
+	require.Equal(t, time.Second, config.AuditChainInterval)
+}
+
+func TestLoadConfigZeroInterval(t *testing.T) {
+	_, err := loadTestConfig(t, "HEALTH_CHECK_INTERVAL=0s\n")
+	require.ErrorContains(t, err, "HEALTH_CHECK_INTERVAL")
+
+	_, err = loadTestConfig(t, "HEALTH_CHECK_TIMEOUT=0s\n")
+	require.ErrorContains(t, err, "HEALTH_CHECK_TIMEOUT")
+}
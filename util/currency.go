@@ -1,17 +1,15 @@
 package util
 
-// Supported currencies
+import "github.com/MElghrbawy/simple_bank/money"
+
+// Currencies used by tests and seed data
 const (
 	USD = "USD"
 	EUR = "EUR"
 	CAD = "CAD"
 )
 
-// isSupportedCurrency checks if the currency is supported.
+// IsSupportedCurrency checks if the currency is an ISO 4217 currency we can hold accounts in.
 func IsSupportedCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, CAD:
-		return true
-	}
-	return false
+	return money.IsSupportedCurrency(currency)
 }
@@ -20,3 +20,12 @@ func CheckPasswordHash(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 
 }
+
+// unknownUserHash is a bcrypt hash at the default cost, which logins of unknown users are checked against.
+const unknownUserHash = "$2a$10$5brJWzzUH0T/ktS1yEGbYerpaxsnSeIbyKV.9Le2SyYcRBxzEyU.K"
+
+// CheckUnknownUserPassword takes as long as checking the password of an existing user,
+// so the response time of a login does not tell whether the username exists.
+func CheckUnknownUserPassword(password string) {
+	_ = CheckPasswordHash(password, unknownUserHash)
+}
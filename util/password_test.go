@@ -21,3 +21,10 @@ func TestPassword(t *testing.T) {
 	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
 
 }
+
+func TestUnknownUserHash(t *testing.T) {
+	// the hash must cost as much to check as the hashes of the users
+	cost, err := bcrypt.Cost([]byte(unknownUserHash))
+	require.NoError(t, err)
+	require.Equal(t, bcrypt.DefaultCost, cost)
+}
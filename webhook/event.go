@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/money"
+)
+
+// Event types a subscription can listen to.
+const (
+	EventTransferSent         = "transfer.sent"
+	EventTransferReceived     = "transfer.received"
+	EventAccountStatusChanged = "account.status_changed"
+)
+
+// EventTypes lists every event type a subscription can listen to.
+var EventTypes = []string{
+	EventTransferSent,
+	EventTransferReceived,
+	EventAccountStatusChanged,
+}
+
+// IsSupportedEventType returns true if subscriptions can listen to the event type.
+func IsSupportedEventType(eventType string) bool {
+	for _, supported := range EventTypes {
+		if eventType == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the body posted to the webhook endpoints.
+// Receivers should use ID to ignore events delivered more than once.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// TransferData is the data of the transfer events.
+type TransferData struct {
+	TransferID    int64        `json:"transfer_id"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        money.Amount `json:"amount"`
+	Fee           money.Amount `json:"fee"`
+}
+
+// AccountStatusData is the data of the account status events.
+type AccountStatusData struct {
+	AccountID int64  `json:"account_id"`
+	Status    string `json:"status"`
+}
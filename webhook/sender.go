@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Headers sent with every delivery besides the signature.
+const (
+	EventIDHeader   = "Webhook-Id"
+	EventTypeHeader = "Webhook-Event"
+)
+
+// StatusError is returned when the endpoint does not answer with a 2xx status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook endpoint responded with status %d", e.StatusCode)
+}
+
+// Retryable reports whether delivering again may succeed. Client errors are
+// final, except for timeouts and rate limiting.
+func (e *StatusError) Retryable() bool {
+	if e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return e.StatusCode < 400 || e.StatusCode >= 500
+}
+
+// Sender posts signed events to webhook endpoints.
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender creates a sender giving up on each request after timeout.
+// It only delivers over https and refuses to connect to loopback, private or link-local addresses,
+// including through redirects. Proxies are not used, as they would connect in its place.
+func NewSender(timeout time.Duration) *Sender {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return ErrInsecureURL
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	return &Sender{client: client}
+}
+
+// NewSenderWithClient creates a sender delivering with client as is, without the address
+// checks of NewSender. It is meant for tests delivering to local receivers.
+func NewSenderWithClient(client *http.Client) *Sender {
+	return &Sender{client: client}
+}
+
+// Send posts the event to url and returns the status code of the response,
+// which is zero when no response was received.
+func (sender *Sender) Send(ctx context.Context, url string, secret string, event Event) (int, error) {
+	if _, err := parseHTTPS(url); err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("cannot marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("cannot create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, fmt.Sprint(event.ID))
+	req.Header.Set(EventTypeHeader, event.Type)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	rsp, err := sender.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(rsp.Body, 64<<10))
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return rsp.StatusCode, &StatusError{StatusCode: rsp.StatusCode}
+	}
+	return rsp.StatusCode, nil
+}
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomEvent(t *testing.T) Event {
+	data, err := json.Marshal(AccountStatusData{AccountID: util.RandomInt(1, 1000), Status: "frozen"})
+	require.NoError(t, err)
+
+	return Event{
+		ID:        util.RandomInt(1, 1000),
+		Type:      EventAccountStatusChanged,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Data:      data,
+	}
+}
+
+func TestSend(t *testing.T) {
+	secret := util.RandomString(32)
+	event := randomEvent(t)
+
+	var received Event
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, Verify(secret, r.Header.Get(SignatureHeader), body, time.Minute))
+		require.Equal(t, event.Type, r.Header.Get(EventTypeHeader))
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	statusCode, err := NewSenderWithClient(receiver.Client()).Send(context.Background(), receiver.URL, secret, event)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, statusCode)
+	require.Equal(t, event.ID, received.ID)
+	require.Equal(t, event.Type, received.Type)
+	require.WithinDuration(t, event.CreatedAt, received.CreatedAt, time.Second)
+	require.JSONEq(t, string(event.Data), string(received.Data))
+}
+
+func TestSendErrorStatus(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+		retryable  bool
+	}{
+		{name: "ServerError", statusCode: http.StatusBadGateway, retryable: true},
+		{name: "TooManyRequests", statusCode: http.StatusTooManyRequests, retryable: true},
+		{name: "ClientError", statusCode: http.StatusGone, retryable: false},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCode)
+			}))
+			defer receiver.Close()
+
+			statusCode, err := NewSenderWithClient(receiver.Client()).Send(context.Background(), receiver.URL, util.RandomString(32), randomEvent(t))
+			require.Equal(t, tc.statusCode, statusCode)
+
+			var statusErr *StatusError
+			require.True(t, errors.As(err, &statusErr))
+			require.Equal(t, tc.retryable, statusErr.Retryable())
+		})
+	}
+}
+
+func TestSendForbiddenEndpoint(t *testing.T) {
+	called := false
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	// the receiver listens on 127.0.0.1, which the dialer refuses
+	statusCode, err := NewSender(time.Second).Send(context.Background(), receiver.URL, util.RandomString(32), randomEvent(t))
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+	require.Zero(t, statusCode)
+
+	_, err = NewSender(time.Second).Send(context.Background(), "http://example.com/hook", util.RandomString(32), randomEvent(t))
+	require.ErrorIs(t, err, ErrInsecureURL)
+	require.False(t, called)
+}
+
+func TestValidateURL(t *testing.T) {
+	testCases := []struct {
+		url string
+		err error
+	}{
+		{url: "https://example.com/hook"},
+		{url: "https://93.184.216.34:8443/hook"},
+		{url: "http://example.com/hook", err: ErrInsecureURL},
+		{url: "ftp://example.com/hook", err: ErrInsecureURL},
+		{url: "https://localhost/hook", err: ErrForbiddenAddress},
+		{url: "https://127.0.0.1/hook", err: ErrForbiddenAddress},
+		{url: "https://10.1.2.3/hook", err: ErrForbiddenAddress},
+		{url: "https://192.168.0.1/hook", err: ErrForbiddenAddress},
+		{url: "https://169.254.169.254/latest/meta-data", err: ErrForbiddenAddress},
+		{url: "https://100.64.0.1/hook", err: ErrForbiddenAddress},
+		{url: "https://[::1]/hook", err: ErrForbiddenAddress},
+		{url: "https://[fd00::1]/hook", err: ErrForbiddenAddress},
+		{url: "https://[::ffff:127.0.0.1]/hook", err: ErrForbiddenAddress},
+		{url: "https://0.0.0.0/hook", err: ErrForbiddenAddress},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateURL(tc.url)
+		if tc.err == nil {
+			require.NoError(t, err, tc.url)
+		} else {
+			require.ErrorIs(t, err, tc.err, tc.url)
+		}
+	}
+
+	require.Error(t, ValidateURL("https:///hook"))
+}
+
+func TestDialControl(t *testing.T) {
+	require.NoError(t, dialControl("tcp4", "93.184.216.34:443", nil))
+	require.NoError(t, dialControl("tcp6", "[2606:2800:220:1:248:1893:25c8:1946]:443", nil))
+
+	for _, address := range []string{"127.0.0.1:443", "10.0.0.1:443", "172.16.0.1:443", "169.254.169.254:80", "[::1]:443", "[fe80::1]:443"} {
+		require.ErrorIs(t, dialControl("tcp", address, nil), ErrForbiddenAddress, address)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := util.RandomString(32)
+	body := []byte(`{"id":1}`)
+
+	header := Sign(secret, time.Now(), body)
+	require.NoError(t, Verify(secret, header, body, time.Minute))
+	require.ErrorIs(t, Verify(util.RandomString(32), header, body, time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, header, []byte(`{"id":2}`), time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, "v1=abc", body, time.Minute), ErrInvalidSignature)
+
+	old := Sign(secret, time.Now().Add(-time.Hour), body)
+	require.ErrorIs(t, Verify(secret, old, body, time.Minute), ErrInvalidSignature)
+}
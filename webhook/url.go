@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+var (
+	// ErrInsecureURL is returned for endpoints that are not served over https.
+	ErrInsecureURL = errors.New("webhook url must use https")
+	// ErrForbiddenAddress is returned for endpoints on loopback, private or link-local addresses,
+	// which would let subscribers reach the internal network of the bank.
+	ErrForbiddenAddress = errors.New("webhook endpoint must have a public address")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, private although net/netip does not say so.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// ValidateURL checks that rawURL is an https url whose host is not obviously internal.
+// Hostnames are only resolved when delivering, where the dialer refuses forbidden addresses.
+func ValidateURL(rawURL string) error {
+	u, err := parseHTTPS(rawURL)
+	if err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("webhook url must have a host")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && forbiddenAddr(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// parseHTTPS parses rawURL and checks it uses https.
+func parseHTTPS(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, ErrInsecureURL
+	}
+	return u, nil
+}
+
+// forbiddenAddr reports whether webhooks must not be delivered to addr.
+func forbiddenAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsGlobalUnicast() ||
+		addr.IsPrivate() ||
+		addr.IsLoopback() ||
+		addr.IsLinkLocalUnicast() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// dialControl refuses connections to forbidden addresses. It runs after the host is
+// resolved, so a hostname pointing at an internal address is refused as well.
+func dialControl(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if forbiddenAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+)
+
+const auditChainBatchSize = 500
+
+// RunAuditChainer appends the staged audit events to the hash chain of the audit log,
+// polling the store every interval until ctx is done. A single chainer runs per process,
+// and the head of the chain keeps the chainers of several processes apart.
+func RunAuditChainer(ctx context.Context, store db.Store, interval time.Duration) {
+	runRelay(ctx, "audit_chainer", auditChainBatchSize, interval, func() (int, error) {
+		return store.ChainAuditEventsTx(ctx, auditChainBatchSize)
+	})
+}
@@ -3,7 +3,9 @@ package worker
 import (
 	"context"
 
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
 	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
 )
 
 type TaskDistributor interface {
@@ -12,13 +14,40 @@ type TaskDistributor interface {
 		payload *PayloadSendVerifyEmail,
 		opts ...asynq.Option,
 	) error
+	DistributeTaskDeliverWebhook(
+		ctx context.Context,
+		payload *PayloadDeliverWebhook,
+		opts ...asynq.Option,
+	) error
+	DistributeOutboxTask(ctx context.Context, task db.TaskOutbox) error
+}
+
+// distributor builds the tasks of the TaskDistributor methods and hands them to
+// enqueue, which is implemented by each queue.
+type distributor struct {
+	enqueue func(ctx context.Context, task *asynq.Task, opts ...asynq.Option) error
 }
 
 type RedisTaskDistributor struct {
+	distributor
 	client *asynq.Client
 }
 
 func NewRedisTaskDistributor(redisOpt asynq.RedisClientOpt) TaskDistributor {
 	client := asynq.NewClient(redisOpt)
-	return &RedisTaskDistributor{client: client}
+	d := &RedisTaskDistributor{client: client}
+	d.distributor.enqueue = d.enqueue
+	return d
+}
+
+// enqueue sends the task to Redis. The options are already part of the task.
+func (d *RedisTaskDistributor) enqueue(ctx context.Context, task *asynq.Task, _ ...asynq.Option) error {
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return err
+	}
+	log.Ctx(ctx).Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", info.Queue).Int("max_retry", info.MaxRetry).
+		Msg("task enqueued")
+	return nil
 }
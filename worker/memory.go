@@ -0,0 +1,294 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/webhook"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+// The asynq defaults of the task options.
+const (
+	memoryDefaultMaxRetry = 25
+	memoryDefaultTimeout  = 30 * time.Minute
+	memoryConcurrency     = 10
+)
+
+type TaskState string
+
+const (
+	TaskStatePending   TaskState = "pending"
+	TaskStateCompleted TaskState = "completed"
+	TaskStateArchived  TaskState = "archived"
+)
+
+// MemoryTask is a task given to a MemoryTaskQueue.
+type MemoryTask struct {
+	ID       string
+	Type     string
+	Payload  []byte
+	Queue    string
+	MaxRetry int
+	Retried  int
+	State    TaskState
+	LastErr  string
+}
+
+// MemoryTaskQueue is a TaskDistributor and TaskProcessor running the tasks in process,
+// for servers and tests without Redis. Failed tasks are retried like asynq does:
+// up to their MaxRetry with the same delays, unless the error wraps asynq.SkipRetry.
+// Tasks are lost on exit, and every task is kept in memory so tests can inspect them.
+type MemoryTaskQueue struct {
+	distributor
+	taskHandlers
+	handler    *asynq.ServeMux
+	retryDelay asynq.RetryDelayFunc
+
+	mu      sync.Mutex
+	started bool
+	tasks   []*MemoryTask
+	ids     map[string]bool
+	held    []*MemoryTask
+	pending int
+	idle    chan struct{}
+	slots   chan struct{}
+}
+
+type MemoryQueueOption func(*MemoryTaskQueue)
+
+// WithRetryDelay replaces the delay before a failed task is retried.
+func WithRetryDelay(retryDelay asynq.RetryDelayFunc) MemoryQueueOption {
+	return func(q *MemoryTaskQueue) {
+		q.retryDelay = retryDelay
+	}
+}
+
+// WithTaskMiddleware runs the tasks through the middlewares.
+func WithTaskMiddleware(middlewares ...asynq.MiddlewareFunc) MemoryQueueOption {
+	return func(q *MemoryTaskQueue) {
+		q.middlewares = append(q.middlewares, middlewares...)
+	}
+}
+
+// WithWebhookSender replaces the sender delivering the webhooks.
+func WithWebhookSender(sender *webhook.Sender) MemoryQueueOption {
+	return func(q *MemoryTaskQueue) {
+		q.webhookSender = sender
+	}
+}
+
+func NewMemoryTaskQueue(store db.Store, opts ...MemoryQueueOption) *MemoryTaskQueue {
+	q := &MemoryTaskQueue{
+		taskHandlers: newTaskHandlers(store),
+		retryDelay:   retryDelay,
+		ids:          make(map[string]bool),
+		idle:         make(chan struct{}),
+		slots:        make(chan struct{}, memoryConcurrency),
+	}
+	close(q.idle)
+	q.distributor.enqueue = q.enqueue
+	for _, opt := range opts {
+		opt(q)
+	}
+	q.handler = q.mux()
+	return q
+}
+
+// enqueue schedules the task. Tasks enqueued before Start are processed once it is called.
+func (q *MemoryTaskQueue) enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	memTask := &MemoryTask{
+		ID:       uuid.NewString(),
+		Type:     task.Type(),
+		Payload:  task.Payload(),
+		Queue:    "default",
+		MaxRetry: memoryDefaultMaxRetry,
+		State:    TaskStatePending,
+	}
+	var delay time.Duration
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.MaxRetryOpt:
+			memTask.MaxRetry = max(opt.Value().(int), 0)
+		case asynq.QueueOpt:
+			memTask.Queue = opt.Value().(string)
+		case asynq.TaskIDOpt:
+			memTask.ID = opt.Value().(string)
+		case asynq.ProcessInOpt:
+			delay = opt.Value().(time.Duration)
+		case asynq.ProcessAtOpt:
+			delay = time.Until(opt.Value().(time.Time))
+		}
+	}
+
+	q.mu.Lock()
+	if q.ids[memTask.ID] {
+		q.mu.Unlock()
+		return asynq.ErrTaskIDConflict
+	}
+	q.ids[memTask.ID] = true
+	q.tasks = append(q.tasks, memTask)
+	if q.pending == 0 {
+		q.idle = make(chan struct{})
+	}
+	q.pending++
+	q.mu.Unlock()
+
+	log.Ctx(ctx).Info().Str("type", memTask.Type).Bytes("payload", memTask.Payload).
+		Str("queue", memTask.Queue).Int("max_retry", memTask.MaxRetry).
+		Msg("task enqueued")
+	q.schedule(memTask, delay)
+	return nil
+}
+
+// Start processes the tasks enqueued so far and the ones enqueued from now on.
+func (q *MemoryTaskQueue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.started {
+		return errors.New("task processor already started")
+	}
+	q.started = true
+	for _, task := range q.held {
+		go q.process(task)
+	}
+	q.held = nil
+	return nil
+}
+
+// Shutdown stops processing tasks and waits for the tasks being processed.
+// The tasks left in the queue are lost.
+func (q *MemoryTaskQueue) Shutdown() {
+	q.mu.Lock()
+	q.started = false
+	q.mu.Unlock()
+
+	// the tasks being processed hold a slot until they return
+	for i := 0; i < cap(q.slots); i++ {
+		q.slots <- struct{}{}
+	}
+}
+
+func (q *MemoryTaskQueue) schedule(task *MemoryTask, delay time.Duration) {
+	if delay > 0 {
+		time.AfterFunc(delay, func() { q.schedule(task, 0) })
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.started {
+		q.held = append(q.held, task)
+		return
+	}
+	go q.process(task)
+}
+
+func (q *MemoryTaskQueue) process(task *MemoryTask) {
+	q.slots <- struct{}{}
+	defer func() { <-q.slots }()
+
+	q.mu.Lock()
+	retried := task.Retried
+	q.mu.Unlock()
+
+	asynqTask := asynq.NewTask(task.Type, task.Payload)
+	err := q.handle(asynqTask, retried)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err == nil {
+		task.State = TaskStateCompleted
+		q.done()
+		return
+	}
+
+	task.LastErr = err.Error()
+	if task.Retried >= task.MaxRetry || errors.Is(err, asynq.SkipRetry) {
+		log.Ctx(payloadContext(context.Background(), task.Payload)).Error().Err(err).
+			Str("type", task.Type).Bytes("payload", task.Payload).Msg("task archived")
+		task.State = TaskStateArchived
+		q.done()
+		return
+	}
+
+	delay := q.retryDelay(task.Retried, err, asynqTask)
+	task.Retried++
+	log.Ctx(payloadContext(context.Background(), task.Payload)).Error().Err(err).
+		Str("type", task.Type).Bytes("payload", task.Payload).Dur("retry_in", delay).Msg("task failed")
+	time.AfterFunc(delay, func() { q.schedule(task, 0) })
+}
+
+// handle runs the handler of the task, turning a panic into an error as asynq does.
+func (q *MemoryTaskQueue) handle(task *asynq.Task, retried int) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("panic: %v", x)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), memoryDefaultTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, retryCountKey{}, retried)
+
+	return q.handler.ProcessTask(ctx, task)
+}
+
+func (q *MemoryTaskQueue) done() {
+	q.pending--
+	if q.pending == 0 {
+		close(q.idle)
+	}
+}
+
+// Wait blocks until every task enqueued so far is completed or archived, including
+// the tasks waiting to be retried, or until ctx is done.
+func (q *MemoryTaskQueue) Wait(ctx context.Context) error {
+	q.mu.Lock()
+	idle := q.idle
+	q.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Tasks returns a copy of every task enqueued so far, in enqueue order.
+func (q *MemoryTaskQueue) Tasks() []MemoryTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tasks := make([]MemoryTask, len(q.tasks))
+	for i, task := range q.tasks {
+		tasks[i] = *task
+	}
+	return tasks
+}
+
+type retryCountKey struct{}
+
+// retryCount returns how many times the task being processed was retried,
+// whichever queue it was taken from.
+func retryCount(ctx context.Context) int {
+	if retried, ok := ctx.Value(retryCountKey{}).(int); ok {
+		return retried
+	}
+	retried, _ := asynq.GetRetryCount(ctx)
+	return retried
+}
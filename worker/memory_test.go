@@ -0,0 +1,246 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/util"
+	"github.com/MElghrbawy/simple_bank/webhook"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryTaskQueue(t *testing.T, store db.Store, opts ...MemoryQueueOption) *MemoryTaskQueue {
+	opts = append([]MemoryQueueOption{WithRetryDelay(func(int, error, *asynq.Task) time.Duration {
+		return time.Millisecond
+	})}, opts...)
+	q := NewMemoryTaskQueue(store, opts...)
+	require.NoError(t, q.Start())
+	return q
+}
+
+func waitForTasks(t *testing.T, q *MemoryTaskQueue) []MemoryTask {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, q.Wait(ctx))
+	return q.Tasks()
+}
+
+func createRandomUser(t *testing.T, store db.Store) db.User {
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func createWebhookDelivery(t *testing.T, store db.Store, url string) (db.WebhookSubscription, db.WebhookEvent) {
+	user := createRandomUser(t, store)
+
+	subscription, err := store.CreateWebhookSubscription(context.Background(), db.CreateWebhookSubscriptionParams{
+		Owner:      user.Username,
+		Url:        url,
+		Secret:     util.RandomString(32),
+		EventTypes: []string{webhook.EventAccountStatusChanged},
+	})
+	require.NoError(t, err)
+
+	event, err := store.CreateWebhookEvent(context.Background(), db.CreateWebhookEventParams{
+		Owner:     user.Username,
+		EventType: webhook.EventAccountStatusChanged,
+		Payload:   []byte(`{"account_id":1,"status":"frozen"}`),
+	})
+	require.NoError(t, err)
+
+	return subscription, event
+}
+
+func listDeliveryAttempts(t *testing.T, store db.Store, subscriptionID int64) []int32 {
+	deliveries, err := store.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{
+		SubscriptionID: subscriptionID,
+		Limit:          100,
+	})
+	require.NoError(t, err)
+
+	attempts := make([]int32, len(deliveries))
+	for i, delivery := range deliveries {
+		attempts[i] = delivery.Attempt
+	}
+	return attempts
+}
+
+func TestMemoryTaskQueueSendVerifyEmail(t *testing.T) {
+	store := db.NewMemStore()
+	user := createRandomUser(t, store)
+
+	q := NewMemoryTaskQueue(store)
+	err := q.DistributeTaskSendEmail(context.Background(), &PayloadSendVerifyEmail{Username: user.Username},
+		asynq.MaxRetry(3), asynq.Queue("critical"))
+	require.NoError(t, err)
+
+	tasks := q.Tasks()
+	require.Len(t, tasks, 1)
+	require.Equal(t, TaskSendVerifyEmail, tasks[0].Type)
+	require.Equal(t, "critical", tasks[0].Queue)
+	require.Equal(t, 3, tasks[0].MaxRetry)
+	require.Equal(t, TaskStatePending, tasks[0].State)
+
+	require.NoError(t, q.Start())
+	tasks = waitForTasks(t, q)
+	require.Equal(t, TaskStateCompleted, tasks[0].State)
+	require.Zero(t, tasks[0].Retried)
+}
+
+func TestMemoryTaskQueueUserNotFound(t *testing.T) {
+	q := newTestMemoryTaskQueue(t, db.NewMemStore())
+
+	err := q.DistributeTaskSendEmail(context.Background(), &PayloadSendVerifyEmail{Username: util.RandomOwner()},
+		asynq.MaxRetry(1))
+	require.NoError(t, err)
+
+	tasks := waitForTasks(t, q)
+	require.Len(t, tasks, 1)
+	require.Equal(t, TaskStateArchived, tasks[0].State)
+	require.Equal(t, 1, tasks[0].Retried)
+	require.Contains(t, tasks[0].LastErr, "user not found")
+}
+
+func TestMemoryTaskQueuePrimaryReads(t *testing.T) {
+	var primary bool
+	handler := withPrimaryReads(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		primary = db.PrimaryReads(ctx)
+		return nil
+	}))
+
+	err := handler.ProcessTask(context.Background(), asynq.NewTask(TaskSendVerifyEmail, nil))
+	require.NoError(t, err)
+	require.True(t, primary)
+}
+
+func TestMemoryTaskQueueRetry(t *testing.T) {
+	var requests atomic.Int32
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	store := db.NewMemStore()
+	subscription, event := createWebhookDelivery(t, store, receiver.URL)
+
+	q := newTestMemoryTaskQueue(t, store, WithWebhookSender(webhook.NewSenderWithClient(receiver.Client())))
+	err := q.DistributeTaskDeliverWebhook(context.Background(), &PayloadDeliverWebhook{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+	})
+	require.NoError(t, err)
+
+	tasks := waitForTasks(t, q)
+	require.Len(t, tasks, 1)
+	require.Equal(t, TaskStateCompleted, tasks[0].State)
+	require.Equal(t, 2, tasks[0].Retried)
+	require.Equal(t, []int32{3, 2, 1}, listDeliveryAttempts(t, store, subscription.ID))
+}
+
+func TestMemoryTaskQueueRetryExhausted(t *testing.T) {
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := db.NewMemStore()
+	subscription, event := createWebhookDelivery(t, store, receiver.URL)
+
+	q := newTestMemoryTaskQueue(t, store, WithWebhookSender(webhook.NewSenderWithClient(receiver.Client())))
+	err := q.DistributeTaskDeliverWebhook(context.Background(), &PayloadDeliverWebhook{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+	}, asynq.MaxRetry(2))
+	require.NoError(t, err)
+
+	tasks := waitForTasks(t, q)
+	require.Len(t, tasks, 1)
+	require.Equal(t, TaskStateArchived, tasks[0].State)
+	require.Equal(t, 2, tasks[0].Retried)
+	require.Equal(t, []int32{3, 2, 1}, listDeliveryAttempts(t, store, subscription.ID))
+}
+
+func TestMemoryTaskQueueForbiddenEndpoint(t *testing.T) {
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	store := db.NewMemStore()
+	subscription, event := createWebhookDelivery(t, store, receiver.URL)
+
+	q := newTestMemoryTaskQueue(t, store)
+	err := q.DistributeTaskDeliverWebhook(context.Background(), &PayloadDeliverWebhook{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+	})
+	require.NoError(t, err)
+
+	tasks := waitForTasks(t, q)
+	require.Len(t, tasks, 1)
+	require.Equal(t, TaskStateArchived, tasks[0].State)
+	require.Zero(t, tasks[0].Retried)
+	require.Contains(t, tasks[0].LastErr, webhook.ErrForbiddenAddress.Error())
+}
+
+func TestMemoryTaskQueueShutdown(t *testing.T) {
+	received := make(chan struct{})
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer receiver.Close()
+
+	store := db.NewMemStore()
+	subscription, event := createWebhookDelivery(t, store, receiver.URL)
+
+	q := newTestMemoryTaskQueue(t, store, WithWebhookSender(webhook.NewSenderWithClient(receiver.Client())))
+	err := q.DistributeTaskDeliverWebhook(context.Background(), &PayloadDeliverWebhook{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+	})
+	require.NoError(t, err)
+
+	<-received
+	q.Shutdown()
+
+	// the delivery in flight finished before Shutdown returned
+	tasks := q.Tasks()
+	require.Len(t, tasks, 1)
+	require.Equal(t, TaskStateCompleted, tasks[0].State)
+}
+
+func TestMemoryTaskQueueOutboxTaskID(t *testing.T) {
+	store := db.NewMemStore()
+	user := createRandomUser(t, store)
+
+	arg, err := NewOutboxTaskSendVerifyEmail(context.Background(), &PayloadSendVerifyEmail{Username: user.Username})
+	require.NoError(t, err)
+	outboxTask, err := store.CreateOutboxTask(context.Background(), arg)
+	require.NoError(t, err)
+
+	q := newTestMemoryTaskQueue(t, store)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, q.DistributeOutboxTask(context.Background(), outboxTask))
+	}
+
+	tasks := waitForTasks(t, q)
+	require.Len(t, tasks, 1)
+	require.Equal(t, fmt.Sprintf("outbox:%d", outboxTask.ID), tasks[0].ID)
+	require.Equal(t, 10, tasks[0].MaxRetry)
+	require.Equal(t, TaskStateCompleted, tasks[0].State)
+}
@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	outboxRelayBatchSize = 100
+	// outboxEnqueueTimeout bounds the enqueue of a single outbox task, so an unresponsive
+	// Redis fails the task instead of stalling the relay.
+	outboxEnqueueTimeout = 5 * time.Second
+	// outboxTaskRetention keeps completed outbox tasks in Redis, so their task id
+	// still rejects a duplicate enqueue by a relay that failed to mark them dispatched.
+	outboxTaskRetention = 24 * time.Hour
+)
+
+// NewOutboxTaskSendVerifyEmail builds the outbox row of a send verify email task,
+// carrying the trace of ctx.
+func NewOutboxTaskSendVerifyEmail(ctx context.Context, payload *PayloadSendVerifyEmail) (db.CreateOutboxTaskParams, error) {
+	return newOutboxTask(ctx, TaskSendVerifyEmail, payload, 10)
+}
+
+func newOutboxTask(ctx context.Context, taskType string, payload any, maxRetry int32) (db.CreateOutboxTaskParams, error) {
+	payloadBytes, err := marshalPayload(ctx, payload)
+	if err != nil {
+		return db.CreateOutboxTaskParams{}, err
+	}
+
+	task := db.CreateOutboxTaskParams{
+		TaskType: taskType,
+		Payload:  payloadBytes,
+		Queue:    "default",
+		MaxRetry: maxRetry,
+	}
+	return task, nil
+}
+
+func (d *distributor) DistributeOutboxTask(ctx context.Context, outboxTask db.TaskOutbox) error {
+	opts := []asynq.Option{
+		asynq.TaskID(fmt.Sprintf("outbox:%d", outboxTask.ID)),
+		asynq.Queue(outboxTask.Queue),
+		asynq.MaxRetry(int(outboxTask.MaxRetry)),
+		asynq.Retention(outboxTaskRetention),
+	}
+	task := asynq.NewTask(outboxTask.TaskType, outboxTask.Payload, opts...)
+
+	err := d.enqueue(ctx, task, opts...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		log.Ctx(ctx).Info().Str("type", task.Type()).Int64("outbox_id", outboxTask.ID).
+			Msg("outbox task already enqueued")
+		return nil
+	}
+	return err
+}
+
+// RunOutboxRelay enqueues the pending outbox tasks, polling the store every
+// interval until ctx is done. Each task is enqueued with an id derived from its
+// outbox row, so a task enqueued again after a failure to mark it dispatched is
+// rejected by asynq instead of processed twice.
+func RunOutboxRelay(ctx context.Context, store db.Store, distributor TaskDistributor, interval time.Duration) {
+	runRelay(ctx, "outbox", outboxRelayBatchSize, interval, func() (int, error) {
+		return store.DispatchOutboxTasks(ctx, db.DispatchOutboxTasksParams{
+			Limit: outboxRelayBatchSize,
+			Dispatch: func(task db.TaskOutbox) error {
+				ctx, cancel := context.WithTimeout(ctx, outboxEnqueueTimeout)
+				defer cancel()
+				return distributor.DistributeOutboxTask(ctx, task)
+			},
+		})
+	})
+}
+
+// runRelay calls dispatch every interval until ctx is done, and right away
+// again while it dispatches full batches.
+func runRelay(ctx context.Context, name string, batchSize int, interval time.Duration, dispatch func() (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		dispatched, err := dispatch()
+		if err != nil {
+			log.Error().Err(err).Str("relay", name).Msg("cannot dispatch")
+		}
+
+		if err == nil && dispatched == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+)
+
+const pendingTransferExpiryBatchSize = 100
+
+// RunPendingTransferExpiry marks expired the pending transfers nobody approved or rejected in time,
+// polling the store every interval until ctx is done.
+func RunPendingTransferExpiry(ctx context.Context, store db.Store, interval time.Duration) {
+	runRelay(ctx, "pending_transfer_expiry", pendingTransferExpiryBatchSize, interval, func() (int, error) {
+		return store.ExpirePendingTransfersTx(ctx, pendingTransferExpiryBatchSize)
+	})
+}
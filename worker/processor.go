@@ -4,29 +4,75 @@ import (
 	"context"
 
 	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/webhook"
 	"github.com/hibiken/asynq"
 )
 
 type TaskProcessor interface {
 	Start() error
+	// Shutdown stops taking tasks and waits for the tasks being processed.
+	Shutdown()
 	ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error
+	ProcessTaskDeliverWebhook(ctx context.Context, task *asynq.Task) error
+}
+
+// taskHandlers processes the tasks, whichever queue they were taken from.
+type taskHandlers struct {
+	store         db.Store
+	webhookSender *webhook.Sender
+	// middlewares wrap the handlers, e.g. to instrument them.
+	middlewares []asynq.MiddlewareFunc
+}
+
+func newTaskHandlers(store db.Store) taskHandlers {
+	return taskHandlers{
+		store:         store,
+		webhookSender: webhook.NewSender(webhookRequestTimeout),
+	}
+}
+
+func (p *taskHandlers) mux() *asynq.ServeMux {
+	mux := asynq.NewServeMux()
+	mux.Use(withTaskContext, withPrimaryReads)
+	mux.Use(p.middlewares...)
+	mux.HandleFunc(TaskSendVerifyEmail, p.ProcessTaskSendVerifyEmail)
+	mux.HandleFunc(TaskDeliverWebhook, p.ProcessTaskDeliverWebhook)
+	return mux
+}
+
+// withPrimaryReads serves the reads of the tasks from the primary database: tasks are enqueued
+// right after the writes they follow up on, which the read replica may not have replayed yet.
+func withPrimaryReads(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		return handler.ProcessTask(db.WithPrimaryReads(ctx), task)
+	})
 }
 
 type RedisTaskProcessor struct {
+	taskHandlers
 	server *asynq.Server
-	store  db.Store
 }
 
-func NewRedisTaskProcessor(redisOpt *asynq.RedisClientOpt, store db.Store) TaskProcessor {
+// NewRedisTaskProcessor creates a processor of the tasks queued in Redis,
+// running them through the middlewares.
+func NewRedisTaskProcessor(redisOpt *asynq.RedisClientOpt, store db.Store, middlewares ...asynq.MiddlewareFunc) TaskProcessor {
 
-	server := asynq.NewServer(redisOpt, asynq.Config{})
-	return &RedisTaskProcessor{server: server, store: store}
+	server := asynq.NewServer(redisOpt, asynq.Config{
+		RetryDelayFunc: retryDelay,
+	})
+	handlers := newTaskHandlers(store)
+	handlers.middlewares = middlewares
+	return &RedisTaskProcessor{
+		taskHandlers: handlers,
+		server:       server,
+	}
 }
 
 func (p *RedisTaskProcessor) Start() error {
-	mux := asynq.NewServeMux()
-	mux.HandleFunc(TaskSendVerifyEmail, p.ProcessTaskSendVerifyEmail)
+	return p.server.Start(p.mux())
 
-	return p.server.Start(mux)
+}
 
+func (p *RedisTaskProcessor) Shutdown() {
+	p.server.Shutdown()
 }
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/MElghrbawy/simple_bank/requestid"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/MElghrbawy/simple_bank/worker"
+
+// TaskContext is embedded in the task payloads to carry the request that created the task:
+// its trace, so processing the task joins that trace, and its ID, so the log lines of the task
+// can be correlated with the request's, even when the task was enqueued long after, through the outbox.
+type TaskContext struct {
+	TraceContext propagation.MapCarrier `json:"trace_context,omitempty"`
+	RequestID    string                 `json:"request_id,omitempty"`
+}
+
+func (t *TaskContext) setTaskContext(ctx context.Context) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		t.TraceContext = carrier
+	}
+	t.RequestID, _ = requestid.FromContext(ctx)
+}
+
+// contextPayload is implemented by the payloads embedding a TaskContext.
+type contextPayload interface {
+	setTaskContext(ctx context.Context)
+}
+
+// marshalPayload encodes payload, with the request of ctx if it carries one.
+func marshalPayload(ctx context.Context, payload any) ([]byte, error) {
+	if p, ok := payload.(contextPayload); ok {
+		p.setTaskContext(ctx)
+	}
+	return json.Marshal(payload)
+}
+
+// withTaskContext processes a task in the context of the request carried by its payload:
+// in a span continuing its trace, and logging with its request ID.
+func withTaskContext(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		ctx, span := otel.Tracer(tracerName).Start(payloadContext(ctx, task.Payload()), "process "+task.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				semconv.MessagingSystemKey.String("asynq"),
+				semconv.MessagingOperationDeliver,
+				attribute.String("task.type", task.Type()),
+				attribute.Int("task.retry_count", retryCount(ctx)),
+			),
+		)
+		defer span.End()
+
+		err := handler.ProcessTask(ctx, task)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.SetAttributes(attribute.Bool("task.skip_retry", errors.Is(err, asynq.SkipRetry)))
+		}
+		return err
+	})
+}
+
+// payloadContext returns a copy of ctx carrying the request of a task payload.
+func payloadContext(ctx context.Context, payload []byte) context.Context {
+	var taskContext TaskContext
+	if err := json.Unmarshal(payload, &taskContext); err != nil {
+		return ctx
+	}
+
+	if taskContext.TraceContext != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, taskContext.TraceContext)
+	}
+	if requestid.Valid(taskContext.RequestID) {
+		ctx = requestid.NewContext(ctx, taskContext.RequestID)
+	}
+	return ctx
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/requestid"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOutboxTaskTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	store := db.NewMemStore()
+	user := createRandomUser(t, store)
+
+	ctx, createUser := otel.Tracer("test").Start(context.Background(), "CreateUser")
+	arg, err := NewOutboxTaskSendVerifyEmail(ctx, &PayloadSendVerifyEmail{Username: user.Username})
+	require.NoError(t, err)
+	createUser.End()
+	require.Contains(t, string(arg.Payload), `"trace_context":{"traceparent":`)
+
+	outboxTask, err := store.CreateOutboxTask(context.Background(), arg)
+	require.NoError(t, err)
+
+	// the relay enqueues the task outside of the request
+	q := newTestMemoryTaskQueue(t, store)
+	require.NoError(t, q.DistributeOutboxTask(context.Background(), outboxTask))
+	tasks := waitForTasks(t, q)
+	require.Equal(t, TaskStateCompleted, tasks[0].State)
+
+	var process sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "process "+TaskSendVerifyEmail {
+			process = span
+		}
+	}
+	require.NotNil(t, process)
+	require.Equal(t, createUser.SpanContext().TraceID(), process.SpanContext().TraceID())
+	require.Equal(t, createUser.SpanContext().SpanID(), process.Parent().SpanID())
+	require.Equal(t, trace.SpanKindConsumer, process.SpanKind())
+}
+
+func TestTaskWithoutTraceContext(t *testing.T) {
+	arg, err := NewOutboxTaskSendVerifyEmail(context.Background(), &PayloadSendVerifyEmail{Username: "alice"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"username":"alice"}`, string(arg.Payload))
+}
+
+func TestTaskRequestID(t *testing.T) {
+	ctx := requestid.NewContext(context.Background(), "checkout-42")
+	arg, err := NewOutboxTaskSendVerifyEmail(ctx, &PayloadSendVerifyEmail{Username: "alice"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"username":"alice","request_id":"checkout-42"}`, string(arg.Payload))
+
+	id, ok := requestid.FromContext(payloadContext(context.Background(), arg.Payload))
+	require.True(t, ok)
+	require.Equal(t, "checkout-42", id)
+
+	_, ok = requestid.FromContext(payloadContext(context.Background(), []byte(`{"username":"alice"}`)))
+	require.False(t, ok)
+}
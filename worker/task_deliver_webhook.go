@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+	"github.com/MElghrbawy/simple_bank/webhook"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskDeliverWebhook = "task:deliver_webhook"
+
+const (
+	webhookMaxRetry       = 10
+	webhookBaseDelay      = 10 * time.Second
+	webhookMaxDelay       = 6 * time.Hour
+	webhookRequestTimeout = 10 * time.Second
+)
+
+type PayloadDeliverWebhook struct {
+	SubscriptionID int64 `json:"subscription_id"`
+	EventID        int64 `json:"event_id"`
+	TaskContext
+}
+
+func (d *distributor) DistributeTaskDeliverWebhook(
+	ctx context.Context,
+	payload *PayloadDeliverWebhook,
+	opts ...asynq.Option,
+) error {
+	payloadBytes, err := marshalPayload(ctx, payload)
+	if err != nil {
+		return err
+	}
+	opts = append([]asynq.Option{asynq.MaxRetry(webhookMaxRetry)}, opts...)
+	task := asynq.NewTask(TaskDeliverWebhook, payloadBytes, opts...)
+
+	return d.enqueue(ctx, task, opts...)
+}
+
+func (p *taskHandlers) ProcessTaskDeliverWebhook(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadDeliverWebhook
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("could not unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	subscription, err := p.store.GetWebhookSubscription(ctx, payload.SubscriptionID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return fmt.Errorf("subscription was deleted: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("could not get subscription: %w", err)
+	}
+
+	event, err := p.store.GetWebhookEvent(ctx, payload.EventID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return fmt.Errorf("event not found: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("could not get event: %w", err)
+	}
+
+	retried := retryCount(ctx)
+	statusCode, sendErr := p.webhookSender.Send(ctx, subscription.Url, subscription.Secret, webhook.Event{
+		ID:        event.ID,
+		Type:      event.EventType,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+
+	arg := db.CreateWebhookDeliveryParams{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		Attempt:        int32(retried + 1),
+		StatusCode:     int32(statusCode),
+		Succeeded:      sendErr == nil,
+	}
+	if sendErr != nil {
+		arg.Error = sendErr.Error()
+	}
+
+	if _, err := p.store.CreateWebhookDelivery(ctx, arg); err != nil {
+		log.Ctx(ctx).Error().Err(err).Int64("event_id", event.ID).Msg("could not record webhook delivery")
+	}
+
+	if sendErr != nil {
+		var statusErr *webhook.StatusError
+		if errors.As(sendErr, &statusErr) && !statusErr.Retryable() {
+			return fmt.Errorf("webhook delivery rejected: %v: %w", sendErr, asynq.SkipRetry)
+		}
+		if errors.Is(sendErr, webhook.ErrInsecureURL) || errors.Is(sendErr, webhook.ErrForbiddenAddress) {
+			return fmt.Errorf("webhook endpoint refused: %v: %w", sendErr, asynq.SkipRetry)
+		}
+		return fmt.Errorf("could not deliver webhook: %w", sendErr)
+	}
+
+	log.Ctx(ctx).Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Int("status_code", statusCode).Msg("processed task")
+	return nil
+}
+
+// webhookRetryDelay backs off exponentially from webhookBaseDelay up to webhookMaxDelay,
+// with up to 20% jitter so failed deliveries to one endpoint do not retry in lockstep.
+func webhookRetryDelay(n int) time.Duration {
+	delay := webhookMaxDelay
+	if n < 20 {
+		delay = min(webhookBaseDelay<<n, webhookMaxDelay)
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// retryDelay picks the retry delay of a failed task by type.
+func retryDelay(n int, err error, task *asynq.Task) time.Duration {
+	if task.Type() == TaskDeliverWebhook {
+		return webhookRetryDelay(n)
+	}
+	return asynq.DefaultRetryDelayFunc(n, err, task)
+}
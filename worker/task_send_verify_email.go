@@ -2,10 +2,11 @@ package worker
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog/log"
 )
@@ -14,30 +15,24 @@ const TaskSendVerifyEmail = "task:send_verify_email"
 
 type PayloadSendVerifyEmail struct {
 	Username string `json:"username"`
+	TaskContext
 }
 
-func (d *RedisTaskDistributor) DistributeTaskSendEmail(
+func (d *distributor) DistributeTaskSendEmail(
 	ctx context.Context,
 	payload *PayloadSendVerifyEmail,
 	opts ...asynq.Option,
 ) error {
-	payloadBytes, err := json.Marshal(payload)
+	payloadBytes, err := marshalPayload(ctx, payload)
 	if err != nil {
 		return err
 	}
 	task := asynq.NewTask(TaskSendVerifyEmail, payloadBytes, opts...)
 
-	info, err := d.client.EnqueueContext(ctx, task)
-	if err != nil {
-		return err
-	}
-	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
-		Str("queue", info.Queue).Int("max_retry", info.MaxRetry).
-		Msg("task enqueued")
-	return nil
+	return d.enqueue(ctx, task, opts...)
 }
 
-func (p *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error {
+func (p *taskHandlers) ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error {
 	var payload PayloadSendVerifyEmail
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
 
@@ -47,14 +42,15 @@ func (p *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Context, tas
 	user, err := p.store.GetUser(ctx, payload.Username)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("user not found: %w", asynq.SkipRetry)
+		// the user may not be visible yet, the task is retried until its retries run out
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return fmt.Errorf("user not found: %w", err)
 		}
 		return fmt.Errorf("could not get user: %w", err)
 	}
 	// send email to user
 
-	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+	log.Ctx(ctx).Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
 		Str("email", user.Email).Msg("processing task")
 	return nil
 }
@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	db "github.com/MElghrbawy/simple_bank/db/sqlc"
+)
+
+const webhookRelayBatchSize = 100
+
+// RunWebhookRelay enqueues a delivery task for every pending webhook event and
+// subscription listening to it, polling the store every interval until ctx is done.
+// An event is marked dispatched only once all of its tasks are enqueued, so a
+// failure may enqueue some deliveries twice but never loses one.
+func RunWebhookRelay(ctx context.Context, store db.Store, distributor TaskDistributor, interval time.Duration) {
+	runRelay(ctx, "webhook", webhookRelayBatchSize, interval, func() (int, error) {
+		return store.DispatchWebhookEventsTx(ctx, db.DispatchWebhookEventsTxParams{
+			Limit: webhookRelayBatchSize,
+			Dispatch: func(event db.WebhookEvent, subscriptions []db.WebhookSubscription) error {
+				for _, subscription := range subscriptions {
+					err := distributor.DistributeTaskDeliverWebhook(ctx, &PayloadDeliverWebhook{
+						SubscriptionID: subscription.ID,
+						EventID:        event.ID,
+					})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	})
+}